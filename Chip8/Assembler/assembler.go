@@ -0,0 +1,279 @@
+// Package Assembler monta programas escritos com os mnemonicos do "cowgod's chip-8 technical reference"
+// (os mesmos do disassembler) em ROMs .ch8.
+//
+// Cada linha tem labels opcionais ("loop:"), uma instrução ou diretiva e um comentario opcional (";").
+// Diretivas: db (bytes e strings), dw (words de 16 bits), org (muda o endereço atual),
+// include "arquivo" e constantes com "NOME equ expr" ou "NOME = expr".
+package Assembler
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/mellotonio/go-chip8/Chip8"
+)
+
+// Endereço onde o programa é carregado
+const origin = 0x200
+
+// Endereço maximo, o fim da memoria do XO-CHIP
+const memoryEnd = 0x10000
+
+// Error é um erro de montagem com a posição no código fonte
+type Error struct {
+	File string
+	Line int
+	Col  int
+	Msg  string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s:%d:%d: %s", e.File, e.Line, e.Col, e.Msg)
+}
+
+// ErrorList são todos os erros encontrados na montagem, um por linha
+type ErrorList []*Error
+
+func (list ErrorList) Error() string {
+	lines := make([]string, len(list))
+	for i, err := range list {
+		lines[i] = err.Error()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Erro com a coluna, a linha e o arquivo são adicionados por quem chamou
+type posError struct {
+	col int
+	msg string
+}
+
+// Program é o resultado da montagem
+type Program struct {
+	ROM     []byte           // Bytes a partir de 0x200, prontos para o LoadROM
+	Symbols *Chip8.SymbolMap // Labels e as linhas do código fonte de cada endereço
+}
+
+// Numero maximo de erros antes de desistir
+const maxErrors = 20
+
+type assembler struct {
+	statements []*statement
+	including  map[string]bool // Arquivos sendo incluidos, para achar includes circulares
+	symbols    map[string]int  // Labels e constantes
+	labels     map[string]bool
+	errors     ErrorList
+}
+
+// AssembleFile monta o arquivo path e os arquivos incluidos por ele
+func AssembleFile(path string) (*Program, error) {
+	src, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Assemble(path, src)
+}
+
+// Assemble monta o código src. name aparece nos erros e os includes são procurados na pasta dele.
+func Assemble(name string, src []byte) (*Program, error) {
+	a := &assembler{
+		including: map[string]bool{},
+		symbols:   map[string]int{},
+		labels:    map[string]bool{},
+	}
+	a.parse(name, src)
+	a.layout()
+	program := a.encode()
+	if len(a.errors) > 0 {
+		sort.SliceStable(a.errors, func(i, j int) bool {
+			if a.errors[i].File != a.errors[j].File {
+				return a.errors[i].File < a.errors[j].File
+			}
+			return a.errors[i].Line < a.errors[j].Line
+		})
+		return nil, a.errors
+	}
+	return program, nil
+}
+
+func (a *assembler) errorf(file string, line, col int, format string, args ...interface{}) {
+	if len(a.errors) < maxErrors {
+		a.errors = append(a.errors, &Error{File: file, Line: line, Col: col, Msg: fmt.Sprintf(format, args...)})
+	}
+}
+
+func (a *assembler) fail(st *statement, err *posError) {
+	a.errorf(st.file, st.line, err.col, "%s", err.msg)
+}
+
+// Divide o arquivo em statements, expandindo os includes
+func (a *assembler) parse(name string, src []byte) {
+	key := name
+	if abs, err := filepath.Abs(name); err == nil {
+		key = abs
+	}
+	a.including[key] = true
+	defer delete(a.including, key)
+
+	for i, text := range strings.Split(strings.Replace(string(src), "\r\n", "\n", -1), "\n") {
+		st, err := parseLine(name, i+1, text)
+		if err != nil {
+			a.errorf(name, i+1, err.col, "%s", err.msg)
+			continue
+		}
+		if st.op != "include" {
+			a.statements = append(a.statements, st)
+			continue
+		}
+
+		// O include entra no lugar da linha, mas os labels dela continuam valendo
+		if len(st.labels) > 0 {
+			a.statements = append(a.statements, &statement{file: st.file, line: st.line, labels: st.labels})
+		}
+		if len(st.operands) != 1 || !isString(st.operands[0].text) {
+			a.errorf(name, i+1, st.opCol, `include expects a file name in quotes`)
+			continue
+		}
+		path := unquote(st.operands[0].text)
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(filepath.Dir(name), path)
+		}
+		abs, _ := filepath.Abs(path)
+		if a.including[abs] {
+			a.errorf(name, i+1, st.operands[0].col, "%s includes itself", path)
+			continue
+		}
+		included, err2 := ioutil.ReadFile(path)
+		if err2 != nil {
+			a.errorf(name, i+1, st.operands[0].col, "%v", err2)
+			continue
+		}
+		a.parse(path, included)
+	}
+}
+
+// Primeira passada: calcula o endereço de cada statement, define os labels e avalia as constantes
+func (a *assembler) layout() {
+	pc := origin
+	for _, st := range a.statements {
+		for _, label := range st.labels {
+			a.define(st, label, pc, true)
+		}
+
+		switch {
+		case st.op == "":
+		case st.constant:
+			value, err := evaluate(st.operands[0].text, st.operands[0].col, pc, a.lookup)
+			if err != nil {
+				a.fail(st, err)
+				continue
+			}
+			a.define(st, operand{st.op, st.opCol}, value, false)
+		case st.op == "org":
+			if len(st.operands) != 1 {
+				a.errorf(st.file, st.line, st.opCol, "org expects one address")
+				continue
+			}
+			value, err := evaluate(st.operands[0].text, st.operands[0].col, pc, a.lookup)
+			if err != nil {
+				a.fail(st, err)
+				continue
+			}
+			if value < origin || value >= memoryEnd {
+				a.errorf(st.file, st.line, st.operands[0].col, "org address 0x%X is outside 0x200-0xFFFF", value)
+				continue
+			}
+			pc = value
+		default:
+			size, err := statementSize(st)
+			if err != nil {
+				a.fail(st, err)
+				st.op = "" // Já reportado, a segunda passada ignora
+				continue
+			}
+			st.addr = pc
+			pc += size
+		}
+	}
+}
+
+func (a *assembler) define(st *statement, name operand, value int, label bool) {
+	if reserved(name.text) {
+		a.errorf(st.file, st.line, name.col, "%s is a register name", name.text)
+		return
+	}
+	if _, exists := a.symbols[name.text]; exists {
+		a.errorf(st.file, st.line, name.col, "%s is already defined", name.text)
+		return
+	}
+	a.symbols[name.text] = value
+	a.labels[name.text] = label
+}
+
+func (a *assembler) lookup(name string) (int, bool) {
+	value, ok := a.symbols[name]
+	return value, ok
+}
+
+// Segunda passada: gera os bytes de cada statement com todos os labels já conhecidos
+func (a *assembler) encode() *Program {
+	var memory [memoryEnd]byte
+	var written [memoryEnd]bool
+	end := origin
+
+	symbols := &Chip8.SymbolMap{Labels: map[string]uint16{}}
+	for name, value := range a.symbols {
+		if a.labels[name] {
+			symbols.Labels[name] = uint16(value)
+		}
+	}
+
+	for _, st := range a.statements {
+		if st.op == "" || st.constant || st.op == "org" {
+			continue
+		}
+		data, err := a.assembleStatement(st)
+		if err != nil {
+			a.fail(st, err)
+			continue
+		}
+		if st.addr+len(data) > memoryEnd {
+			a.errorf(st.file, st.line, st.opCol, "program does not fit in memory (ends at 0x%X)", st.addr+len(data))
+			continue
+		}
+		for i, b := range data {
+			if written[st.addr+i] {
+				a.errorf(st.file, st.line, st.opCol, "address 0x%X is written twice (check org)", st.addr+i)
+				break
+			}
+			written[st.addr+i] = true
+			memory[st.addr+i] = b
+		}
+		if len(data) > 0 {
+			file := st.file
+			if abs, err := filepath.Abs(file); err == nil {
+				file = abs
+			}
+			symbols.Lines = append(symbols.Lines, Chip8.SourceLine{File: file, Line: st.line, Addr: uint16(st.addr)})
+		}
+		if st.addr+len(data) > end {
+			end = st.addr + len(data)
+		}
+	}
+
+	return &Program{
+		ROM:     append([]byte(nil), memory[origin:end]...),
+		Symbols: symbols,
+	}
+}
+
+func isString(text string) bool {
+	return len(text) >= 2 && text[0] == '"' && text[len(text)-1] == '"'
+}
+
+func unquote(text string) string {
+	return text[1 : len(text)-1]
+}
@@ -0,0 +1,119 @@
+package Assembler
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/mellotonio/go-chip8/Chip8"
+)
+
+const program = `
+; Desenha um sprite e espera uma tecla
+SPEED equ 3
+start:
+	cls
+	ld i, sprite
+	ld v0, 10
+	ld v1, SPEED * 2
+loop:	drw v0, v1, 5
+	ld v2, k
+	add v0, SPEED
+	se v0, 60
+	jp loop
+	call done
+	ld i, long sprite
+	save v0 - v1
+done:	ret
+sprite:	db 0xF0, 0x90, 0x90, 0x90, 0xF0
+`
+
+func TestAssemble(t *testing.T) {
+	prog, err := Assemble("test.asm", []byte(program))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []byte{
+		0x00, 0xE0, 0xA2, 0x1C, 0x60, 0x0A, 0x61, 0x06,
+		0xD0, 0x15, 0xF2, 0x0A, 0x70, 0x03, 0x30, 0x3C,
+		0x12, 0x08, 0x22, 0x1A, 0xF0, 0x00, 0x02, 0x1C,
+		0x50, 0x12, 0x00, 0xEE, 0xF0, 0x90, 0x90, 0x90,
+		0xF0,
+	}
+	if !bytes.Equal(prog.ROM, want) {
+		t.Errorf("ROM = % X\nwant  % X", prog.ROM, want)
+	}
+	for label, addr := range map[string]uint16{"start": 0x200, "loop": 0x208, "done": 0x21A, "sprite": 0x21C} {
+		if prog.Symbols.Labels[label] != addr {
+			t.Errorf("label %s = %03X, want %03X", label, prog.Symbols.Labels[label], addr)
+		}
+	}
+}
+
+// Desmontar o programa montado e montar o resultado de novo tem que dar os mesmos bytes
+func TestDisassembleRoundTrip(t *testing.T) {
+	prog, err := Assemble("test.asm", []byte(program))
+	if err != nil {
+		t.Fatal(err)
+	}
+	code := prog.Symbols.Labels["sprite"] - 0x200
+	memory := prog.ROM[:code]
+	var listing strings.Builder
+	for addr := 0; addr < len(memory); {
+		inst := Chip8.DisassembleAt(memory, addr, Chip8.XOChipMode)
+		listing.WriteString(inst.String() + "\n")
+		addr += inst.Size
+	}
+	again, err := Assemble("listing.asm", []byte(listing.String()))
+	if err != nil {
+		t.Fatalf("%v\n%s", err, listing.String())
+	}
+	if !bytes.Equal(again.ROM, memory) {
+		t.Errorf("reassembled % X\nwant        % X", again.ROM, memory)
+	}
+}
+
+// Toda instrução do XO-CHIP que o disassembler conhece volta para a mesma word
+func TestDisassembleEveryWord(t *testing.T) {
+	for word := 0; word <= 0xFFFF; word++ {
+		memory := []byte{byte(word >> 8), byte(word), 0x12, 0x34}
+		inst := Chip8.DisassembleAt(memory, 0, Chip8.XOChipMode)
+		prog, err := Assemble("word.asm", []byte(inst.String()))
+		if err != nil {
+			t.Errorf("%04X %q: %v", word, inst, err)
+			continue
+		}
+		if !bytes.Equal(prog.ROM, memory[:inst.Size]) {
+			t.Errorf("%04X %q assembles to % X", word, inst, prog.ROM)
+		}
+	}
+}
+
+func TestErrorPositions(t *testing.T) {
+	for _, test := range []struct {
+		src  string
+		want string
+	}{
+		{"\tcls\n  foo v1\n", `test.asm:2:3: unknown instruction "foo"`},
+		{"start:\n  ld v1, 0x100\n", "test.asm:2:10: value 256 (0x100) is out of range -128-255"},
+		{"  jp nowhere\n", `test.asm:1:6: undefined symbol "nowhere"`},
+		{"  add vg, 1\n", `test.asm:1:7: expected a register V0-VF, got "vg"`},
+		{"a:\na:\n", "test.asm:2:1: a is already defined"},
+		{"  ld v1, (2\n", "test.asm:1:10: missing )"},
+		{"  db \"abc\n", "test.asm:1:6: unterminated string"},
+		{"  cls v1\n", "test.asm:1:3: CLS expects 0 operand(s), got 1"},
+		{"  drw v1, v2, 1/0\n", "test.asm:1:16: division by zero"},
+		{"  bad\n  ld v1, 300\n", "test.asm:1:3: unknown instruction \"bad\"\ntest.asm:2:10: value 300 (0x12C) is out of range -128-255"},
+	} {
+		_, err := Assemble("test.asm", []byte(test.src))
+		if err == nil || err.Error() != test.want {
+			t.Errorf("Assemble(%q) = %v, want %q", test.src, err, test.want)
+		}
+	}
+
+	_, err := Assemble("test.asm", []byte("  cls\n\n  ld v1, vz\n"))
+	list, ok := err.(ErrorList)
+	if !ok || len(list) != 1 || list[0].File != "test.asm" || list[0].Line != 3 || list[0].Col != 10 {
+		t.Errorf("Assemble error = %#v, want an ErrorList with test.asm:3:10", err)
+	}
+}
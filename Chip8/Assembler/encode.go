@@ -0,0 +1,444 @@
+package Assembler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Operandos que são nomes de registradores e não podem ser usados como labels
+var keywords = map[string]bool{
+	"I": true, "[I]": true, "DT": true, "ST": true, "K": true,
+	"F": true, "HF": true, "B": true, "R": true, "LONG": true,
+}
+
+func reserved(name string) bool {
+	if _, ok := register(name); ok {
+		return true
+	}
+	return keywords[strings.ToUpper(name)]
+}
+
+// register reconhece V0 - VF
+func register(text string) (int, bool) {
+	if len(text) != 2 || (text[0] != 'V' && text[0] != 'v') {
+		return 0, false
+	}
+	n, err := strconv.ParseUint(text[1:], 16, 8)
+	if err != nil {
+		return 0, false
+	}
+	return int(n), true
+}
+
+func isKeyword(opnd operand, keyword string) bool {
+	return strings.EqualFold(strings.Replace(opnd.text, " ", "", -1), keyword)
+}
+
+// Operando "LONG expr" do LD I, LONG NNNN (XO-CHIP)
+func longOperand(opnd operand) (operand, bool) {
+	word, end := nextWord(opnd.text, 0)
+	if !strings.EqualFold(word, "long") || end == len(opnd.text) || (opnd.text[end] != ' ' && opnd.text[end] != '\t') {
+		return operand{}, false
+	}
+	rest := skipSpaces(opnd.text, end)
+	return operand{opnd.text[rest:], opnd.col + rest}, true
+}
+
+// Quantos bytes o statement gera, calculado antes dos labels serem conhecidos
+func statementSize(st *statement) (int, *posError) {
+	switch st.op {
+	case "db":
+		if len(st.operands) == 0 {
+			return 0, &posError{st.opCol, "db expects at least one value"}
+		}
+		size := 0
+		for _, opnd := range st.operands {
+			if isString(opnd.text) {
+				size += len(unquote(opnd.text))
+			} else {
+				size++
+			}
+		}
+		return size, nil
+	case "dw":
+		if len(st.operands) == 0 {
+			return 0, &posError{st.opCol, "dw expects at least one value"}
+		}
+		return 2 * len(st.operands), nil
+	case "ld":
+		if len(st.operands) == 2 && isKeyword(st.operands[0], "I") {
+			if _, ok := longOperand(st.operands[1]); ok {
+				return 4, nil
+			}
+		}
+	}
+	if _, ok := opcodes[st.op]; !ok {
+		return 0, &posError{st.opCol, fmt.Sprintf("unknown instruction %q", st.op)}
+	}
+	return 2, nil
+}
+
+// Instruções sem operandos
+var opcodes = map[string]uint16{
+	"cls": 0x00E0, "ret": 0x00EE, "scr": 0x00FB, "scl": 0x00FC,
+	"exit": 0x00FD, "low": 0x00FE, "high": 0x00FF, "audio": 0xF002,
+	// As outras só precisam existir aqui para o statementSize
+	"scd": 0, "scu": 0, "sys": 0, "jp": 0, "call": 0, "se": 0, "sne": 0, "save": 0, "load": 0,
+	"ld": 0, "add": 0, "or": 0, "and": 0, "xor": 0, "sub": 0, "subn": 0, "shr": 0, "shl": 0,
+	"rnd": 0, "drw": 0, "skp": 0, "sknp": 0, "plane": 0, "pitch": 0,
+}
+
+// Instruções 8XYN de dois registradores
+var aluOps = map[string]uint16{
+	"or": 0x1, "and": 0x2, "xor": 0x3, "sub": 0x5, "subn": 0x7,
+}
+
+// Instruções FXNN de um registrador, pela combinação de operandos do LD
+var loadFrom = map[string]uint16{"DT": 0x07, "K": 0x0A, "[I]": 0x65, "R": 0x85}
+var loadTo = map[string]uint16{"DT": 0x15, "ST": 0x18, "F": 0x29, "HF": 0x30, "B": 0x33, "[I]": 0x55, "R": 0x75}
+
+// Gera os bytes de um statement
+func (a *assembler) assembleStatement(st *statement) ([]byte, *posError) {
+	switch st.op {
+	case "db":
+		var data []byte
+		for _, opnd := range st.operands {
+			if isString(opnd.text) {
+				data = append(data, unquote(opnd.text)...)
+				continue
+			}
+			value, err := a.value(st, opnd, -128, 0xFF)
+			if err != nil {
+				return nil, err
+			}
+			data = append(data, byte(value))
+		}
+		return data, nil
+	case "dw":
+		var data []byte
+		for _, opnd := range st.operands {
+			value, err := a.value(st, opnd, -0x8000, 0xFFFF)
+			if err != nil {
+				return nil, err
+			}
+			data = append(data, byte(value>>8), byte(value))
+		}
+		return data, nil
+	}
+
+	words, err := a.instruction(st)
+	if err != nil {
+		return nil, err
+	}
+	data := make([]byte, 0, 2*len(words))
+	for _, w := range words {
+		data = append(data, byte(w>>8), byte(w))
+	}
+	return data, nil
+}
+
+func (a *assembler) instruction(st *statement) ([]uint16, *posError) {
+	ops := st.operands
+	count := func(n int) *posError {
+		if len(ops) != n {
+			return &posError{st.opCol, fmt.Sprintf("%s expects %d operand(s), got %d", strings.ToUpper(st.op), n, len(ops))}
+		}
+		return nil
+	}
+	one := func(w uint16) ([]uint16, *posError) { return []uint16{w}, nil }
+
+	switch st.op {
+	case "cls", "ret", "scr", "scl", "exit", "low", "high", "audio":
+		if err := count(0); err != nil {
+			return nil, err
+		}
+		return one(opcodes[st.op])
+	case "scd", "scu":
+		if err := count(1); err != nil {
+			return nil, err
+		}
+		n, err := a.value(st, ops[0], 0, 0xF)
+		if err != nil {
+			return nil, err
+		}
+		if st.op == "scu" {
+			return one(0x00D0 | uint16(n))
+		}
+		return one(0x00C0 | uint16(n))
+	case "sys", "call":
+		if err := count(1); err != nil {
+			return nil, err
+		}
+		nnn, err := a.address(st, ops[0])
+		if err != nil {
+			return nil, err
+		}
+		if st.op == "call" {
+			return one(0x2000 | nnn)
+		}
+		return one(nnn)
+	case "jp":
+		if len(ops) == 2 {
+			if x, ok := register(ops[0].text); !ok || x != 0 {
+				return nil, &posError{ops[0].col, "JP with two operands must be JP V0, addr"}
+			}
+			nnn, err := a.address(st, ops[1])
+			if err != nil {
+				return nil, err
+			}
+			return one(0xB000 | nnn)
+		}
+		if err := count(1); err != nil {
+			return nil, err
+		}
+		nnn, err := a.address(st, ops[0])
+		if err != nil {
+			return nil, err
+		}
+		return one(0x1000 | nnn)
+	case "se", "sne":
+		if err := count(2); err != nil {
+			return nil, err
+		}
+		x, err := a.register(ops[0])
+		if err != nil {
+			return nil, err
+		}
+		if y, ok := register(ops[1].text); ok {
+			base := uint16(0x5000)
+			if st.op == "sne" {
+				base = 0x9000
+			}
+			return one(base | x<<8 | uint16(y)<<4)
+		}
+		nn, err := a.byteValue(st, ops[1])
+		if err != nil {
+			return nil, err
+		}
+		base := uint16(0x3000)
+		if st.op == "sne" {
+			base = 0x4000
+		}
+		return one(base | x<<8 | nn)
+	case "save", "load":
+		// SAVE Vx - Vy ou SAVE Vx, Vy
+		if len(ops) == 1 {
+			if parts := strings.SplitN(ops[0].text, "-", 2); len(parts) == 2 {
+				first := strings.TrimSpace(parts[0])
+				ops = []operand{{first, ops[0].col}, {strings.TrimSpace(parts[1]), ops[0].col + len(parts[0]) + 1}}
+			}
+		}
+		if err := count(2); err != nil {
+			return nil, err
+		}
+		x, err := a.register(ops[0])
+		if err != nil {
+			return nil, err
+		}
+		y, err := a.register(ops[1])
+		if err != nil {
+			return nil, err
+		}
+		base := uint16(0x5002)
+		if st.op == "load" {
+			base = 0x5003
+		}
+		return one(base | x<<8 | y<<4)
+	case "ld":
+		return a.load(st, count)
+	case "add":
+		if err := count(2); err != nil {
+			return nil, err
+		}
+		if isKeyword(ops[0], "I") {
+			x, err := a.register(ops[1])
+			if err != nil {
+				return nil, err
+			}
+			return one(0xF01E | x<<8)
+		}
+		x, err := a.register(ops[0])
+		if err != nil {
+			return nil, err
+		}
+		if y, ok := register(ops[1].text); ok {
+			return one(0x8004 | x<<8 | uint16(y)<<4)
+		}
+		nn, err := a.byteValue(st, ops[1])
+		if err != nil {
+			return nil, err
+		}
+		return one(0x7000 | x<<8 | nn)
+	case "or", "and", "xor", "sub", "subn":
+		if err := count(2); err != nil {
+			return nil, err
+		}
+		x, err := a.register(ops[0])
+		if err != nil {
+			return nil, err
+		}
+		y, err := a.register(ops[1])
+		if err != nil {
+			return nil, err
+		}
+		return one(0x8000 | x<<8 | y<<4 | aluOps[st.op])
+	case "shr", "shl":
+		// O Vy é opcional, sem ele o registrador é deslocado nele mesmo
+		if len(ops) != 1 && len(ops) != 2 {
+			return nil, &posError{st.opCol, fmt.Sprintf("%s expects 1 or 2 operands, got %d", strings.ToUpper(st.op), len(ops))}
+		}
+		x, err := a.register(ops[0])
+		if err != nil {
+			return nil, err
+		}
+		y := x
+		if len(ops) == 2 {
+			if y, err = a.register(ops[1]); err != nil {
+				return nil, err
+			}
+		}
+		n := uint16(0x6)
+		if st.op == "shl" {
+			n = 0xE
+		}
+		return one(0x8000 | x<<8 | y<<4 | n)
+	case "rnd":
+		if err := count(2); err != nil {
+			return nil, err
+		}
+		x, err := a.register(ops[0])
+		if err != nil {
+			return nil, err
+		}
+		nn, err := a.byteValue(st, ops[1])
+		if err != nil {
+			return nil, err
+		}
+		return one(0xC000 | x<<8 | nn)
+	case "drw":
+		if err := count(3); err != nil {
+			return nil, err
+		}
+		x, err := a.register(ops[0])
+		if err != nil {
+			return nil, err
+		}
+		y, err := a.register(ops[1])
+		if err != nil {
+			return nil, err
+		}
+		n, err := a.value(st, ops[2], 0, 0xF)
+		if err != nil {
+			return nil, err
+		}
+		return one(0xD000 | x<<8 | y<<4 | uint16(n))
+	case "skp", "sknp", "pitch":
+		if err := count(1); err != nil {
+			return nil, err
+		}
+		x, err := a.register(ops[0])
+		if err != nil {
+			return nil, err
+		}
+		switch st.op {
+		case "skp":
+			return one(0xE09E | x<<8)
+		case "sknp":
+			return one(0xE0A1 | x<<8)
+		}
+		return one(0xF03A | x<<8)
+	case "plane":
+		if err := count(1); err != nil {
+			return nil, err
+		}
+		n, err := a.value(st, ops[0], 0, 0xF)
+		if err != nil {
+			return nil, err
+		}
+		return one(0xF001 | uint16(n)<<8)
+	}
+	return nil, &posError{st.opCol, fmt.Sprintf("unknown instruction %q", st.op)}
+}
+
+// Todas as formas do LD
+func (a *assembler) load(st *statement, count func(int) *posError) ([]uint16, *posError) {
+	if err := count(2); err != nil {
+		return nil, err
+	}
+	dst, src := st.operands[0], st.operands[1]
+	dstKey := strings.ToUpper(strings.Replace(dst.text, " ", "", -1))
+	srcKey := strings.ToUpper(strings.Replace(src.text, " ", "", -1))
+
+	if dstKey == "I" {
+		if long, ok := longOperand(src); ok {
+			value, err := a.value(st, long, 0, 0xFFFF)
+			if err != nil {
+				return nil, err
+			}
+			return []uint16{0xF000, uint16(value)}, nil
+		}
+		nnn, err := a.address(st, src)
+		if err != nil {
+			return nil, err
+		}
+		return []uint16{0xA000 | nnn}, nil
+	}
+
+	if n, ok := loadTo[dstKey]; ok {
+		x, err := a.register(src)
+		if err != nil {
+			return nil, err
+		}
+		return []uint16{0xF000 | x<<8 | n}, nil
+	}
+
+	x, err := a.register(dst)
+	if err != nil {
+		return nil, err
+	}
+	if n, ok := loadFrom[srcKey]; ok {
+		return []uint16{0xF000 | x<<8 | n}, nil
+	}
+	if y, ok := register(src.text); ok {
+		return []uint16{0x8000 | x<<8 | uint16(y)<<4}, nil
+	}
+	nn, err := a.byteValue(st, src)
+	if err != nil {
+		return nil, err
+	}
+	return []uint16{0x6000 | x<<8 | nn}, nil
+}
+
+func (a *assembler) register(opnd operand) (uint16, *posError) {
+	x, ok := register(opnd.text)
+	if !ok {
+		return 0, &posError{opnd.col, fmt.Sprintf("expected a register V0-VF, got %q", opnd.text)}
+	}
+	return uint16(x), nil
+}
+
+// Avalia um operando e confere se ele está entre min e max
+func (a *assembler) value(st *statement, opnd operand, min, max int) (int, *posError) {
+	if reserved(opnd.text) {
+		return 0, &posError{opnd.col, fmt.Sprintf("unexpected register %s", opnd.text)}
+	}
+	value, err := evaluate(opnd.text, opnd.col, st.addr, a.lookup)
+	if err != nil {
+		return 0, err
+	}
+	if value < min || value > max {
+		return 0, &posError{opnd.col, fmt.Sprintf("value %d (0x%X) is out of range %d-%d", value, value, min, max)}
+	}
+	return value, nil
+}
+
+func (a *assembler) byteValue(st *statement, opnd operand) (uint16, *posError) {
+	value, err := a.value(st, opnd, -128, 0xFF)
+	return uint16(value) & 0xFF, err
+}
+
+func (a *assembler) address(st *statement, opnd operand) (uint16, *posError) {
+	value, err := a.value(st, opnd, 0, 0xFFF)
+	return uint16(value), err
+}
@@ -0,0 +1,227 @@
+package Assembler
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Avaliação das expressões dos operandos: numeros (10, 0x1F, $1F, 0b101, 'A'), nomes de labels
+// e constantes, "$" sozinho para o endereço atual, parenteses e os operadores
+// | ^ & << >> + - * / % e os unarios - ~ +, com a precedencia do C.
+
+type token struct {
+	text string
+	col  int // Coluna na linha (começando em 1)
+}
+
+// Resolve o valor de um nome, ok = false se ele não existir (ainda)
+type lookupFunc func(name string) (int, bool)
+
+type exprParser struct {
+	tokens []token
+	pos    int
+	here   int // Endereço atual, valor do "$"
+	lookup lookupFunc
+	end    int // Coluna do fim da expressão, para os erros
+}
+
+// Divide uma expressão em tokens, col é a coluna do primeiro caractere de text
+func tokenize(text string, col int) ([]token, *posError) {
+	var tokens []token
+	for i := 0; i < len(text); {
+		c := text[i]
+		switch {
+		case c == ' ' || c == '\t':
+			i++
+		case c == '\'':
+			end := strings.IndexByte(text[i+1:], '\'')
+			if end < 0 {
+				return nil, &posError{col + i, "unterminated character literal"}
+			}
+			tokens = append(tokens, token{text[i : i+end+2], col + i})
+			i += end + 2
+		case isIdentChar(c) || c == '$':
+			start := i
+			i++
+			for i < len(text) && isIdentChar(text[i]) {
+				i++
+			}
+			tokens = append(tokens, token{text[start:i], col + start})
+		case c == '<' || c == '>':
+			if i+1 >= len(text) || text[i+1] != c {
+				return nil, &posError{col + i, "unexpected " + strconv.Quote(string(c))}
+			}
+			tokens = append(tokens, token{text[i : i+2], col + i})
+			i += 2
+		case strings.IndexByte("+-*/%&|^~()", c) >= 0:
+			tokens = append(tokens, token{string(c), col + i})
+			i++
+		default:
+			return nil, &posError{col + i, "unexpected " + strconv.Quote(string(c))}
+		}
+	}
+	return tokens, nil
+}
+
+func isIdentChar(c byte) bool {
+	return c == '_' || c == '.' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// evaluate avalia a expressão text que começa na coluna col
+func evaluate(text string, col int, here int, lookup lookupFunc) (int, *posError) {
+	tokens, err := tokenize(text, col)
+	if err != nil {
+		return 0, err
+	}
+	if len(tokens) == 0 {
+		return 0, &posError{col, "missing expression"}
+	}
+	p := &exprParser{tokens: tokens, here: here, lookup: lookup, end: col + len(text)}
+	value, err := p.binary(0)
+	if err != nil {
+		return 0, err
+	}
+	if p.pos < len(p.tokens) {
+		return 0, &posError{p.tokens[p.pos].col, "unexpected " + strconv.Quote(p.tokens[p.pos].text)}
+	}
+	return value, nil
+}
+
+// Precedencia dos operadores binarios, do menor para o maior
+var precedence = map[string]int{
+	"|": 1, "^": 2, "&": 3,
+	"<<": 4, ">>": 4,
+	"+": 5, "-": 5,
+	"*": 6, "/": 6, "%": 6,
+}
+
+func (p *exprParser) peek() (token, bool) {
+	if p.pos >= len(p.tokens) {
+		return token{}, false
+	}
+	return p.tokens[p.pos], true
+}
+
+func (p *exprParser) binary(minPrec int) (int, *posError) {
+	left, err := p.unary()
+	if err != nil {
+		return 0, err
+	}
+	for {
+		tok, ok := p.peek()
+		prec, isOp := precedence[tok.text]
+		if !ok || !isOp || prec <= minPrec {
+			return left, nil
+		}
+		p.pos++
+		right, err := p.binary(prec)
+		if err != nil {
+			return 0, err
+		}
+		switch tok.text {
+		case "|":
+			left |= right
+		case "^":
+			left ^= right
+		case "&":
+			left &= right
+		case "<<":
+			left <<= uint(right)
+		case ">>":
+			left >>= uint(right)
+		case "+":
+			left += right
+		case "-":
+			left -= right
+		case "*":
+			left *= right
+		case "/", "%":
+			if right == 0 {
+				return 0, &posError{tok.col, "division by zero"}
+			}
+			if tok.text == "/" {
+				left /= right
+			} else {
+				left %= right
+			}
+		}
+	}
+}
+
+func (p *exprParser) unary() (int, *posError) {
+	tok, ok := p.peek()
+	if !ok {
+		return 0, &posError{p.end, "missing operand"}
+	}
+	switch tok.text {
+	case "-", "~", "+":
+		p.pos++
+		value, err := p.unary()
+		if err != nil {
+			return 0, err
+		}
+		switch tok.text {
+		case "-":
+			return -value, nil
+		case "~":
+			return ^value, nil
+		}
+		return value, nil
+	case "(":
+		p.pos++
+		value, err := p.binary(0)
+		if err != nil {
+			return 0, err
+		}
+		if closing, ok := p.peek(); !ok || closing.text != ")" {
+			return 0, &posError{tok.col, "missing )"}
+		}
+		p.pos++
+		return value, nil
+	}
+	p.pos++
+	return p.primary(tok)
+}
+
+func (p *exprParser) primary(tok token) (int, *posError) {
+	text := tok.text
+	switch {
+	case text == "$":
+		return p.here, nil
+	case text[0] == '\'':
+		inner := text[1 : len(text)-1]
+		if len(inner) != 1 {
+			return 0, &posError{tok.col, "character literal must have exactly one character"}
+		}
+		return int(inner[0]), nil
+	case text[0] == '$':
+		return parseNumber(text[1:], 16, tok)
+	case text[0] >= '0' && text[0] <= '9':
+		lower := strings.ToLower(text)
+		switch {
+		case strings.HasPrefix(lower, "0x"):
+			return parseNumber(text[2:], 16, tok)
+		case strings.HasPrefix(lower, "0b"):
+			return parseNumber(text[2:], 2, tok)
+		}
+		return parseNumber(text, 10, tok)
+	case strings.IndexByte("+-*/%&|^~()<>", text[0]) >= 0:
+		return 0, &posError{tok.col, "unexpected " + strconv.Quote(text)}
+	}
+	if reserved(text) {
+		return 0, &posError{tok.col, "register " + text + " cannot be used in an expression"}
+	}
+	value, ok := p.lookup(text)
+	if !ok {
+		return 0, &posError{tok.col, "undefined symbol " + strconv.Quote(text)}
+	}
+	return value, nil
+}
+
+func parseNumber(digits string, base int, tok token) (int, *posError) {
+	value, err := strconv.ParseInt(strings.Replace(digits, "_", "", -1), base, 32)
+	if err != nil {
+		return 0, &posError{tok.col, "invalid number " + strconv.Quote(tok.text)}
+	}
+	return int(value), nil
+}
@@ -0,0 +1,154 @@
+package Assembler
+
+import (
+	"strings"
+)
+
+// Uma linha do código fonte já dividida em labels, instrução (ou diretiva) e operandos
+type statement struct {
+	file     string
+	line     int
+	labels   []operand // Nome e coluna dos labels definidos na linha
+	op       string    // Instrução ou diretiva em minusculas, vazio em linhas só com labels
+	opCol    int
+	operands []operand
+	constant bool // "NOME equ expr" ou "NOME = expr", op guarda o nome
+	addr     int  // Endereço do primeiro byte gerado, calculado na primeira passada
+}
+
+// Um operando e a coluna onde ele começa
+type operand struct {
+	text string
+	col  int
+}
+
+// Divide uma linha. Comentarios começam com ";" e labels terminam com ":".
+func parseLine(file string, number int, text string) (*statement, *posError) {
+	text = stripComment(text)
+	st := &statement{file: file, line: number}
+
+	i := skipSpaces(text, 0)
+	for i < len(text) {
+		start := i
+		for i < len(text) && isIdentChar(text[i]) {
+			i++
+		}
+		if i == start {
+			return nil, &posError{start + 1, "expected a label or an instruction"}
+		}
+		word := text[start:i]
+
+		// Label
+		if i < len(text) && text[i] == ':' {
+			st.labels = append(st.labels, operand{word, start + 1})
+			i = skipSpaces(text, i+1)
+			continue
+		}
+
+		rest := skipSpaces(text, i)
+		if rest < len(text) && text[rest] == '=' {
+			st.constant = true
+			st.op, st.opCol = word, start+1
+			st.operands = []operand{{strings.TrimSpace(text[rest+1:]), skipSpaces(text, rest+1) + 1}}
+			return st, nil
+		}
+		if next, end := nextWord(text, rest); strings.EqualFold(next, "equ") {
+			st.constant = true
+			st.op, st.opCol = word, start+1
+			st.operands = []operand{{strings.TrimSpace(text[end:]), skipSpaces(text, end) + 1}}
+			return st, nil
+		}
+
+		st.op, st.opCol = strings.ToLower(word), start+1
+		operands, err := splitOperands(text, rest)
+		if err != nil {
+			return nil, err
+		}
+		st.operands = operands
+		return st, nil
+	}
+	return st, nil
+}
+
+func stripComment(text string) string {
+	inString, inChar := false, false
+	for i := 0; i < len(text); i++ {
+		switch text[i] {
+		case '"':
+			if !inChar {
+				inString = !inString
+			}
+		case '\'':
+			if !inString {
+				inChar = !inChar
+			}
+		case ';':
+			if !inString && !inChar {
+				return text[:i]
+			}
+		}
+	}
+	return text
+}
+
+func skipSpaces(text string, i int) int {
+	for i < len(text) && (text[i] == ' ' || text[i] == '\t') {
+		i++
+	}
+	return i
+}
+
+func nextWord(text string, i int) (string, int) {
+	start := i
+	for i < len(text) && isIdentChar(text[i]) {
+		i++
+	}
+	return text[start:i], i
+}
+
+// Separa os operandos por virgula, fora de strings, caracteres e parenteses
+func splitOperands(text string, i int) ([]operand, *posError) {
+	var operands []operand
+	if strings.TrimSpace(text[i:]) == "" {
+		return nil, nil
+	}
+
+	start, depth := i, 0
+	inString, inChar := false, false
+	flush := func(end int) *posError {
+		raw := text[start:end]
+		trimmed := strings.TrimSpace(raw)
+		col := start + len(raw) - len(strings.TrimLeft(raw, " \t")) + 1
+		if trimmed == "" {
+			return &posError{col, "missing operand"}
+		}
+		operands = append(operands, operand{trimmed, col})
+		return nil
+	}
+
+	for ; i < len(text); i++ {
+		switch c := text[i]; {
+		case c == '"' && !inChar:
+			inString = !inString
+		case c == '\'' && !inString:
+			inChar = !inChar
+		case inString || inChar:
+		case c == '(' || c == '[':
+			depth++
+		case c == ')' || c == ']':
+			depth--
+		case c == ',' && depth == 0:
+			if err := flush(i); err != nil {
+				return nil, err
+			}
+			start = i + 1
+		}
+	}
+	if inString {
+		return nil, &posError{start + 1, "unterminated string"}
+	}
+	if err := flush(len(text)); err != nil {
+		return nil, err
+	}
+	return operands, nil
+}
@@ -0,0 +1,147 @@
+package Audio
+
+import (
+	"os"
+	"sync"
+	"time"
+
+	"github.com/faiface/beep"
+	"github.com/faiface/beep/effects"
+	"github.com/faiface/beep/mp3"
+	"github.com/faiface/beep/speaker"
+)
+
+// Beeper toca um arquivo mp3 toda vez que o tom do sound timer do Chip-8 é ligado.
+// Se o programa carregar um audio pattern (XO-CHIP), o pattern é tocado enquanto o tom estiver ligado.
+type Beeper struct {
+	path      string
+	audioChan chan bool // channel for pushing audio events (tom ligado/desligado)
+
+	mu         sync.Mutex
+	pattern    [16]byte
+	rate       float64 // Amostras do pattern por segundo
+	hasPattern bool
+	volume     float64 // 0 (mudo) até 1
+}
+
+func NewBeeper(path string) *Beeper {
+	return &Beeper{
+		path:      path,
+		audioChan: make(chan bool, 8),
+		volume:    1,
+	}
+}
+
+// SetVolume muda o volume do som, de 0 (mudo) até 1
+func (b *Beeper) SetVolume(volume float64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.volume = volume
+}
+
+// SetTone avisa que o tom ligou ou desligou,
+// sem bloquear caso o audio não esteja disponivel
+func (b *Beeper) SetTone(on bool) {
+	select {
+	case b.audioChan <- on:
+	default:
+	}
+}
+
+// SetPattern troca o audio pattern do XO-CHIP tocado enquanto o tom estiver ligado
+func (b *Beeper) SetPattern(pattern [16]byte, sampleRate float64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.pattern = pattern
+	b.rate = sampleRate
+	b.hasPattern = true
+}
+
+// Run inicializa o speaker e toca o som a cada SetTone(true), até o Close ser chamado
+func (b *Beeper) Run() {
+	f, err := os.Open(b.path)
+	if err != nil {
+		return
+	}
+
+	streamer, format, err := mp3.Decode(f)
+	if err != nil {
+		return
+	}
+	defer streamer.Close()
+
+	speaker.Init(
+		format.SampleRate,
+		format.SampleRate.N(time.Second/10),
+	)
+
+	var playing *patternStreamer
+	for on := range b.audioChan {
+		if playing != nil {
+			speaker.Lock()
+			playing.stopped = true
+			speaker.Unlock()
+			playing = nil
+		}
+		if !on {
+			continue
+		}
+
+		b.mu.Lock()
+		hasPattern, volume := b.hasPattern, b.volume
+		b.mu.Unlock()
+
+		var sound beep.Streamer = streamer
+		if hasPattern {
+			playing = &patternStreamer{beeper: b, sampleRate: float64(format.SampleRate)}
+			sound = playing
+		}
+		speaker.Play(&effects.Gain{Streamer: sound, Gain: volume - 1}) // Gain multiplica as amostras por 1 + Gain
+	}
+}
+
+// Se recebido sinal de shutdown, fecha o sinal de audio.
+func (b *Beeper) Close() {
+	close(b.audioChan)
+}
+
+// patternStreamer toca em loop as 128 amostras de 1 bit do audio pattern
+type patternStreamer struct {
+	beeper     *Beeper
+	sampleRate float64 // Amostras por segundo do speaker
+	pos        float64 // Posição atual no pattern (0 - 128)
+	stopped    bool
+}
+
+const patternVolume = 0.25
+
+func (p *patternStreamer) Stream(samples [][2]float64) (int, bool) {
+	if p.stopped {
+		return 0, false
+	}
+
+	p.beeper.mu.Lock()
+	pattern, rate := p.beeper.pattern, p.beeper.rate
+	p.beeper.mu.Unlock()
+
+	for i := range samples {
+		bit := int(p.pos)
+		v := -patternVolume
+		if pattern[bit/8]&(0x80>>uint(bit%8)) != 0 {
+			v = patternVolume
+		}
+		samples[i] = [2]float64{v, v}
+
+		p.pos += rate / p.sampleRate
+		for p.pos >= 128 {
+			p.pos -= 128
+		}
+	}
+	return len(samples), true
+}
+
+func (p *patternStreamer) Err() error {
+	return nil
+}
+
+var _ beep.Streamer = (*patternStreamer)(nil)
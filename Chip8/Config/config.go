@@ -0,0 +1,346 @@
+// Package Config lê o arquivo de configuração do usuário ($XDG_CONFIG_HOME/xp8/config.toml).
+//
+// Exemplo:
+//
+//	clock = 600
+//	palette = "#000000,#FFCC00"
+//	scale = 12
+//	volume = 0.5
+//	layout = "azerty"
+//
+//	[keymap]
+//	5 = ["Up", "W"]
+//	8 = "Down"
+//
+//	[gamepad2]  # Controles do segundo gamepad
+//	C = ["Up", "LeftY-"]
+//
+//	[rom.5f518084744bf3cb8733f6e5454dfd1634320563]  # SHA-1 da ROM (xp8 info)
+//	clock = 900
+//	mode = "schip"
+//
+//	[rom.5f518084744bf3cb8733f6e5454dfd1634320563.quirks]  # Perfil e quirks avulsos
+//	profile = "schip"
+//	clipping = false
+package Config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Settings são as preferencias que podem ser definidas para todas as ROMs ou para uma só.
+// Os valores zero (e Fullscreen e Volume nil) significam "não definido".
+type Settings struct {
+	Clock      int                    // Instruções por segundo
+	Mode       string                 // Plataforma ("chip8", "schip" ou "xochip")
+	Palette    string                 // Cores separadas por virgulas, como no Chip8.ParsePalette
+	Quirks     string                 // Nome do perfil de quirks
+	QuirkFlags map[string]bool        // Quirks ligados ou desligados por cima do perfil ("clipping" -> false)
+	Timing     string                 // Modelo de tempo ("frames" ou "vip")
+	OnFault    string                 // Política de falhas ("halt", "skip" ou "log")
+	Scale      int                    // Pixels da janela por pixel do Chip-8
+	Width      int                    // Tamanho da janela, usado quando Scale não é definido
+	Height     int                    //
+	Fullscreen *bool                  //
+	Volume     *float64               // Volume do som, de 0 (mudo) até 1
+	Layout     string                 // Layout de teclado (qwerty, azerty, numpad, cosmac)
+	Keymap     map[string][]string    // Tecla do Chip-8 ("5") -> teclas do teclado ("Up", "W")
+	Gamepads   [2]map[string][]string // Tecla do Chip-8 -> controles ("A", "LeftY-") do gamepad 1 e 2
+}
+
+// Config é o arquivo de configuração inteiro
+type Config struct {
+	Settings
+	ROMs map[string]Settings // Preferencias de cada ROM, pelo SHA-1 em hexadecimal
+}
+
+// DefaultPath retorna $XDG_CONFIG_HOME/xp8/config.toml (ou o diretório de configuração do sistema)
+func DefaultPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "xp8", "config.toml")
+}
+
+// Load lê o arquivo de configuração em path. Se ele não existir, retorna uma Config vazia.
+func Load(path string) (*Config, error) {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &Config{ROMs: map[string]Settings{}}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return Parse(path, data)
+}
+
+// Parse lê uma configuração em TOML, file é usado nas mensagens de erro
+func Parse(file string, data []byte) (*Config, error) {
+	root, err := parseTOML(file, string(data))
+	if err != nil {
+		return nil, err
+	}
+
+	config := &Config{ROMs: map[string]Settings{}}
+	roms, _ := root["rom"].(map[string]interface{})
+	delete(root, "rom")
+	if err := decodeSettings(root, &config.Settings); err != nil {
+		return nil, fmt.Errorf("%s: %v", file, err)
+	}
+	for hash, value := range roms {
+		table, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%s: rom.%s must be a table", file, hash)
+		}
+		var settings Settings
+		if err := decodeSettings(table, &settings); err != nil {
+			return nil, fmt.Errorf("%s: rom.%s: %v", file, hash, err)
+		}
+		config.ROMs[strings.ToLower(hash)] = settings
+	}
+	return config, nil
+}
+
+func decodeSettings(table map[string]interface{}, settings *Settings) error {
+	// Ordem fixa para que o primeiro erro seja sempre o mesmo
+	keys := make([]string, 0, len(table))
+	for key := range table {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		value := table[key]
+		var err error
+		switch key {
+		case "clock":
+			settings.Clock, err = positiveInt(key, value)
+		case "scale":
+			settings.Scale, err = positiveInt(key, value)
+		case "width":
+			settings.Width, err = positiveInt(key, value)
+		case "height":
+			settings.Height, err = positiveInt(key, value)
+		case "palette":
+			settings.Palette, err = stringValue(key, value)
+		case "mode":
+			settings.Mode, err = stringValue(key, value)
+		case "quirks":
+			err = quirksValue(key, value, settings)
+		case "timing":
+			settings.Timing, err = stringValue(key, value)
+		case "on_fault":
+			settings.OnFault, err = stringValue(key, value)
+		case "layout":
+			settings.Layout, err = stringValue(key, value)
+		case "fullscreen":
+			fullscreen, ok := value.(bool)
+			if !ok {
+				err = fmt.Errorf("%s must be true or false", key)
+			}
+			settings.Fullscreen = &fullscreen
+		case "volume":
+			var volume float64
+			switch v := value.(type) {
+			case int64:
+				volume = float64(v)
+			case float64:
+				volume = v
+			default:
+				err = fmt.Errorf("%s must be a number", key)
+			}
+			if err == nil && (volume < 0 || volume > 1) {
+				err = fmt.Errorf("%s must be between 0 and 1", key)
+			}
+			settings.Volume = &volume
+		case "keymap":
+			settings.Keymap, err = keymapValue(key, value)
+		case "gamepad1", "gamepad2":
+			settings.Gamepads[key[len(key)-1]-'1'], err = keymapValue(key, value)
+		default:
+			err = fmt.Errorf("unknown setting %q", key)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// quirks pode ser o nome de um perfil ("schip") ou uma tabela com o perfil e quirks avulsos:
+//
+//	[quirks]
+//	profile = "schip"
+//	clipping = false
+func quirksValue(key string, value interface{}, settings *Settings) error {
+	if profile, ok := value.(string); ok {
+		settings.Quirks = profile
+		return nil
+	}
+	table, ok := value.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("%s must be a profile name or a table", key)
+	}
+	for name, value := range table {
+		if name == "profile" {
+			profile, err := stringValue(key+".profile", value)
+			if err != nil {
+				return err
+			}
+			settings.Quirks = profile
+			continue
+		}
+		on, ok := value.(bool)
+		if !ok {
+			return fmt.Errorf("%s.%s must be true or false", key, name)
+		}
+		if settings.QuirkFlags == nil {
+			settings.QuirkFlags = map[string]bool{}
+		}
+		settings.QuirkFlags[strings.ToLower(name)] = on
+	}
+	return nil
+}
+
+func positiveInt(key string, value interface{}) (int, error) {
+	n, ok := value.(int64)
+	if !ok || n <= 0 {
+		return 0, fmt.Errorf("%s must be a positive integer", key)
+	}
+	return int(n), nil
+}
+
+func stringValue(key string, value interface{}) (string, error) {
+	s, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("%s must be a string", key)
+	}
+	return s, nil
+}
+
+// As teclas do keymap (e os controles dos gamepads) podem ser um nome ("Up") ou uma lista (["Up", "W"])
+func keymapValue(name string, value interface{}) (map[string][]string, error) {
+	table, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s must be a table", name)
+	}
+	keymap := map[string][]string{}
+	for key, buttons := range table {
+		if k, err := strconv.ParseUint(key, 16, 8); err != nil || k > 0xF {
+			return nil, fmt.Errorf("invalid CHIP-8 key %q in %s (expected 0-F)", key, name)
+		}
+		list, isList := buttons.([]interface{})
+		if !isList {
+			list = []interface{}{buttons}
+		}
+		var names []string
+		for _, button := range list {
+			control, ok := button.(string)
+			if !ok {
+				return nil, fmt.Errorf("%s.%s must be a name or a list of names", name, key)
+			}
+			names = append(names, control)
+		}
+		keymap[strings.ToUpper(key)] = names
+	}
+	return keymap, nil
+}
+
+// ForROM junta as preferencias gerais com as da ROM de SHA-1 hash, as da ROM valem mais
+func (config *Config) ForROM(hash string) Settings {
+	settings := config.Settings
+	rom, ok := config.ROMs[strings.ToLower(hash)]
+	if !ok {
+		return settings
+	}
+	settings.Merge(rom)
+	return settings
+}
+
+// Merge troca as preferencias pelas que estiverem definidas em other
+func (settings *Settings) Merge(other Settings) {
+	if other.Clock != 0 {
+		settings.Clock = other.Clock
+	}
+	if other.Mode != "" {
+		settings.Mode = other.Mode
+	}
+	if other.Palette != "" {
+		settings.Palette = other.Palette
+	}
+	if other.Quirks != "" {
+		settings.Quirks = other.Quirks
+	}
+	if len(other.QuirkFlags) > 0 {
+		merged := map[string]bool{}
+		for name, on := range settings.QuirkFlags {
+			merged[name] = on
+		}
+		for name, on := range other.QuirkFlags {
+			merged[name] = on
+		}
+		settings.QuirkFlags = merged
+	}
+	if other.Timing != "" {
+		settings.Timing = other.Timing
+	}
+	if other.OnFault != "" {
+		settings.OnFault = other.OnFault
+	}
+	if other.Layout != "" {
+		settings.Layout = other.Layout
+	}
+	if other.Scale != 0 || other.Width != 0 {
+		settings.Scale, settings.Width, settings.Height = other.Scale, other.Width, other.Height
+	}
+	if other.Fullscreen != nil {
+		settings.Fullscreen = other.Fullscreen
+	}
+	if other.Volume != nil {
+		settings.Volume = other.Volume
+	}
+	settings.Keymap = mergeKeys(settings.Keymap, other.Keymap)
+	for i := range settings.Gamepads {
+		settings.Gamepads[i] = mergeKeys(settings.Gamepads[i], other.Gamepads[i])
+	}
+}
+
+func mergeKeys(keys, other map[string][]string) map[string][]string {
+	if len(other) == 0 {
+		return keys
+	}
+	merged := map[string][]string{}
+	for key, names := range keys {
+		merged[key] = names
+	}
+	for key, names := range other {
+		merged[key] = names
+	}
+	return merged
+}
+
+// KeymapSpec converte o Keymap no formato do Display.ParseKeymap ("5=Up|W,8=Down")
+func (settings *Settings) KeymapSpec() string {
+	return keysSpec(settings.Keymap)
+}
+
+// GamepadSpec converte o mapa do gamepad player (1 ou 2) no formato do Display.ParseGamepadMap
+func (settings *Settings) GamepadSpec(player int) string {
+	return keysSpec(settings.Gamepads[player-1])
+}
+
+func keysSpec(keys map[string][]string) string {
+	entries := make([]string, 0, len(keys))
+	for key, names := range keys {
+		entries = append(entries, key+"="+strings.Join(names, "|"))
+	}
+	sort.Strings(entries)
+	return strings.Join(entries, ",")
+}
@@ -0,0 +1,114 @@
+package Config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const sample = `
+clock = 600
+palette = "#000000,#FFCC00"
+scale = 12
+volume = 0.5
+layout = "azerty"
+
+[keymap]
+5 = ["Up", "W"]
+8 = "Down"
+
+[gamepad2]  # Controles do segundo gamepad
+C = ["Up", "LeftY-"]
+
+[rom.5F518084744BF3CB8733F6E5454DFD1634320563]
+clock = 900
+mode = "schip"
+quirks = "schip"
+fullscreen = true
+`
+
+func TestParse(t *testing.T) {
+	config, err := Parse("config.toml", []byte(sample))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Clock != 600 || config.Scale != 12 || config.Layout != "azerty" || config.Palette != "#000000,#FFCC00" {
+		t.Errorf("settings = %+v", config.Settings)
+	}
+	if config.Volume == nil || *config.Volume != 0.5 {
+		t.Errorf("volume = %v, want 0.5", config.Volume)
+	}
+	if want := map[string][]string{"5": {"Up", "W"}, "8": {"Down"}}; !reflect.DeepEqual(config.Keymap, want) {
+		t.Errorf("keymap = %v, want %v", config.Keymap, want)
+	}
+	if want := map[string][]string{"C": {"Up", "LeftY-"}}; !reflect.DeepEqual(config.Gamepads[1], want) {
+		t.Errorf("gamepad2 = %v, want %v", config.Gamepads[1], want)
+	}
+
+	rom := config.ForROM("5f518084744bf3cb8733f6e5454dfd1634320563")
+	if rom.Clock != 900 || rom.Mode != "schip" || rom.Quirks != "schip" || rom.Fullscreen == nil || !*rom.Fullscreen || rom.Scale != 12 {
+		t.Errorf("ForROM = %+v", rom)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, test := range []struct {
+		src  string
+		want string
+	}{
+		{"clock = \n", "config.toml: toml: line"},
+		{"clock = -1", "clock must be a positive integer"},
+		{"volume = 2", "volume must be between 0 and 1"},
+		{"speed = 1", `unknown setting "speed"`},
+		{"[keymap]\n5 = 3", "keymap.5 must be a name or a list of names"},
+		{"[gamepad1]\n5 = [1]", "gamepad1.5 must be a name or a list of names"},
+		{"[keymap]\nG = \"Up\"", `invalid CHIP-8 key "G" in keymap`},
+		{"[rom.abc]\nclock = \"fast\"", "rom.abc: clock must be a positive integer"},
+		{"quirks = 3", "quirks must be a profile name or a table"},
+		{"[quirks]\nshift = 1", "quirks.shift must be true or false"},
+	} {
+		_, err := Parse("config.toml", []byte(test.src))
+		if err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("Parse(%q) = %v, want an error containing %q", test.src, err, test.want)
+		}
+	}
+}
+
+func TestMergeFalse(t *testing.T) {
+	config, err := Parse("config.toml", []byte("fullscreen = true\n[rom.abc]\nfullscreen = false\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rom := config.ForROM("abc"); rom.Fullscreen == nil || *rom.Fullscreen {
+		t.Errorf("fullscreen = false for the ROM did not override the top-level true")
+	}
+
+	settings := config.Settings
+	off := false
+	settings.Merge(Settings{Fullscreen: &off})
+	if *settings.Fullscreen {
+		t.Errorf("Merge did not turn fullscreen off")
+	}
+	settings.Merge(Settings{})
+	if *settings.Fullscreen {
+		t.Errorf("Merge of unset settings changed fullscreen")
+	}
+}
+
+func TestQuirksTable(t *testing.T) {
+	config, err := Parse("config.toml", []byte("[quirks]\nprofile = \"schip\"\nclipping = false\nshift = true\n[rom.abc.quirks]\nShift = false\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Quirks != "schip" || !reflect.DeepEqual(config.QuirkFlags, map[string]bool{"clipping": false, "shift": true}) {
+		t.Errorf("quirks = %q %v", config.Quirks, config.QuirkFlags)
+	}
+	// Os quirks da ROM são aplicados um a um por cima dos gerais
+	rom := config.ForROM("abc")
+	if rom.Quirks != "schip" || !reflect.DeepEqual(rom.QuirkFlags, map[string]bool{"clipping": false, "shift": false}) {
+		t.Errorf("ForROM quirks = %q %v", rom.Quirks, rom.QuirkFlags)
+	}
+	if !config.QuirkFlags["shift"] {
+		t.Errorf("ForROM changed the top-level quirks")
+	}
+}
@@ -0,0 +1,18 @@
+package Config
+
+import (
+	"fmt"
+
+	"github.com/BurntSushi/toml"
+)
+
+// O arquivo é lido pelo github.com/BurntSushi/toml. Cada tabela vira um map[string]interface{},
+// os inteiros int64, os floats float64 e os arrays []interface{}.
+// Os erros de sintaxe já trazem a linha, ex: "config.toml: toml: line 3: ...".
+func parseTOML(file, src string) (map[string]interface{}, error) {
+	root := map[string]interface{}{}
+	if _, err := toml.Decode(src, &root); err != nil {
+		return nil, fmt.Errorf("%s: %v", file, err)
+	}
+	return root, nil
+}
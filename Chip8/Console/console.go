@@ -0,0 +1,248 @@
+package Console
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/mellotonio/go-chip8/Chip8"
+)
+
+const help = `commands:
+  p, pause              pause execution
+  c, continue           run until the next breakpoint
+  s, step               execute one instruction
+  n, next               execute one instruction, stepping over calls (2NNN)
+  o, out                run until the current subroutine returns (00EE)
+  b, break <addr> [if <reg> <op> <value>]
+                        add a breakpoint, "*" as address matches any address
+  w, watch <start>[-<end>] [r|w|rw] [if <reg> <op> <value>]
+                        stop when an instruction reads/writes memory in the range
+  catch collision|clear [if <reg> <op> <value>]
+                        stop when DXYN sets VF on a collision or 00E0 clears the screen
+  d, delete <id>        remove a breakpoint, watchpoint or catchpoint
+  l, list               list breakpoints, watchpoints and catchpoints
+  v, view               show registers, stack and disassembly
+  h, help               show this help
+`
+
+// Linhas de disassembly mostradas antes e depois do program counter
+const viewAround = 5
+
+// Run lê comandos do debugger do in e escreve as respostas no out, até o in acabar
+func Run(d *Chip8.Debugger, in io.Reader, out io.Writer) {
+	go func() {
+		for event := range d.Stops() {
+			switch {
+			case event.Reason == Chip8.StopWatchpoint:
+				access := "read"
+				if event.Write {
+					access = "write"
+				}
+				fmt.Fprintf(out, "stopped at %03X (watchpoint %d: %s of %03X by instruction at %03X)\n",
+					event.PC, event.Breakpoint.ID, access, event.Addr, event.InstructionPC)
+			case event.Reason == Chip8.StopCatchpoint:
+				fmt.Fprintf(out, "stopped at %03X (catchpoint %d: %s by instruction at %03X)\n",
+					event.PC, event.Breakpoint.ID, event.Breakpoint.Kind, event.InstructionPC)
+			case event.Reason == Chip8.StopFault:
+				fmt.Fprintf(out, "stopped at %03X (%v)\n", event.PC, event.Err)
+			case event.Breakpoint != nil:
+				fmt.Fprintf(out, "stopped at %03X (breakpoint %d)\n", event.PC, event.Breakpoint.ID)
+			default:
+				fmt.Fprintf(out, "stopped at %03X (%s)\n", event.PC, event.Reason)
+			}
+			d.View(out, viewAround)
+		}
+	}()
+
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if err := command(d, out, fields); err != nil {
+			fmt.Fprintf(out, "error: %v\n", err)
+		}
+	}
+}
+
+func command(d *Chip8.Debugger, out io.Writer, fields []string) error {
+	switch fields[0] {
+	case "p", "pause":
+		d.Pause()
+	case "c", "continue":
+		d.Continue()
+	case "s", "step":
+		return d.Step()
+	case "n", "next":
+		return d.StepOver()
+	case "o", "out":
+		return d.StepOut()
+	case "b", "break":
+		return addBreakpoint(d, out, fields[1:])
+	case "w", "watch":
+		return addWatchpoint(d, out, fields[1:])
+	case "catch":
+		return addCatchpoint(d, out, fields[1:])
+	case "d", "delete":
+		if len(fields) != 2 {
+			return fmt.Errorf("usage: delete <id>")
+		}
+		id, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return err
+		}
+		return d.RemoveBreakpoint(id)
+	case "l", "list":
+		for _, bp := range d.Breakpoints() {
+			fmt.Fprintf(out, "%d: %s\n", bp.ID, describe(bp))
+		}
+	case "v", "view":
+		d.View(out, viewAround)
+	case "h", "help":
+		fmt.Fprint(out, help)
+	default:
+		return fmt.Errorf("unknown command %q, type \"help\"", fields[0])
+	}
+	return nil
+}
+
+func addBreakpoint(d *Chip8.Debugger, out io.Writer, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: break <addr> [if <reg> <op> <value>]")
+	}
+
+	args, condition, err := splitCondition(args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 1 {
+		return fmt.Errorf("usage: break <addr> [if <reg> <op> <value>]")
+	}
+
+	addr := -1
+	if args[0] != "*" {
+		if addr, err = parseAddr(args[0]); err != nil {
+			return err
+		}
+	}
+
+	bp, err := d.AddBreakpoint(addr, condition)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "breakpoint %d: %s\n", bp.ID, describe(bp))
+	return nil
+}
+
+// Separa o "if <reg> <op> <value>" do fim dos argumentos
+func splitCondition(args []string) ([]string, string, error) {
+	for i, arg := range args {
+		if arg == "if" {
+			if i == len(args)-1 {
+				return nil, "", fmt.Errorf("missing condition after \"if\"")
+			}
+			return args[:i], strings.Join(args[i+1:], " "), nil
+		}
+	}
+	return args, "", nil
+}
+
+func parseAddr(text string) (int, error) {
+	value, err := strconv.ParseUint(strings.TrimPrefix(strings.ToLower(text), "0x"), 16, 16)
+	if err != nil {
+		return 0, fmt.Errorf("invalid address %q", text)
+	}
+	return int(value), nil
+}
+
+func addWatchpoint(d *Chip8.Debugger, out io.Writer, args []string) error {
+	args, condition, err := splitCondition(args)
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 || len(args) > 2 {
+		return fmt.Errorf("usage: watch <start>[-<end>] [r|w|rw] [if <reg> <op> <value>]")
+	}
+
+	bounds := strings.SplitN(args[0], "-", 2)
+	start, err := parseAddr(bounds[0])
+	if err != nil {
+		return err
+	}
+	end := start
+	if len(bounds) == 2 {
+		if end, err = parseAddr(bounds[1]); err != nil {
+			return err
+		}
+	}
+
+	kind := Chip8.BreakWrite
+	if len(args) == 2 {
+		switch args[1] {
+		case "r":
+			kind = Chip8.BreakRead
+		case "w":
+			kind = Chip8.BreakWrite
+		case "rw":
+			kind = Chip8.BreakAccess
+		default:
+			return fmt.Errorf("invalid access %q, expected r, w or rw", args[1])
+		}
+	}
+
+	bp, err := d.AddWatchpoint(start, end, kind, condition)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "watchpoint %d: %s\n", bp.ID, describe(bp))
+	return nil
+}
+
+func addCatchpoint(d *Chip8.Debugger, out io.Writer, args []string) error {
+	args, condition, err := splitCondition(args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 1 {
+		return fmt.Errorf("usage: catch collision|clear [if <reg> <op> <value>]")
+	}
+
+	var kind Chip8.BreakpointKind
+	switch args[0] {
+	case "collision":
+		kind = Chip8.BreakCollision
+	case "clear":
+		kind = Chip8.BreakClear
+	default:
+		return fmt.Errorf("unknown event %q, expected collision or clear", args[0])
+	}
+
+	bp, err := d.AddCatchpoint(kind, condition)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "catchpoint %d: %s\n", bp.ID, describe(bp))
+	return nil
+}
+
+func describe(bp *Chip8.Breakpoint) string {
+	where := "*"
+	switch bp.Kind {
+	case Chip8.BreakExec:
+		if bp.Addr >= 0 {
+			where = fmt.Sprintf("%03X", bp.Addr)
+		}
+	case Chip8.BreakRead, Chip8.BreakWrite, Chip8.BreakAccess:
+		where = fmt.Sprintf("%s %03X-%03X", bp.Kind, bp.Addr, bp.End)
+	default:
+		where = bp.Kind.String()
+	}
+	if bp.Condition != nil {
+		return fmt.Sprintf("%s if %s", where, bp.Condition)
+	}
+	return where
+}
@@ -0,0 +1,212 @@
+package DAP
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Mensagens do Debug Adapter Protocol. Cada mensagem é um JSON precedido pelo header "Content-Length".
+type request struct {
+	Seq       int             `json:"seq"`
+	Type      string          `json:"type"`
+	Command   string          `json:"command"`
+	Arguments json.RawMessage `json:"arguments"`
+}
+
+type response struct {
+	Seq        int         `json:"seq"`
+	Type       string      `json:"type"`
+	RequestSeq int         `json:"request_seq"`
+	Success    bool        `json:"success"`
+	Command    string      `json:"command"`
+	Message    string      `json:"message,omitempty"`
+	Body       interface{} `json:"body,omitempty"`
+}
+
+type event struct {
+	Seq   int         `json:"seq"`
+	Type  string      `json:"type"`
+	Event string      `json:"event"`
+	Body  interface{} `json:"body,omitempty"`
+}
+
+// Lê e escreve mensagens, a escrita pode ser feita de varias goroutines
+type transport struct {
+	r   *bufio.Reader
+	w   io.Writer
+	mu  sync.Mutex
+	seq int
+}
+
+func newTransport(rw io.ReadWriter) *transport {
+	return &transport{r: bufio.NewReader(rw), w: rw}
+}
+
+func (t *transport) read() (*request, error) {
+	header, err := textproto.NewReader(t.r).ReadMIMEHeader()
+	if err != nil {
+		return nil, err
+	}
+	length, err := strconv.Atoi(strings.TrimSpace(header.Get("Content-Length")))
+	if err != nil {
+		return nil, fmt.Errorf("invalid Content-Length header: %v", err)
+	}
+
+	data := make([]byte, length)
+	if _, err := io.ReadFull(t.r, data); err != nil {
+		return nil, err
+	}
+	var req request
+	if err := json.Unmarshal(data, &req); err != nil {
+		return nil, err
+	}
+	return &req, nil
+}
+
+func (t *transport) respond(req *request, body interface{}, err error) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.seq++
+	res := response{Seq: t.seq, Type: "response", RequestSeq: req.Seq, Command: req.Command, Success: err == nil, Body: body}
+	if err != nil {
+		res.Message = err.Error()
+	}
+	return t.write(res)
+}
+
+func (t *transport) event(name string, body interface{}) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.seq++
+	return t.write(event{Seq: t.seq, Type: "event", Event: name, Body: body})
+}
+
+func (t *transport) write(message interface{}) error {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(t.w, "Content-Length: %d\r\n\r\n", len(data)); err != nil {
+		return err
+	}
+	_, err = t.w.Write(data)
+	return err
+}
+
+// Argumentos das requests usadas pelo servidor
+
+// LaunchArgs são os argumentos do "launch", vindos do launch.json do editor
+type LaunchArgs struct {
+	Program     string `json:"program"`     // Caminho da ROM
+	Symbols     string `json:"symbols"`     // Symbol map (opcional), por padrão o caminho da ROM + ".sym.json" se existir
+	StopOnEntry bool   `json:"stopOnEntry"` // Para antes da primeira instrução
+}
+
+type source struct {
+	Name string `json:"name,omitempty"`
+	Path string `json:"path,omitempty"`
+}
+
+type sourceBreakpoint struct {
+	Line      int    `json:"line"`
+	Condition string `json:"condition"`
+}
+
+type setBreakpointsArgs struct {
+	Source      source             `json:"source"`
+	Breakpoints []sourceBreakpoint `json:"breakpoints"`
+}
+
+type instructionBreakpoint struct {
+	InstructionReference string `json:"instructionReference"`
+	Offset               int    `json:"offset"`
+	Condition            string `json:"condition"`
+}
+
+type setInstructionBreakpointsArgs struct {
+	Breakpoints []instructionBreakpoint `json:"breakpoints"`
+}
+
+type functionBreakpoint struct {
+	Name      string `json:"name"`
+	Condition string `json:"condition"`
+}
+
+type setFunctionBreakpointsArgs struct {
+	Breakpoints []functionBreakpoint `json:"breakpoints"`
+}
+
+type breakpoint struct {
+	ID                   int     `json:"id,omitempty"`
+	Verified             bool    `json:"verified"`
+	Message              string  `json:"message,omitempty"`
+	Source               *source `json:"source,omitempty"`
+	Line                 int     `json:"line,omitempty"`
+	InstructionReference string  `json:"instructionReference,omitempty"`
+}
+
+type stackFrame struct {
+	ID                          int     `json:"id"`
+	Name                        string  `json:"name"`
+	Source                      *source `json:"source,omitempty"`
+	Line                        int     `json:"line"`
+	Column                      int     `json:"column"`
+	InstructionPointerReference string  `json:"instructionPointerReference"`
+}
+
+type scope struct {
+	Name               string `json:"name"`
+	VariablesReference int    `json:"variablesReference"`
+	Expensive          bool   `json:"expensive"`
+}
+
+type variablesArgs struct {
+	VariablesReference int `json:"variablesReference"`
+}
+
+type variable struct {
+	Name               string `json:"name"`
+	Value              string `json:"value"`
+	VariablesReference int    `json:"variablesReference"`
+	MemoryReference    string `json:"memoryReference,omitempty"`
+}
+
+type setVariableArgs struct {
+	VariablesReference int    `json:"variablesReference"`
+	Name               string `json:"name"`
+	Value              string `json:"value"`
+}
+
+type readMemoryArgs struct {
+	MemoryReference string `json:"memoryReference"`
+	Offset          int    `json:"offset"`
+	Count           int    `json:"count"`
+}
+
+type writeMemoryArgs struct {
+	MemoryReference string `json:"memoryReference"`
+	Offset          int    `json:"offset"`
+	Data            string `json:"data"`
+}
+
+type disassembleArgs struct {
+	MemoryReference   string `json:"memoryReference"`
+	Offset            int    `json:"offset"`
+	InstructionOffset int    `json:"instructionOffset"`
+	InstructionCount  int    `json:"instructionCount"`
+}
+
+type disassembledInstruction struct {
+	Address          string  `json:"address"`
+	InstructionBytes string  `json:"instructionBytes"`
+	Instruction      string  `json:"instruction"`
+	Symbol           string  `json:"symbol,omitempty"`
+	Location         *source `json:"location,omitempty"`
+	Line             int     `json:"line,omitempty"`
+}
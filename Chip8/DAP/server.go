@@ -0,0 +1,662 @@
+package DAP
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/mellotonio/go-chip8/Chip8"
+)
+
+// LaunchFunc cria a Machine com a ROM pedida pelo editor, já com os front ends conectados
+type LaunchFunc func(args LaunchArgs) (*Chip8.Machine, error)
+
+// Referencias das variables, o Debug Adapter Protocol não permite o 0
+const (
+	registersReference = 1
+	stackReference     = 2
+)
+
+// Só existe uma thread, a CPU do CHIP-8
+const threadID = 1
+
+var errNotLaunched = errors.New("no program has been launched")
+
+// Server implementa o Debug Adapter Protocol em cima do Debugger da Machine
+type Server struct {
+	launch LaunchFunc
+}
+
+func NewServer(launch LaunchFunc) *Server {
+	return &Server{launch: launch}
+}
+
+// ListenAndServe espera a conexão do editor no addr (ex: "localhost:4711") e atende uma única sessão
+func (s *Server) ListenAndServe(addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	defer listener.Close()
+
+	fmt.Printf("debug adapter listening on %s\n", listener.Addr())
+	conn, err := listener.Accept()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	return s.Serve(conn)
+}
+
+// Uma sessão de debug, do initialize até o disconnect
+type session struct {
+	server    *Server
+	t         *transport
+	machine   *Chip8.Machine
+	debugger  *Chip8.Debugger
+	symbols   *Chip8.SymbolMap
+	entry     bool
+	mu        sync.Mutex
+	sourceBPs map[string][]int // IDs dos breakpoints de cada arquivo
+	instrBPs  []int
+	funcBPs   []int
+	done      chan struct{}
+}
+
+// Serve atende uma sessão já conectada até o editor desconectar ou o programa terminar
+func (s *Server) Serve(conn io.ReadWriter) error {
+	sess := &session{
+		server:    s,
+		t:         newTransport(conn),
+		sourceBPs: map[string][]int{},
+		done:      make(chan struct{}),
+	}
+	defer close(sess.done)
+
+	for {
+		req, err := sess.t.read()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				err = nil
+			}
+			sess.release()
+			return err
+		}
+		if req.Type != "request" {
+			continue
+		}
+		body, err := sess.handle(req)
+		if err := sess.t.respond(req, body, err); err != nil {
+			sess.release()
+			return err
+		}
+		if req.Command == "launch" && err == nil {
+			sess.t.event("initialized", nil)
+		}
+		if req.Command == "disconnect" || req.Command == "terminate" {
+			return nil
+		}
+	}
+}
+
+func (sess *session) handle(req *request) (interface{}, error) {
+	switch req.Command {
+	case "initialize":
+		return map[string]interface{}{
+			"supportsConfigurationDoneRequest": true,
+			"supportsConditionalBreakpoints":   true,
+			"supportsFunctionBreakpoints":      true,
+			"supportsInstructionBreakpoints":   true,
+			"supportsSetVariable":              true,
+			"supportsReadMemoryRequest":        true,
+			"supportsWriteMemoryRequest":       true,
+			"supportsDisassembleRequest":       true,
+			"supportsSteppingGranularity":      true,
+			"supportsTerminateRequest":         true,
+			"supportTerminateDebuggee":         true,
+		}, nil
+	case "launch":
+		return nil, sess.launch(req.Arguments)
+	case "disconnect", "terminate":
+		sess.release()
+		return nil, nil
+	}
+
+	if sess.debugger == nil {
+		return nil, errNotLaunched
+	}
+	d := sess.debugger
+
+	switch req.Command {
+	case "setBreakpoints":
+		return sess.setBreakpoints(req.Arguments)
+	case "setInstructionBreakpoints":
+		return sess.setInstructionBreakpoints(req.Arguments)
+	case "setFunctionBreakpoints":
+		return sess.setFunctionBreakpoints(req.Arguments)
+	case "setExceptionBreakpoints":
+		return map[string]interface{}{"breakpoints": []breakpoint{}}, nil
+	case "configurationDone":
+		if sess.entry {
+			sess.t.event("stopped", stoppedBody("entry", ""))
+		} else {
+			d.Continue()
+		}
+		return nil, nil
+	case "threads":
+		return map[string]interface{}{
+			"threads": []map[string]interface{}{{"id": threadID, "name": "CHIP-8"}},
+		}, nil
+	case "stackTrace":
+		frames := sess.stackTrace()
+		return map[string]interface{}{"stackFrames": frames, "totalFrames": len(frames)}, nil
+	case "scopes":
+		return map[string]interface{}{"scopes": []scope{
+			{Name: "Registers", VariablesReference: registersReference},
+			{Name: "Stack", VariablesReference: stackReference},
+		}}, nil
+	case "variables":
+		return sess.variables(req.Arguments)
+	case "setVariable":
+		return sess.setVariable(req.Arguments)
+	case "readMemory":
+		return sess.readMemory(req.Arguments)
+	case "writeMemory":
+		return sess.writeMemory(req.Arguments)
+	case "disassemble":
+		return sess.disassemble(req.Arguments)
+	case "continue":
+		d.Continue()
+		return map[string]interface{}{"allThreadsContinued": true}, nil
+	case "next":
+		return nil, d.StepOver()
+	case "stepIn":
+		return nil, d.Step()
+	case "stepOut":
+		return nil, d.StepOut()
+	case "pause":
+		d.Pause()
+		return nil, nil
+	}
+	return nil, fmt.Errorf("unsupported request %q", req.Command)
+}
+
+func (sess *session) launch(raw json.RawMessage) error {
+	if sess.machine != nil {
+		return errors.New("a program has already been launched")
+	}
+	var args LaunchArgs
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return err
+	}
+	if args.Program == "" {
+		return errors.New(`launch needs a "program" to run`)
+	}
+
+	symbolsPath := args.Symbols
+	if symbolsPath == "" {
+		if _, err := os.Stat(args.Program + ".sym.json"); err == nil {
+			symbolsPath = args.Program + ".sym.json"
+		}
+	}
+	if symbolsPath != "" {
+		symbols, err := Chip8.LoadSymbolMap(symbolsPath)
+		if err != nil {
+			return fmt.Errorf("loading symbols: %v", err)
+		}
+		sess.symbols = symbols
+	}
+
+	machine, err := sess.server.launch(args)
+	if err != nil {
+		return err
+	}
+	sess.machine = machine
+	sess.entry = args.StopOnEntry
+	// Sem arquivo de símbolos, usa os do programa compilado pelo LoadROM (código fonte Octo)
+	if sess.symbols == nil {
+		sess.symbols = machine.Symbols()
+	}
+
+	// A Machine fica parada até o configurationDone, o stop do Pause não vai para o editor
+	sess.debugger = Chip8.NewDebugger(machine)
+	sess.debugger.Pause()
+	<-sess.debugger.Stops()
+
+	go sess.forwardStops()
+	go sess.run()
+	return nil
+}
+
+// Avisa o editor sempre que a Machine para
+func (sess *session) forwardStops() {
+	for {
+		select {
+		case event := <-sess.debugger.Stops():
+			reason, text := "step", ""
+			switch event.Reason {
+			case Chip8.StopPause:
+				reason = "pause"
+			case Chip8.StopBreakpoint:
+				reason = "breakpoint"
+				if event.Breakpoint.Addr < 0 || sess.isFunctionBreakpoint(event.Breakpoint.ID) {
+					reason = "function breakpoint"
+				}
+				if sess.isInstructionBreakpoint(event.Breakpoint.ID) {
+					reason = "instruction breakpoint"
+				}
+			case Chip8.StopWatchpoint:
+				reason = "data breakpoint"
+				text = fmt.Sprintf("%s at 0x%03X", event.Breakpoint.Kind, event.Addr)
+			case Chip8.StopCatchpoint:
+				reason = "exception"
+				text = event.Breakpoint.Kind.String()
+			case Chip8.StopFault:
+				reason = "exception"
+				text = event.Err.Error()
+			}
+			sess.t.event("stopped", stoppedBody(reason, text))
+		case <-sess.done:
+			return
+		}
+	}
+}
+
+// Roda a Machine e avisa o editor quando o programa termina (00FD ou a janela foi fechada)
+func (sess *session) run() {
+	sess.machine.Run()
+	select {
+	case <-sess.done:
+	default:
+		sess.t.event("exited", map[string]interface{}{"exitCode": 0})
+		sess.t.event("terminated", nil)
+	}
+}
+
+// Libera a Machine quando o editor desconecta
+func (sess *session) release() {
+	if sess.debugger == nil {
+		return
+	}
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	for _, bp := range sess.debugger.Breakpoints() {
+		sess.debugger.RemoveBreakpoint(bp.ID)
+	}
+	sess.sourceBPs = map[string][]int{}
+	sess.instrBPs, sess.funcBPs = nil, nil
+	sess.debugger.Continue()
+}
+
+func stoppedBody(reason, text string) map[string]interface{} {
+	body := map[string]interface{}{
+		"reason":            reason,
+		"threadId":          threadID,
+		"allThreadsStopped": true,
+	}
+	if text != "" {
+		body["text"] = text
+	}
+	return body
+}
+
+// Breakpoints
+
+func (sess *session) setBreakpoints(raw json.RawMessage) (interface{}, error) {
+	var args setBreakpointsArgs
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return nil, err
+	}
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+
+	sess.removeAll(sess.sourceBPs[args.Source.Path])
+	var ids []int
+	result := make([]breakpoint, 0, len(args.Breakpoints))
+	for _, sbp := range args.Breakpoints {
+		if sess.symbols == nil {
+			result = append(result, breakpoint{Verified: false, Message: "no symbol map loaded for this program", Line: sbp.Line})
+			continue
+		}
+		addr, line, ok := sess.symbols.AddrForLine(args.Source.Path, sbp.Line)
+		if !ok {
+			result = append(result, breakpoint{Verified: false, Message: "no code at or after this line", Line: sbp.Line})
+			continue
+		}
+		bp, err := sess.debugger.AddBreakpoint(int(addr), sbp.Condition)
+		if err != nil {
+			result = append(result, breakpoint{Verified: false, Message: err.Error(), Line: sbp.Line})
+			continue
+		}
+		ids = append(ids, bp.ID)
+		result = append(result, breakpoint{
+			ID:                   bp.ID,
+			Verified:             true,
+			Source:               &source{Name: filepath.Base(args.Source.Path), Path: args.Source.Path},
+			Line:                 line,
+			InstructionReference: addressReference(addr),
+		})
+	}
+	sess.sourceBPs[args.Source.Path] = ids
+	return map[string]interface{}{"breakpoints": result}, nil
+}
+
+func (sess *session) setInstructionBreakpoints(raw json.RawMessage) (interface{}, error) {
+	var args setInstructionBreakpointsArgs
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return nil, err
+	}
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+
+	sess.removeAll(sess.instrBPs)
+	sess.instrBPs = nil
+	result := make([]breakpoint, 0, len(args.Breakpoints))
+	for _, ibp := range args.Breakpoints {
+		addr, err := parseAddress(ibp.InstructionReference)
+		if err != nil {
+			result = append(result, breakpoint{Verified: false, Message: err.Error()})
+			continue
+		}
+		addr += ibp.Offset
+		bp, err := sess.debugger.AddBreakpoint(addr, ibp.Condition)
+		if err != nil {
+			result = append(result, breakpoint{Verified: false, Message: err.Error()})
+			continue
+		}
+		sess.instrBPs = append(sess.instrBPs, bp.ID)
+		result = append(result, breakpoint{ID: bp.ID, Verified: true, InstructionReference: addressReference(uint16(addr))})
+	}
+	return map[string]interface{}{"breakpoints": result}, nil
+}
+
+// Function breakpoints aceitam o nome de um label do symbol map ou um endereço
+func (sess *session) setFunctionBreakpoints(raw json.RawMessage) (interface{}, error) {
+	var args setFunctionBreakpointsArgs
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return nil, err
+	}
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+
+	sess.removeAll(sess.funcBPs)
+	sess.funcBPs = nil
+	result := make([]breakpoint, 0, len(args.Breakpoints))
+	for _, fbp := range args.Breakpoints {
+		addr, ok := -1, false
+		if sess.symbols != nil {
+			if a, found := sess.symbols.Labels[fbp.Name]; found {
+				addr, ok = int(a), true
+			}
+		}
+		if !ok {
+			a, err := parseAddress(fbp.Name)
+			if err != nil {
+				result = append(result, breakpoint{Verified: false, Message: fmt.Sprintf("unknown label %q", fbp.Name)})
+				continue
+			}
+			addr = a
+		}
+		bp, err := sess.debugger.AddBreakpoint(addr, fbp.Condition)
+		if err != nil {
+			result = append(result, breakpoint{Verified: false, Message: err.Error()})
+			continue
+		}
+		sess.funcBPs = append(sess.funcBPs, bp.ID)
+		result = append(result, breakpoint{ID: bp.ID, Verified: true, InstructionReference: addressReference(uint16(addr))})
+	}
+	return map[string]interface{}{"breakpoints": result}, nil
+}
+
+// Remove breakpoints pelo ID, deve ser chamado com o lock da sessão
+func (sess *session) removeAll(ids []int) {
+	for _, id := range ids {
+		sess.debugger.RemoveBreakpoint(id)
+	}
+}
+
+func (sess *session) isFunctionBreakpoint(id int) bool {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	return containsID(sess.funcBPs, id)
+}
+
+func (sess *session) isInstructionBreakpoint(id int) bool {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	return containsID(sess.instrBPs, id)
+}
+
+func containsID(ids []int, id int) bool {
+	for _, i := range ids {
+		if i == id {
+			return true
+		}
+	}
+	return false
+}
+
+// Stack, registradores e memoria
+
+// O frame 0 é o program counter, os outros são as instruções 2NNN guardadas na pilha
+func (sess *session) stackTrace() []stackFrame {
+	state := sess.debugger.State()
+	addrs := []uint16{state.ProgramCounter}
+	for sp := int(state.StackPointer); sp > 0 && sp < len(state.Stack); sp-- {
+		addrs = append(addrs, state.Stack[sp])
+	}
+
+	frames := make([]stackFrame, 0, len(addrs))
+	for i, addr := range addrs {
+		frame := stackFrame{
+			ID:                          i,
+			Name:                        sess.addressName(addr),
+			InstructionPointerReference: addressReference(addr),
+		}
+		if sess.symbols != nil {
+			if line, ok := sess.symbols.LineForAddr(addr); ok {
+				frame.Source = &source{Name: filepath.Base(line.File), Path: line.File}
+				frame.Line, frame.Column = line.Line, 1
+			}
+		}
+		frames = append(frames, frame)
+	}
+	return frames
+}
+
+// Nome de um endereço: o label mais proximo antes dele, ou o próprio endereço
+func (sess *session) addressName(addr uint16) string {
+	if sess.symbols != nil {
+		best, bestAddr := "", -1
+		for name, a := range sess.symbols.Labels {
+			if a <= addr && (int(a) > bestAddr || (int(a) == bestAddr && name < best)) {
+				best, bestAddr = name, int(a)
+			}
+		}
+		if bestAddr == int(addr) {
+			return best
+		}
+		if bestAddr >= 0 {
+			return fmt.Sprintf("%s+0x%X", best, int(addr)-bestAddr)
+		}
+	}
+	return fmt.Sprintf("0x%03X", addr)
+}
+
+func (sess *session) variables(raw json.RawMessage) (interface{}, error) {
+	var args variablesArgs
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return nil, err
+	}
+	state := sess.debugger.State()
+
+	var vars []variable
+	switch args.VariablesReference {
+	case registersReference:
+		for i, v := range state.Registers {
+			vars = append(vars, variable{Name: fmt.Sprintf("V%X", i), Value: byteValue(v)})
+		}
+		vars = append(vars,
+			variable{Name: "I", Value: fmt.Sprintf("0x%03X", state.Index), MemoryReference: addressReference(state.Index)},
+			variable{Name: "PC", Value: fmt.Sprintf("0x%03X", state.ProgramCounter), MemoryReference: addressReference(state.ProgramCounter)},
+			variable{Name: "SP", Value: strconv.Itoa(int(state.StackPointer))},
+			variable{Name: "DT", Value: byteValue(state.DelayTimer)},
+			variable{Name: "ST", Value: byteValue(state.SoundTimer)},
+		)
+	case stackReference:
+		for sp := int(state.StackPointer); sp > 0 && sp < len(state.Stack); sp-- {
+			addr := state.Stack[sp]
+			vars = append(vars, variable{
+				Name:            fmt.Sprintf("[%d]", sp),
+				Value:           fmt.Sprintf("0x%03X (%s)", addr, sess.addressName(addr)),
+				MemoryReference: addressReference(addr),
+			})
+		}
+	default:
+		return nil, fmt.Errorf("unknown variables reference %d", args.VariablesReference)
+	}
+	if vars == nil {
+		vars = []variable{}
+	}
+	return map[string]interface{}{"variables": vars}, nil
+}
+
+func byteValue(v byte) string {
+	return fmt.Sprintf("0x%02X (%d)", v, v)
+}
+
+func (sess *session) setVariable(raw json.RawMessage) (interface{}, error) {
+	var args setVariableArgs
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return nil, err
+	}
+	if args.VariablesReference != registersReference {
+		return nil, errors.New("only registers can be changed")
+	}
+	value, err := strconv.ParseInt(strings.Fields(args.Value + " ")[0], 0, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid value %q", args.Value)
+	}
+	if err := sess.debugger.SetRegister(args.Name, int(value)); err != nil {
+		return nil, err
+	}
+
+	state := sess.debugger.State()
+	text := ""
+	switch strings.ToUpper(args.Name) {
+	case "I":
+		text = fmt.Sprintf("0x%03X", state.Index)
+	case "PC":
+		text = fmt.Sprintf("0x%03X", state.ProgramCounter)
+	case "SP":
+		text = strconv.Itoa(int(state.StackPointer))
+	case "DT":
+		text = byteValue(state.DelayTimer)
+	case "ST":
+		text = byteValue(state.SoundTimer)
+	default:
+		reg, _ := strconv.ParseUint(args.Name[1:], 16, 8)
+		text = byteValue(state.Registers[reg])
+	}
+	return map[string]interface{}{"value": text}, nil
+}
+
+func (sess *session) readMemory(raw json.RawMessage) (interface{}, error) {
+	var args readMemoryArgs
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return nil, err
+	}
+	addr, err := parseAddress(args.MemoryReference)
+	if err != nil {
+		return nil, err
+	}
+	addr += args.Offset
+	data := sess.debugger.ReadMemory(addr, args.Count)
+	return map[string]interface{}{
+		"address":         addressReference(uint16(addr)),
+		"data":            base64.StdEncoding.EncodeToString(data),
+		"unreadableBytes": args.Count - len(data),
+	}, nil
+}
+
+func (sess *session) writeMemory(raw json.RawMessage) (interface{}, error) {
+	var args writeMemoryArgs
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return nil, err
+	}
+	addr, err := parseAddress(args.MemoryReference)
+	if err != nil {
+		return nil, err
+	}
+	data, err := base64.StdEncoding.DecodeString(args.Data)
+	if err != nil {
+		return nil, err
+	}
+	if err := sess.debugger.WriteMemory(addr+args.Offset, data); err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"bytesWritten": len(data)}, nil
+}
+
+// Disassembly de instructionCount instruções de 2 bytes, a partir de memoryReference + offset + instructionOffset * 2
+func (sess *session) disassemble(raw json.RawMessage) (interface{}, error) {
+	var args disassembleArgs
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return nil, err
+	}
+	base, err := parseAddress(args.MemoryReference)
+	if err != nil {
+		return nil, err
+	}
+	start := base + args.Offset + args.InstructionOffset*2
+
+	instructions := make([]disassembledInstruction, 0, args.InstructionCount)
+	for i := 0; i < args.InstructionCount; i++ {
+		addr := start + i*2
+		data := sess.debugger.ReadMemory(addr, 2)
+		if addr < 0 || len(data) < 2 {
+			instructions = append(instructions, disassembledInstruction{
+				Address:     fmt.Sprintf("0x%X", addr),
+				Instruction: "??",
+			})
+			continue
+		}
+		word := uint16(data[0])<<8 | uint16(data[1])
+		inst := disassembledInstruction{
+			Address:          addressReference(uint16(addr)),
+			InstructionBytes: fmt.Sprintf("%02X %02X", data[0], data[1]),
+			Instruction:      Chip8.Mnemonic(word),
+		}
+		if sess.symbols != nil {
+			if label, ok := sess.symbols.LabelForAddr(uint16(addr)); ok {
+				inst.Symbol = label
+			}
+			if line, ok := sess.symbols.LineForAddr(uint16(addr)); ok {
+				inst.Location = &source{Name: filepath.Base(line.File), Path: line.File}
+				inst.Line = line.Line
+			}
+		}
+		instructions = append(instructions, inst)
+	}
+	return map[string]interface{}{"instructions": instructions}, nil
+}
+
+func addressReference(addr uint16) string {
+	return fmt.Sprintf("0x%04X", addr)
+}
+
+func parseAddress(text string) (int, error) {
+	addr, err := strconv.ParseInt(strings.TrimSpace(text), 0, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid address %q", text)
+	}
+	return int(addr), nil
+}
@@ -0,0 +1,207 @@
+package DAP
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net"
+	"net/textproto"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/mellotonio/go-chip8/Chip8"
+)
+
+// Mensagem recebida pelo editor, resposta ou evento
+type message struct {
+	Seq        int             `json:"seq"`
+	Type       string          `json:"type"`
+	RequestSeq int             `json:"request_seq"`
+	Success    bool            `json:"success"`
+	Command    string          `json:"command"`
+	Message    string          `json:"message"`
+	Event      string          `json:"event"`
+	Body       json.RawMessage `json:"body"`
+}
+
+// Lado do editor da conexão
+type client struct {
+	t        *testing.T
+	conn     net.Conn
+	seq      int
+	messages chan message
+	events   []message // Eventos recebidos enquanto esperava uma resposta
+}
+
+func newClient(t *testing.T, conn net.Conn) *client {
+	c := &client{t: t, conn: conn, messages: make(chan message, 64)}
+	go func() {
+		r := bufio.NewReader(conn)
+		for {
+			header, err := textproto.NewReader(r).ReadMIMEHeader()
+			if err != nil {
+				close(c.messages)
+				return
+			}
+			length, _ := strconv.Atoi(header.Get("Content-Length"))
+			data := make([]byte, length)
+			if _, err := io.ReadFull(r, data); err != nil {
+				close(c.messages)
+				return
+			}
+			var msg message
+			if err := json.Unmarshal(data, &msg); err != nil {
+				t.Errorf("invalid message %s: %v", data, err)
+			}
+			c.messages <- msg
+		}
+	}()
+	return c
+}
+
+func (c *client) next() message {
+	c.t.Helper()
+	select {
+	case msg, ok := <-c.messages:
+		if !ok {
+			c.t.Fatal("the server closed the connection")
+		}
+		return msg
+	case <-time.After(5 * time.Second):
+		c.t.Fatal("timeout waiting for the server")
+	}
+	return message{}
+}
+
+// Envia uma request e espera a resposta dela, guardando os eventos que chegarem antes
+func (c *client) request(command string, args interface{}, body interface{}) message {
+	c.t.Helper()
+	c.seq++
+	req := map[string]interface{}{"seq": c.seq, "type": "request", "command": command, "arguments": args}
+	if err := (&transport{w: c.conn}).write(req); err != nil {
+		c.t.Fatal(err)
+	}
+	for {
+		msg := c.next()
+		if msg.Type == "event" {
+			c.events = append(c.events, msg)
+			continue
+		}
+		if msg.RequestSeq != c.seq || msg.Command != command {
+			c.t.Fatalf("%s: got the response %+v", command, msg)
+		}
+		if body != nil && msg.Success {
+			if err := json.Unmarshal(msg.Body, body); err != nil {
+				c.t.Fatalf("%s: %v", command, err)
+			}
+		}
+		return msg
+	}
+}
+
+// Espera um evento, que pode já ter chegado junto com uma resposta
+func (c *client) event(name string, body interface{}) message {
+	c.t.Helper()
+	for {
+		var msg message
+		if len(c.events) > 0 {
+			msg, c.events = c.events[0], c.events[1:]
+		} else {
+			msg = c.next()
+		}
+		if msg.Type != "event" || msg.Event != name {
+			c.t.Fatalf("waiting for the %s event, got %+v", name, msg)
+		}
+		if body != nil {
+			if err := json.Unmarshal(msg.Body, body); err != nil {
+				c.t.Fatalf("%s: %v", name, err)
+			}
+		}
+		return msg
+	}
+}
+
+func TestSession(t *testing.T) {
+	// call sub, V1 = 2, loop  /  sub: V0 = 1, return
+	rom := []byte{0x22, 0x06, 0x61, 0x02, 0x12, 0x04, 0x60, 0x01, 0x00, 0xEE}
+	var machine *Chip8.Machine
+	server := NewServer(func(args LaunchArgs) (*Chip8.Machine, error) {
+		machine = Chip8.New()
+		return machine, machine.LoadBytes(rom)
+	})
+	serverConn, clientConn := net.Pipe()
+	defer clientConn.Close()
+	done := make(chan error, 1)
+	go func() { done <- server.Serve(serverConn) }()
+	c := newClient(t, clientConn)
+
+	var capabilities map[string]bool
+	if res := c.request("initialize", map[string]string{"adapterID": "xp8"}, &capabilities); !res.Success || !capabilities["supportsInstructionBreakpoints"] {
+		t.Fatalf("initialize = %+v", res)
+	}
+	if res := c.request("threads", nil, nil); res.Success || res.Message != errNotLaunched.Error() {
+		t.Errorf("threads before launch = %+v, want %q", res, errNotLaunched)
+	}
+	if res := c.request("launch", LaunchArgs{}, nil); res.Success {
+		t.Errorf("launch without a program succeeded")
+	}
+	if res := c.request("launch", LaunchArgs{Program: "game.ch8", StopOnEntry: true}, nil); !res.Success {
+		t.Fatalf("launch = %+v", res)
+	}
+	c.event("initialized", nil)
+
+	var bps struct{ Breakpoints []breakpoint }
+	args := map[string]interface{}{"breakpoints": []map[string]interface{}{{"instructionReference": "0x206"}, {"instructionReference": "sub"}}}
+	c.request("setInstructionBreakpoints", args, &bps)
+	if len(bps.Breakpoints) != 2 || !bps.Breakpoints[0].Verified || bps.Breakpoints[1].Verified {
+		t.Errorf("setInstructionBreakpoints = %+v", bps.Breakpoints)
+	}
+
+	var stopped map[string]interface{}
+	c.request("configurationDone", nil, nil)
+	if c.event("stopped", &stopped); stopped["reason"] != "entry" {
+		t.Errorf("stopped = %v, want entry", stopped)
+	}
+	c.request("continue", map[string]int{"threadId": threadID}, nil)
+	if c.event("stopped", &stopped); stopped["reason"] != "instruction breakpoint" {
+		t.Errorf("stopped = %v, want instruction breakpoint", stopped)
+	}
+
+	// Dentro da subrotina: o PC e a chamada na pilha
+	var trace struct {
+		StackFrames []stackFrame
+		TotalFrames int
+	}
+	c.request("stackTrace", map[string]int{"threadId": threadID}, &trace)
+	if trace.TotalFrames != 2 || len(trace.StackFrames) != 2 ||
+		trace.StackFrames[0].InstructionPointerReference != "0x0206" || trace.StackFrames[0].Name != "0x206" ||
+		trace.StackFrames[1].InstructionPointerReference != "0x0200" {
+		t.Errorf("stackTrace = %+v", trace)
+	}
+
+	var vars struct{ Variables []variable }
+	c.request("variables", variablesArgs{VariablesReference: registersReference}, &vars)
+	if len(vars.Variables) != 21 || vars.Variables[17].Name != "PC" || vars.Variables[17].Value != "0x206" {
+		t.Errorf("variables = %+v", vars.Variables)
+	}
+
+	c.request("stepIn", map[string]int{"threadId": threadID}, nil)
+	if c.event("stopped", &stopped); stopped["reason"] != "step" {
+		t.Errorf("stopped = %v, want step", stopped)
+	}
+	if state := machine.Debugger().State(); state.Registers[0] != 1 || state.ProgramCounter != 0x208 {
+		t.Errorf("after stepIn V0 = %d, PC = %03X, want 1, 208", state.Registers[0], state.ProgramCounter)
+	}
+
+	if res := c.request("disconnect", nil, nil); !res.Success {
+		t.Errorf("disconnect = %+v", res)
+	}
+	if err := <-done; err != nil {
+		t.Errorf("Serve = %v", err)
+	}
+	if machine.Debugger().Paused() || len(machine.Debugger().Breakpoints()) != 0 {
+		t.Errorf("disconnect left the machine paused or with breakpoints")
+	}
+	machine.Stop()
+}
@@ -0,0 +1,244 @@
+package Display
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/faiface/pixel/pixelgl"
+)
+
+// Quanto um eixo precisa ser movido (de 0 até 1) para contar como tecla apertada
+const axisThreshold = 0.5
+
+// Quantos controles são lidos. O controle 1 é o primeiro joystick conectado no GLFW.
+const Gamepads = 2
+
+// GamepadControl é um botão do controle ou a direção de um eixo (analógico ou gatilho)
+type GamepadControl struct {
+	Button pixelgl.GamepadButton
+	Axis   pixelgl.GamepadAxis
+	Sign   int // 0 = botão, -1 ou +1 = eixo Axis nessa direção
+}
+
+// GamepadMap liga cada tecla do Chip-8 aos controles de um gamepad que a acionam
+type GamepadMap map[uint16][]GamepadControl
+
+// Nomes dos botões, na ordem do GLFW (layout do controle do Xbox)
+var gamepadButtonNames = []string{
+	pixelgl.ButtonA:           "A",
+	pixelgl.ButtonB:           "B",
+	pixelgl.ButtonX:           "X",
+	pixelgl.ButtonY:           "Y",
+	pixelgl.ButtonLeftBumper:  "LB",
+	pixelgl.ButtonRightBumper: "RB",
+	pixelgl.ButtonBack:        "Back",
+	pixelgl.ButtonStart:       "Start",
+	pixelgl.ButtonGuide:       "Guide",
+	pixelgl.ButtonLeftThumb:   "LS",
+	pixelgl.ButtonRightThumb:  "RS",
+	pixelgl.ButtonDpadUp:      "Up",
+	pixelgl.ButtonDpadRight:   "Right",
+	pixelgl.ButtonDpadDown:    "Down",
+	pixelgl.ButtonDpadLeft:    "Left",
+}
+
+var gamepadAxisNames = []string{
+	pixelgl.AxisLeftX:        "LeftX",
+	pixelgl.AxisLeftY:        "LeftY",
+	pixelgl.AxisRightX:       "RightX",
+	pixelgl.AxisRightY:       "RightY",
+	pixelgl.AxisLeftTrigger:  "LT",
+	pixelgl.AxisRightTrigger: "RT",
+}
+
+// Direções do D-pad junto com a mesma direção do analógico esquerdo (no GLFW, Y negativo é para cima)
+var (
+	padUp    = []GamepadControl{{Button: pixelgl.ButtonDpadUp}, {Axis: pixelgl.AxisLeftY, Sign: -1}}
+	padDown  = []GamepadControl{{Button: pixelgl.ButtonDpadDown}, {Axis: pixelgl.AxisLeftY, Sign: 1}}
+	padLeft  = []GamepadControl{{Button: pixelgl.ButtonDpadLeft}, {Axis: pixelgl.AxisLeftX, Sign: -1}}
+	padRight = []GamepadControl{{Button: pixelgl.ButtonDpadRight}, {Axis: pixelgl.AxisLeftX, Sign: 1}}
+)
+
+// Controles usados para as ações do chip-8-database. Os dois controles usam as ações de um
+// jogador só; as de player1 vão para o controle 1 e as de player2 para o controle 2.
+var actionControls = map[string][]GamepadControl{
+	"up":    padUp,
+	"down":  padDown,
+	"left":  padLeft,
+	"right": padRight,
+	"a":     {{Button: pixelgl.ButtonA}},
+	"b":     {{Button: pixelgl.ButtonB}},
+}
+
+// NewGamepadMap retorna o mapa do controle player (1 ou 2) para uma ROM com as ações actions
+// (ROMInfo.Keys). Sem ações conhecidas, o D-pad aciona 2/8/4/6 (as setas do teclado
+// hexadecimal) e o botão A aciona 5.
+func NewGamepadMap(player int, actions map[string]byte) GamepadMap {
+	gamepad := GamepadMap{}
+	prefix := fmt.Sprintf("player%d", player)
+	names := make([]string, 0, len(actions))
+	for name := range actions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		key := actions[name]
+		action := strings.ToLower(strings.TrimPrefix(name, prefix))
+		if strings.HasPrefix(name, "player") && !strings.HasPrefix(name, prefix) {
+			continue // Ação de outro jogador
+		}
+		controls, ok := actionControls[action]
+		if !ok || key > 0xF {
+			continue
+		}
+		for _, control := range controls {
+			gamepad.Bind(uint16(key), control)
+		}
+	}
+	if len(gamepad) > 0 {
+		return gamepad
+	}
+	return GamepadMap{
+		0x2: append([]GamepadControl(nil), padUp...),
+		0x8: append([]GamepadControl(nil), padDown...),
+		0x4: append([]GamepadControl(nil), padLeft...),
+		0x6: append([]GamepadControl(nil), padRight...),
+		0x5: {{Button: pixelgl.ButtonA}},
+	}
+}
+
+// Bind adiciona control aos controles que acionam key, se ele ainda não estiver lá
+func (gamepad GamepadMap) Bind(key uint16, control GamepadControl) {
+	for _, bound := range gamepad[key] {
+		if bound == control {
+			return
+		}
+	}
+	gamepad[key] = append(gamepad[key], control)
+}
+
+// Merge troca as teclas do Chip-8 que existirem em other pelas de other.
+// Os controles usados em other deixam de acionar as outras teclas do Chip-8.
+func (gamepad GamepadMap) Merge(other GamepadMap) {
+	for _, controls := range other {
+		for _, control := range controls {
+			for key, bound := range gamepad {
+				var kept []GamepadControl
+				for _, c := range bound {
+					if c != control {
+						kept = append(kept, c)
+					}
+				}
+				gamepad[key] = kept
+			}
+		}
+	}
+	for key, controls := range other {
+		gamepad[key] = append([]GamepadControl(nil), controls...)
+	}
+}
+
+// Pressed diz se o controle está apertado no joystick js da janela w
+func (control GamepadControl) Pressed(w *pixelgl.Window, js pixelgl.Joystick) bool {
+	if control.Sign == 0 {
+		return w.JoystickPressed(js, control.Button)
+	}
+	return w.JoystickAxis(js, control.Axis)*float64(control.Sign) > axisThreshold
+}
+
+// String retorna o nome do controle, como no ParseGamepadControl
+func (control GamepadControl) String() string {
+	switch {
+	case control.Sign == 0 && int(control.Button) < len(gamepadButtonNames):
+		return gamepadButtonNames[control.Button]
+	case control.Sign == 0:
+		return fmt.Sprintf("Button%d", control.Button)
+	case int(control.Axis) < len(gamepadAxisNames) && control.Sign < 0:
+		return gamepadAxisNames[control.Axis] + "-"
+	case int(control.Axis) < len(gamepadAxisNames):
+		return gamepadAxisNames[control.Axis] + "+"
+	case control.Sign < 0:
+		return fmt.Sprintf("Axis%d-", control.Axis)
+	}
+	return fmt.Sprintf("Axis%d+", control.Axis)
+}
+
+// String retorna o mapa no formato do ParseGamepadMap
+func (gamepad GamepadMap) String() string {
+	var entries []string
+	for _, key := range HexPadOrder {
+		if controls := gamepad[key]; len(controls) > 0 {
+			names := make([]string, len(controls))
+			for i, control := range controls {
+				names[i] = control.String()
+			}
+			entries = append(entries, fmt.Sprintf("%X=%s", key, strings.Join(names, "|")))
+		}
+	}
+	return strings.Join(entries, ",")
+}
+
+// ParseGamepadControl converte o nome de um controle em GamepadControl. Os botões são
+// A, B, X, Y, LB, RB, Back, Start, Guide, LS, RS e Up, Down, Left, Right (D-pad); os eixos
+// são LeftX, LeftY, RightX, RightY, LT e RT seguidos da direção (+ ou -). Joysticks que o
+// GLFW não conhece como gamepad usam Button0, Button1... e Axis0+, Axis0-...
+func ParseGamepadControl(name string) (GamepadControl, error) {
+	name = strings.TrimSpace(name)
+	lower := strings.ToLower(name)
+	for i, button := range gamepadButtonNames {
+		if lower == strings.ToLower(button) {
+			return GamepadControl{Button: pixelgl.GamepadButton(i)}, nil
+		}
+	}
+	if n, err := strconv.Atoi(strings.TrimPrefix(lower, "button")); err == nil && strings.HasPrefix(lower, "button") && n >= 0 {
+		return GamepadControl{Button: pixelgl.GamepadButton(n)}, nil
+	}
+
+	if strings.HasSuffix(lower, "+") || strings.HasSuffix(lower, "-") {
+		sign := 1
+		if strings.HasSuffix(lower, "-") {
+			sign = -1
+		}
+		axis := lower[:len(lower)-1]
+		for i, axisName := range gamepadAxisNames {
+			if axis == strings.ToLower(axisName) {
+				return GamepadControl{Axis: pixelgl.GamepadAxis(i), Sign: sign}, nil
+			}
+		}
+		if n, err := strconv.Atoi(strings.TrimPrefix(axis, "axis")); err == nil && strings.HasPrefix(axis, "axis") && n >= 0 {
+			return GamepadControl{Axis: pixelgl.GamepadAxis(n), Sign: sign}, nil
+		}
+	}
+	return GamepadControl{}, fmt.Errorf("unknown gamepad control %q", name)
+}
+
+// ParseGamepadMap lê uma lista "tecla do chip-8=controles" separada por virgulas,
+// ex: "C=Up|LeftY-,D=Down|LeftY+". Funciona como o ParseKeymap.
+func ParseGamepadMap(spec string) (GamepadMap, error) {
+	gamepad := GamepadMap{}
+	for _, entry := range strings.Split(spec, ",") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid gamepad entry %q (expected chip8key=control, e.g. 5=A)", entry)
+		}
+		key, err := strconv.ParseUint(strings.TrimSpace(parts[0]), 16, 8)
+		if err != nil || key > 0xF {
+			return nil, fmt.Errorf("invalid CHIP-8 key %q (expected 0-F)", parts[0])
+		}
+		gamepad[uint16(key)] = nil
+		for _, name := range strings.Split(parts[1], "|") {
+			control, err := ParseGamepadControl(name)
+			if err != nil {
+				return nil, err
+			}
+			gamepad.Bind(uint16(key), control)
+		}
+	}
+	return gamepad, nil
+}
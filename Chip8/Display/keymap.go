@@ -0,0 +1,239 @@
+package Display
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/faiface/pixel/pixelgl"
+)
+
+// Keymap liga cada tecla do Chip-8 (0x0 - 0xF) às teclas do teclado que a acionam.
+// Uma tecla do Chip-8 pode ter várias teclas do teclado, ex: dois jogadores no Pong.
+type Keymap map[uint16][]pixelgl.Button
+
+// Ordem das teclas no teclado hexadecimal do COSMAC VIP, linha por linha
+var HexPadOrder = []uint16{
+	0x1, 0x2, 0x3, 0xC,
+	0x4, 0x5, 0x6, 0xD,
+	0x7, 0x8, 0x9, 0xE,
+	0xA, 0x0, 0xB, 0xF,
+}
+
+// Cria um Keymap que coloca as teclas em buttons na mesma posição do teclado hexadecimal
+func gridKeymap(buttons ...pixelgl.Button) Keymap {
+	keymap := Keymap{}
+	for i, key := range HexPadOrder {
+		keymap[key] = []pixelgl.Button{buttons[i]}
+	}
+	return keymap
+}
+
+// 1234/QWER/ASDF/ZXCV, o teclado hexadecimal no canto do teclado
+var qwerty = gridKeymap(
+	pixelgl.Key1, pixelgl.Key2, pixelgl.Key3, pixelgl.Key4,
+	pixelgl.KeyQ, pixelgl.KeyW, pixelgl.KeyE, pixelgl.KeyR,
+	pixelgl.KeyA, pixelgl.KeyS, pixelgl.KeyD, pixelgl.KeyF,
+	pixelgl.KeyZ, pixelgl.KeyX, pixelgl.KeyC, pixelgl.KeyV,
+)
+
+// Layouts prontos, selecionados pelo nome
+var Presets = map[string]Keymap{
+	"qwerty": qwerty,
+	// Apelido do qwerty: as teclas do pixelgl (GLFW) são posições fisicas com o nome que
+	// elas têm no teclado americano, não o que está escrito nelas. Num teclado AZERTY o
+	// pixelgl.KeyQ é a tecla marcada A, então a mesma grade cai em 1234/AZER/QSDF/WXCV.
+	"azerty": qwerty.Clone(),
+	// 789/ 456* 123- 0.Enter+ no teclado numerico
+	"numpad": gridKeymap(
+		pixelgl.KeyKP7, pixelgl.KeyKP8, pixelgl.KeyKP9, pixelgl.KeyKPDivide,
+		pixelgl.KeyKP4, pixelgl.KeyKP5, pixelgl.KeyKP6, pixelgl.KeyKPMultiply,
+		pixelgl.KeyKP1, pixelgl.KeyKP2, pixelgl.KeyKP3, pixelgl.KeyKPSubtract,
+		pixelgl.KeyKP0, pixelgl.KeyKPDecimal, pixelgl.KeyKPEnter, pixelgl.KeyKPAdd,
+	),
+	// Cada tecla do Chip-8 na tecla com o mesmo nome (0-9, A-F), como no teclado do COSMAC VIP
+	"cosmac": {
+		0x0: {pixelgl.Key0}, 0x1: {pixelgl.Key1}, 0x2: {pixelgl.Key2}, 0x3: {pixelgl.Key3},
+		0x4: {pixelgl.Key4}, 0x5: {pixelgl.Key5}, 0x6: {pixelgl.Key6}, 0x7: {pixelgl.Key7},
+		0x8: {pixelgl.Key8}, 0x9: {pixelgl.Key9}, 0xA: {pixelgl.KeyA}, 0xB: {pixelgl.KeyB},
+		0xC: {pixelgl.KeyC}, 0xD: {pixelgl.KeyD}, 0xE: {pixelgl.KeyE}, 0xF: {pixelgl.KeyF},
+	},
+}
+
+// Layout usado quando nenhum outro é escolhido
+const DefaultPreset = "qwerty"
+
+// Preset retorna uma cópia de um layout pelo nome
+func Preset(name string) (Keymap, error) {
+	keymap, ok := Presets[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("unknown keymap layout %q (available: %s)", name, strings.Join(PresetNames(), ", "))
+	}
+	return keymap.Clone(), nil
+}
+
+// PresetNames retorna os nomes dos layouts em ordem alfabética
+func PresetNames() []string {
+	names := make([]string, 0, len(Presets))
+	for name := range Presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Clone retorna uma cópia que pode ser alterada sem mudar o original
+func (keymap Keymap) Clone() Keymap {
+	clone := Keymap{}
+	for key, buttons := range keymap {
+		clone[key] = append([]pixelgl.Button(nil), buttons...)
+	}
+	return clone
+}
+
+// Bind adiciona button às teclas que acionam key, se ele ainda não estiver lá
+func (keymap Keymap) Bind(key uint16, button pixelgl.Button) {
+	for _, bound := range keymap[key] {
+		if bound == button {
+			return
+		}
+	}
+	keymap[key] = append(keymap[key], button)
+}
+
+// Key retorna a tecla do Chip-8 acionada por button
+func (keymap Keymap) Key(button pixelgl.Button) (uint16, bool) {
+	for key, buttons := range keymap {
+		for _, bound := range buttons {
+			if bound == button {
+				return key, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// Merge troca as teclas do Chip-8 que existirem em other pelas de other.
+// As teclas do teclado usadas em other deixam de acionar as outras teclas do Chip-8.
+func (keymap Keymap) Merge(other Keymap) {
+	for _, buttons := range other {
+		for _, button := range buttons {
+			for key := range keymap {
+				keymap[key] = removeButton(keymap[key], button)
+			}
+		}
+	}
+	for key, buttons := range other {
+		keymap[key] = append([]pixelgl.Button(nil), buttons...)
+	}
+}
+
+// Teclas do teclado sugeridas para as ações do chip-8-database ("up", "player2Down"...).
+// As do jogador 1 ficam no WASD e as do jogador 2 nas setas.
+var actionButtons = map[string]pixelgl.Button{
+	"up":           pixelgl.KeyUp,
+	"down":         pixelgl.KeyDown,
+	"left":         pixelgl.KeyLeft,
+	"right":        pixelgl.KeyRight,
+	"a":            pixelgl.KeySpace,
+	"b":            pixelgl.KeyEnter,
+	"player1Up":    pixelgl.KeyW,
+	"player1Down":  pixelgl.KeyS,
+	"player1Left":  pixelgl.KeyA,
+	"player1Right": pixelgl.KeyD,
+	"player2Up":    pixelgl.KeyUp,
+	"player2Down":  pixelgl.KeyDown,
+	"player2Left":  pixelgl.KeyLeft,
+	"player2Right": pixelgl.KeyRight,
+}
+
+// BindActions adiciona as teclas sugeridas para as ações de uma ROM (ROMInfo.Keys).
+// Teclas do teclado que já acionam outra tecla do Chip-8 não são usadas.
+func (keymap Keymap) BindActions(actions map[string]byte) {
+	names := make([]string, 0, len(actions))
+	for name := range actions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		button, ok := actionButtons[name]
+		key := uint16(actions[name])
+		if !ok || key > 0xF {
+			continue
+		}
+		if bound, used := keymap.Key(button); used && bound != key {
+			continue
+		}
+		keymap.Bind(key, button)
+	}
+}
+
+// String retorna o keymap no formato do ParseKeymap
+func (keymap Keymap) String() string {
+	var entries []string
+	for _, key := range HexPadOrder {
+		if buttons := keymap[key]; len(buttons) > 0 {
+			entries = append(entries, fmt.Sprintf("%X=%s", key, joinButtons(buttons)))
+		}
+	}
+	return strings.Join(entries, ",")
+}
+
+func joinButtons(buttons []pixelgl.Button) string {
+	names := make([]string, len(buttons))
+	for i, button := range buttons {
+		names[i] = button.String()
+	}
+	return strings.Join(names, "|")
+}
+
+// Teclas do teclado pelo nome do pixelgl ("A", "Space", "Left"...), sem diferenciar maiusculas
+var buttonsByName = func() map[string]pixelgl.Button {
+	buttons := map[string]pixelgl.Button{}
+	for button := pixelgl.KeySpace; button <= pixelgl.KeyLast; button++ {
+		if name := button.String(); name != "Invalid" {
+			buttons[strings.ToLower(name)] = button
+		}
+	}
+	return buttons
+}()
+
+// ParseButton converte o nome de uma tecla ("W", "Space", "KP5"...) em pixelgl.Button
+func ParseButton(name string) (pixelgl.Button, error) {
+	button, ok := buttonsByName[strings.ToLower(strings.TrimSpace(name))]
+	if !ok {
+		return 0, fmt.Errorf("unknown key %q", name)
+	}
+	return button, nil
+}
+
+// ParseKeymap lê uma lista "tecla do chip-8=teclas do teclado" separada por virgulas,
+// ex: "5=Up|W,8=Down,4=Left,6=Right". As teclas do Chip-8 são hexadecimais (0-F)
+// e várias teclas do teclado são separadas por "|".
+func ParseKeymap(spec string) (Keymap, error) {
+	keymap := Keymap{}
+	for _, entry := range strings.Split(spec, ",") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid keymap entry %q (expected chip8key=key, e.g. 5=Up)", entry)
+		}
+		key, err := strconv.ParseUint(strings.TrimSpace(parts[0]), 16, 8)
+		if err != nil || key > 0xF {
+			return nil, fmt.Errorf("invalid CHIP-8 key %q (expected 0-F)", parts[0])
+		}
+		keymap[uint16(key)] = nil
+		for _, name := range strings.Split(parts[1], "|") {
+			button, err := ParseButton(name)
+			if err != nil {
+				return nil, err
+			}
+			keymap.Bind(uint16(key), button)
+		}
+	}
+	return keymap, nil
+}
@@ -0,0 +1,25 @@
+package Display
+
+import (
+	"reflect"
+	"testing"
+)
+
+// As teclas do pixelgl são posições fisicas, então o azerty é a mesma grade do qwerty
+func TestAzertyPreset(t *testing.T) {
+	qwerty, err := Preset("qwerty")
+	if err != nil {
+		t.Fatal(err)
+	}
+	azerty, err := Preset("AZERTY")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(azerty, qwerty) {
+		t.Errorf("azerty = %v, want the qwerty grid %v", azerty, qwerty)
+	}
+	azerty[0x5] = nil
+	if len(Presets["qwerty"][0x5]) == 0 || len(Presets["azerty"][0x5]) == 0 {
+		t.Errorf("changing a copy of azerty changed the presets")
+	}
+}
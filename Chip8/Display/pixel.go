@@ -2,88 +2,149 @@ package Display
 
 import (
 	"fmt"
-	"time"
 
 	"github.com/faiface/pixel"
 	"github.com/faiface/pixel/imdraw"
 	"github.com/faiface/pixel/pixelgl"
-	"golang.org/x/image/colornames"
+	"github.com/mellotonio/go-chip8/Chip8"
 )
 
-// Fonte em hexadecimal
-var FontSet = [80]byte{
-	0xF0, 0x90, 0x90, 0x90, 0xF0, // 0
-	0x20, 0x60, 0x20, 0x20, 0x70, // 1
-	0xF0, 0x10, 0xF0, 0x80, 0xF0, // 2
-	0xF0, 0x10, 0xF0, 0x10, 0xF0, // 3
-	0x90, 0x90, 0xF0, 0x10, 0x10, // 4
-	0xF0, 0x80, 0xF0, 0x10, 0xF0, // 5
-	0xF0, 0x80, 0xF0, 0x90, 0xF0, // 6
-	0xF0, 0x10, 0x20, 0x40, 0x40, // 7
-	0xF0, 0x90, 0xF0, 0x90, 0xF0, // 8
-	0xF0, 0x90, 0xF0, 0x10, 0xF0, // 9
-	0xF0, 0x90, 0xF0, 0x90, 0x90, // A
-	0xE0, 0x90, 0xe0, 0x90, 0xE0, // B
-	0xF0, 0x80, 0x80, 0x80, 0x80, // C
-	0xF0, 0x90, 0x90, 0x90, 0xE0, // D
-	0xF0, 0x80, 0xF0, 0x80, 0xF0, // E
-	0xF0, 0x80, 0xF0, 0x80, 0x80, // F
-}
-
 const windowX float64 = 64
 const windowY float64 = 32
 
+// Tamanho padrão da janela
 const screenWidth float64 = 1024
 const screenHeight float64 = 768
 
+// Option configura a janela criada por NewWindow
+type Option func(*config)
+
+type config struct {
+	scale         int
+	width, height int
+	fullscreen    bool
+	keymap        Keymap
+	gamepads      []GamepadMap
+}
+
+// WithScale faz cada pixel do Chip-8 (em 64x32) ocupar scale x scale pixels da janela
+func WithScale(scale int) Option {
+	return func(cfg *config) {
+		cfg.scale = scale
+	}
+}
+
+// WithSize define o tamanho da janela em pixels, WithScale vale mais se os dois forem usados
+func WithSize(width, height int) Option {
+	return func(cfg *config) {
+		cfg.width, cfg.height = width, height
+	}
+}
+
+// WithKeymap troca as teclas do Chip-8 (o padrão é o layout DefaultPreset)
+func WithKeymap(keymap Keymap) Option {
+	return func(cfg *config) {
+		cfg.keymap = keymap
+	}
+}
+
+// WithGamepads define o mapa de cada controle, o primeiro é o do controle 1 (o padrão é
+// NewGamepadMap sem ações para os dois controles)
+func WithGamepads(gamepads ...GamepadMap) Option {
+	return func(cfg *config) {
+		cfg.gamepads = gamepads
+	}
+}
+
+// WithFullscreen abre a janela em tela cheia no monitor principal
+func WithFullscreen(fullscreen bool) Option {
+	return func(cfg *config) {
+		cfg.fullscreen = fullscreen
+	}
+}
+
 type Window struct {
 	*pixelgl.Window
-	KeyMap   map[uint16]pixelgl.Button
-	KeysDown [16]*time.Ticker
+	KeyMap Keymap
+	// Mapa de cada controle, Gamepads[0] é o do primeiro joystick
+	Gamepads []GamepadMap
+	// Hotkeys que não fazem parte do teclado do Chip-8
+	Hotkeys map[pixelgl.Button]Chip8.Command
+
+	hotkeysDown map[pixelgl.Button]bool
+	lastFrame   Chip8.Frame // Ultimo quadro, redesenhado embaixo da tela de remapeamento
+	remap       *remapState // Tela de remapeamento aberta (nil = fechada)
+	connected   [Gamepads]bool
 }
 
 // https://github.com/faiface/pixel/wiki/Creating-a-Window
-func NewWindow() (*Window, error) {
+func NewWindow(opts ...Option) (*Window, error) {
+	var options config
+	for _, opt := range opts {
+		opt(&options)
+	}
+
 	cfg := pixelgl.WindowConfig{
 		Title:  "XP-8",
-		Bounds: pixel.R(0, 0, 1024, 768), // Rectangle minX, minY, maxX, maxY
+		Bounds: pixel.R(0, 0, screenWidth, screenHeight), // Rectangle minX, minY, maxX, maxY
 		VSync:  true,
 	}
+	if options.width > 0 && options.height > 0 {
+		cfg.Bounds = pixel.R(0, 0, float64(options.width), float64(options.height))
+	}
+	if options.scale > 0 {
+		cfg.Bounds = pixel.R(0, 0, windowX*float64(options.scale), windowY*float64(options.scale))
+	}
+	if options.fullscreen {
+		cfg.Monitor = pixelgl.PrimaryMonitor()
+		cfg.Bounds = pixel.R(0, 0, 0, 0)
+		cfg.Bounds.Max.X, cfg.Bounds.Max.Y = cfg.Monitor.Size()
+	}
 	w, err := pixelgl.NewWindow(cfg)
 	if err != nil {
 		return nil, fmt.Errorf("error creating new window: %v", err)
 	}
 	// Teclas utilizadas no chip-8
-	km := map[uint16]pixelgl.Button{
-		0x1: pixelgl.Key1, 0x2: pixelgl.Key2,
-		0x3: pixelgl.Key3, 0xC: pixelgl.Key4,
-		0x4: pixelgl.KeyQ, 0x5: pixelgl.KeyW,
-		0x6: pixelgl.KeyE, 0xD: pixelgl.KeyR,
-		0x7: pixelgl.KeyA, 0x8: pixelgl.KeyS,
-		0x9: pixelgl.KeyD, 0xE: pixelgl.KeyF,
-		0xA: pixelgl.KeyZ, 0x0: pixelgl.KeyX,
-		0xB: pixelgl.KeyC, 0xF: pixelgl.KeyV,
+	km := options.keymap
+	if km == nil {
+		km = Presets[DefaultPreset].Clone()
+	}
+	gamepads := options.gamepads
+	if gamepads == nil {
+		for player := 1; player <= Gamepads; player++ {
+			gamepads = append(gamepads, NewGamepadMap(player, nil))
+		}
 	}
 	return &Window{
 		Window:   w,
 		KeyMap:   km,
-		KeysDown: [16]*time.Ticker{},
+		Gamepads: gamepads,
+		Hotkeys: map[pixelgl.Button]Chip8.Command{
+			pixelgl.KeyF5:        Chip8.CommandSaveState,
+			pixelgl.KeyF9:        Chip8.CommandLoadState,
+			pixelgl.KeyBackspace: Chip8.CommandRewind,
+		},
+		hotkeysDown: map[pixelgl.Button]bool{},
 	}, nil
 }
 
-func (w *Window) DrawGraphics(gfx [64 * 32]byte) {
-	w.Clear(colornames.Black)
+// Render desenha o quadro escalado para o tamanho da janela, usando a paleta do quadro
+func (w *Window) Render(frame Chip8.Frame) {
+	w.lastFrame = frame
+	w.Clear(frame.Palette[0])
 	imDraw := imdraw.New(nil)
-	imDraw.Color = pixel.RGB(1, 1, 1)
-	width, height := screenWidth/windowX, screenHeight/windowY
+	bounds := w.Bounds()
+	width, height := bounds.W()/float64(frame.Width), bounds.H()/float64(frame.Height)
 
-	for i := 0; i < 64; i++ {
-		for j := 0; j < 32; j++ {
+	for i := 0; i < frame.Width; i++ {
+		for j := 0; j < frame.Height; j++ {
 			// If the gfx byte in question is turned off,
 			// continue and skip drawing the rectangle
-			if gfx[(31-j)*64+i] == 0 {
+			p := frame.Pixels[(frame.Height-1-j)*frame.Width+i]
+			if p == 0 {
 				continue
 			}
+			imDraw.Color = frame.Palette[p&3]                         // Cor do bitplane (XO-CHIP)
 			imDraw.Push(pixel.V(width*float64(i), height*float64(j))) // Adiciona um pixel desenhado nas coordenadas x,y
 			imDraw.Push(pixel.V(width*float64(i)+width, height*float64(j)+height))
 			imDraw.Rectangle(0)
@@ -91,5 +152,71 @@ func (w *Window) DrawGraphics(gfx [64 * 32]byte) {
 	}
 
 	imDraw.Draw(w)
+	if w.remap != nil {
+		w.drawRemap()
+	}
 	w.Update()
 }
+
+// KeyState retorna quais teclas do Chip-8 estão pressionadas agora, no teclado ou nos controles.
+// Enquanto a tela de remapeamento estiver aberta, nenhuma tecla chega no Chip-8.
+func (w *Window) KeyState() [16]bool {
+	w.UpdateInput()
+
+	var keys [16]bool
+	remapDown := w.Pressed(RemapHotkey)
+	if w.remap == nil && remapDown && !w.hotkeysDown[RemapHotkey] {
+		w.startRemap()
+	}
+	w.hotkeysDown[RemapHotkey] = remapDown
+	if w.remap != nil {
+		w.updateRemap()
+		return keys
+	}
+	for i, buttons := range w.KeyMap {
+		for _, button := range buttons {
+			keys[i] = keys[i] || w.Pressed(button)
+		}
+	}
+	for i, gamepad := range w.Gamepads {
+		if i >= Gamepads {
+			break
+		}
+		js := pixelgl.Joystick1 + pixelgl.Joystick(i)
+		present := w.JoystickPresent(js)
+		if present != w.connected[i] {
+			w.connected[i] = present
+			if present {
+				fmt.Printf("gamepad %d connected: %s\n", i+1, w.JoystickName(js))
+			} else {
+				fmt.Printf("gamepad %d disconnected\n", i+1)
+			}
+		}
+		if !present {
+			continue
+		}
+		for key, controls := range gamepad {
+			for _, control := range controls {
+				keys[key] = keys[key] || control.Pressed(w.Window, js)
+			}
+		}
+	}
+	return keys
+}
+
+// Commands retorna os comandos dos hotkeys pressionados desde a ultima chamada.
+// O rewind é repetido a cada chamada enquanto o hotkey estiver segurado.
+func (w *Window) Commands() []Chip8.Command {
+	var commands []Chip8.Command
+	if w.remap != nil {
+		return nil
+	}
+	for button, command := range w.Hotkeys {
+		down := w.Pressed(button)
+		if down && (!w.hotkeysDown[button] || command == Chip8.CommandRewind) {
+			commands = append(commands, command)
+		}
+		w.hotkeysDown[button] = down
+	}
+	return commands
+}
@@ -0,0 +1,151 @@
+package Display
+
+import (
+	"fmt"
+	"image/color"
+	"strings"
+
+	"github.com/faiface/pixel"
+	"github.com/faiface/pixel/imdraw"
+	"github.com/faiface/pixel/pixelgl"
+	"github.com/faiface/pixel/text"
+	"golang.org/x/image/font/basicfont"
+)
+
+// Tecla que abre a tela de remapeamento. Nela cada tecla do Chip-8 é pedida na ordem do
+// teclado hexadecimal; Tab mantém as teclas atuais e Esc cancela.
+const RemapHotkey = pixelgl.KeyF2
+
+var remapAtlas = text.NewAtlas(basicfont.Face7x13, text.ASCII)
+
+type remapState struct {
+	index    int    // Posição em HexPadOrder da tecla sendo pedida
+	keymap   Keymap // Keymap novo, montado tecla por tecla
+	previous Keymap // Keymap antigo, volta se o remapeamento for cancelado
+	down     map[pixelgl.Button]bool
+}
+
+func (w *Window) startRemap() {
+	w.remap = &remapState{
+		keymap:   Keymap{},
+		previous: w.KeyMap.Clone(),
+		down:     w.buttonsDown(),
+	}
+	w.Render(w.lastFrame)
+}
+
+// Teclas seguradas agora, para que só as que forem apertadas depois contem
+func (w *Window) buttonsDown() map[pixelgl.Button]bool {
+	down := map[pixelgl.Button]bool{}
+	for button := pixelgl.KeySpace; button <= pixelgl.KeyLast; button++ {
+		if w.Pressed(button) {
+			down[button] = true
+		}
+	}
+	return down
+}
+
+func (w *Window) updateRemap() {
+	remap := w.remap
+	now := w.buttonsDown()
+	var pressed []pixelgl.Button
+	for button := range now {
+		if !remap.down[button] {
+			pressed = append(pressed, button)
+		}
+	}
+	remap.down = now
+	if len(pressed) == 0 {
+		return
+	}
+
+	key := HexPadOrder[remap.index]
+	switch button := pressed[0]; button {
+	case pixelgl.KeyEscape:
+		w.KeyMap = remap.previous
+		w.remap = nil
+		fmt.Println("key remapping cancelled")
+	case RemapHotkey:
+		return
+	case pixelgl.KeyTab:
+		remap.keymap[key] = append([]pixelgl.Button(nil), remap.previous[key]...)
+		remap.index++
+	default:
+		// Cada tecla do teclado aciona uma só tecla do Chip-8
+		if other, used := remap.keymap.Key(button); used {
+			remap.keymap[other] = removeButton(remap.keymap[other], button)
+		}
+		remap.keymap[key] = []pixelgl.Button{button}
+		remap.index++
+	}
+
+	if w.remap != nil && remap.index == len(HexPadOrder) {
+		w.KeyMap = remap.keymap
+		w.remap = nil
+		fmt.Printf("new keymap (use it with -keymap or in the config file): %s\n", w.KeyMap)
+	}
+	w.Render(w.lastFrame)
+}
+
+func removeButton(buttons []pixelgl.Button, button pixelgl.Button) []pixelgl.Button {
+	var kept []pixelgl.Button
+	for _, b := range buttons {
+		if b != button {
+			kept = append(kept, b)
+		}
+	}
+	return kept
+}
+
+// Desenha a tela de remapeamento por cima do quadro atual
+func (w *Window) drawRemap() {
+	bounds := w.Bounds()
+
+	shade := imdraw.New(nil)
+	shade.Color = color.RGBA{0, 0, 0, 0xD0}
+	shade.Push(bounds.Min, bounds.Max)
+	shade.Rectangle(0)
+	shade.Draw(w)
+
+	key := HexPadOrder[w.remap.index]
+	var lines []string
+	lines = append(lines,
+		"REMAP KEYS",
+		"",
+		fmt.Sprintf("Press the key for CHIP-8 key %X", key),
+		fmt.Sprintf("Tab keeps %s, Esc cancels", buttonNames(w.remap.previous[key])),
+		"",
+	)
+	for row := 0; row < 4; row++ {
+		var cells []string
+		for _, k := range HexPadOrder[row*4 : row*4+4] {
+			name := "?"
+			if k == key {
+				name = ">"
+			} else if buttons, ok := w.remap.keymap[k]; ok {
+				name = buttonNames(buttons)
+			}
+			cells = append(cells, fmt.Sprintf("%X:%-8s", k, name))
+		}
+		lines = append(lines, strings.Join(cells, " "))
+	}
+
+	txt := text.New(pixel.ZV, remapAtlas)
+	txt.Color = color.White
+	for _, line := range lines {
+		fmt.Fprintln(txt, line)
+	}
+	scale := bounds.W() / (txt.Bounds().W() + 40)
+	if scale > 3 {
+		scale = 3
+	}
+	position := bounds.Center().Sub(txt.Bounds().Center().Scaled(scale))
+	txt.Draw(w, pixel.IM.Scaled(pixel.ZV, scale).Moved(position))
+}
+
+func buttonNames(buttons []pixelgl.Button) string {
+	if len(buttons) == 0 {
+		return "nothing"
+	}
+	return joinButtons(buttons)
+}
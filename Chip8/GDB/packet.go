@@ -0,0 +1,112 @@
+package GDB
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// Conexão com o gdb. Os pacotes têm o formato "$<dados>#<checksum>" e são confirmados com "+".
+type connection struct {
+	w          io.Writer
+	packets    chan string
+	interrupts chan struct{} // Ctrl-C do gdb (byte 0x03 fora de um pacote)
+	errs       chan error
+}
+
+var errChecksum = errors.New("invalid packet checksum")
+
+// Lê os pacotes da conexão até ela fechar
+func (c *connection) read(r *bufio.Reader) {
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			c.errs <- err
+			return
+		}
+
+		switch b {
+		case 0x03:
+			select {
+			case c.interrupts <- struct{}{}:
+			default:
+			}
+		case '$':
+			packet, err := readPacket(r)
+			if err == errChecksum {
+				c.w.Write([]byte("-"))
+				continue
+			}
+			if err != nil {
+				c.errs <- err
+				return
+			}
+			c.w.Write([]byte("+"))
+			c.packets <- packet
+		}
+		// '+' e '-' do gdb são ignorados, os pacotes não são reenviados
+	}
+}
+
+func readPacket(r *bufio.Reader) (string, error) {
+	data, err := r.ReadBytes('#')
+	if err != nil {
+		return "", err
+	}
+	data = data[:len(data)-1]
+
+	sum := make([]byte, 2)
+	if _, err := io.ReadFull(r, sum); err != nil {
+		return "", err
+	}
+	expected, err := strconv.ParseUint(string(sum), 16, 8)
+	if err != nil || byte(expected) != checksum(data) {
+		return "", errChecksum
+	}
+
+	return string(unescape(data)), nil
+}
+
+// Remove o escape "}" (o byte seguinte xor 0x20) usado pelo gdb em pacotes binarios
+func unescape(data []byte) []byte {
+	out := make([]byte, 0, len(data))
+	for i := 0; i < len(data); i++ {
+		if data[i] == '}' && i+1 < len(data) {
+			i++
+			out = append(out, data[i]^0x20)
+			continue
+		}
+		out = append(out, data[i])
+	}
+	return out
+}
+
+func checksum(data []byte) byte {
+	var sum byte
+	for _, b := range data {
+		sum += b
+	}
+	return sum
+}
+
+func (c *connection) send(packet string) error {
+	data := escape([]byte(packet))
+	_, err := fmt.Fprintf(c.w, "$%s#%02x", data, checksum(data))
+	return err
+}
+
+// Caracteres especiais do protocolo precisam de escape na resposta
+func escape(data []byte) []byte {
+	out := make([]byte, 0, len(data))
+	for _, b := range data {
+		switch b {
+		case '$', '#', '}', '*':
+			out = append(out, '}', b^0x20)
+		default:
+			out = append(out, b)
+		}
+	}
+	return out
+}
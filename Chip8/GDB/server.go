@@ -0,0 +1,429 @@
+package GDB
+
+import (
+	"bufio"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/mellotonio/go-chip8/Chip8"
+)
+
+// Descrição dos registradores enviada para o gdb (qXfer:features:read:target.xml).
+// Ordem e tamanho devem bater com o pacote "g": V0 - VF (8 bits), I e PC (16 bits), SP, DT e ST (8 bits).
+const targetXML = `<?xml version="1.0"?>
+<!DOCTYPE target SYSTEM "gdb-target.dtd">
+<target version="1.0">
+  <feature name="org.xp8.chip8">
+    <reg name="v0" bitsize="8" type="uint8" regnum="0"/>
+    <reg name="v1" bitsize="8" type="uint8"/>
+    <reg name="v2" bitsize="8" type="uint8"/>
+    <reg name="v3" bitsize="8" type="uint8"/>
+    <reg name="v4" bitsize="8" type="uint8"/>
+    <reg name="v5" bitsize="8" type="uint8"/>
+    <reg name="v6" bitsize="8" type="uint8"/>
+    <reg name="v7" bitsize="8" type="uint8"/>
+    <reg name="v8" bitsize="8" type="uint8"/>
+    <reg name="v9" bitsize="8" type="uint8"/>
+    <reg name="va" bitsize="8" type="uint8"/>
+    <reg name="vb" bitsize="8" type="uint8"/>
+    <reg name="vc" bitsize="8" type="uint8"/>
+    <reg name="vd" bitsize="8" type="uint8"/>
+    <reg name="ve" bitsize="8" type="uint8"/>
+    <reg name="vf" bitsize="8" type="uint8"/>
+    <reg name="i" bitsize="16" type="data_ptr"/>
+    <reg name="pc" bitsize="16" type="code_ptr"/>
+    <reg name="sp" bitsize="8" type="uint8"/>
+    <reg name="dt" bitsize="8" type="uint8"/>
+    <reg name="st" bitsize="8" type="uint8"/>
+  </feature>
+</target>
+`
+
+// Nomes dos registradores na ordem do pacote "g", como o Debugger conhece
+var registerNames = []string{
+	"V0", "V1", "V2", "V3", "V4", "V5", "V6", "V7",
+	"V8", "V9", "VA", "VB", "VC", "VD", "VE", "VF",
+	"I", "PC", "SP", "DT", "ST",
+}
+
+// Tamanho em bytes de cada registrador no pacote "g"
+func registerSize(n int) int {
+	if n == 16 || n == 17 {
+		return 2
+	}
+	return 1
+}
+
+// Server implementa o GDB remote serial protocol em cima do Debugger da Machine
+type Server struct {
+	debugger *Chip8.Debugger
+	// Breakpoints criados pelo gdb, pela chave "tipo,endereço,tamanho" dos pacotes Z/z
+	breakpoints map[string]int
+}
+
+func NewServer(debugger *Chip8.Debugger) *Server {
+	return &Server{debugger: debugger, breakpoints: map[string]int{}}
+}
+
+// ListenAndServe espera conexões do gdb no addr (ex: "localhost:1234"), uma de cada vez
+func (s *Server) ListenAndServe(addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	defer listener.Close()
+
+	fmt.Printf("gdb stub listening on %s\n", listener.Addr())
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return err
+		}
+		s.debugger.Pause()
+		if err := s.Serve(conn); err != nil && !errors.Is(err, io.EOF) {
+			fmt.Printf("gdb connection closed: %v\n", err)
+		}
+		conn.Close()
+	}
+}
+
+// Serve atende uma conexão já aberta até o gdb desconectar
+func (s *Server) Serve(conn io.ReadWriter) error {
+	c := &connection{w: conn, packets: make(chan string), interrupts: make(chan struct{}, 1), errs: make(chan error, 1)}
+	go c.read(bufio.NewReader(conn))
+
+	for {
+		select {
+		case err := <-c.errs:
+			return err
+		case <-c.interrupts:
+			s.debugger.Pause()
+		case packet := <-c.packets:
+			reply, resume, done := s.handle(packet)
+			if resume {
+				reply = s.waitStop(c)
+				if reply == "" {
+					return io.EOF
+				}
+			}
+			if err := c.send(reply); err != nil {
+				return err
+			}
+			if done {
+				return nil
+			}
+		}
+	}
+}
+
+// Executa um pacote. resume indica que a Machine voltou a rodar e a resposta é o proximo stop.
+func (s *Server) handle(packet string) (reply string, resume bool, done bool) {
+	d := s.debugger
+	switch {
+	case packet == "?":
+		return "S05", false, false
+	case packet == "g":
+		return s.readRegisters(), false, false
+	case strings.HasPrefix(packet, "G"):
+		return s.writeRegisters(packet[1:]), false, false
+	case strings.HasPrefix(packet, "p"):
+		return s.readRegister(packet[1:]), false, false
+	case strings.HasPrefix(packet, "P"):
+		return s.writeRegister(packet[1:]), false, false
+	case strings.HasPrefix(packet, "m"):
+		return s.readMemory(packet[1:]), false, false
+	case strings.HasPrefix(packet, "M"):
+		return s.writeMemory(packet[1:]), false, false
+	case strings.HasPrefix(packet, "Z"):
+		return s.insertBreakpoint(packet[1:]), false, false
+	case strings.HasPrefix(packet, "z"):
+		return s.removeBreakpoint(packet[1:]), false, false
+	case strings.HasPrefix(packet, "s"):
+		s.drainStops()
+		if err := d.Step(); err != nil {
+			return "E01", false, false
+		}
+		return "", true, false
+	case strings.HasPrefix(packet, "c"):
+		s.drainStops()
+		d.Continue()
+		return "", true, false
+	case packet == "D" || strings.HasPrefix(packet, "D;"):
+		d.Continue()
+		return "OK", false, true
+	case packet == "k":
+		d.Continue()
+		return "", false, true
+	case strings.HasPrefix(packet, "qSupported"):
+		return "PacketSize=4000;qXfer:features:read+", false, false
+	case strings.HasPrefix(packet, "qXfer:features:read:target.xml:"):
+		return xfer(targetXML, strings.TrimPrefix(packet, "qXfer:features:read:target.xml:")), false, false
+	case packet == "qAttached":
+		return "1", false, false
+	case packet == "qC":
+		return "QC1", false, false
+	case packet == "qfThreadInfo":
+		return "m1", false, false
+	case packet == "qsThreadInfo":
+		return "l", false, false
+	case strings.HasPrefix(packet, "H"), packet == "!":
+		return "OK", false, false
+	}
+	// Pacotes desconhecidos recebem uma resposta vazia, como manda o protocolo
+	return "", false, false
+}
+
+// Espera a Machine parar (ou o gdb pedir uma interrupção) e monta a resposta de stop
+func (s *Server) waitStop(c *connection) string {
+	for {
+		select {
+		case event := <-s.debugger.Stops():
+			return stopReply(event)
+		case <-c.interrupts:
+			s.debugger.Pause()
+		case err := <-c.errs:
+			c.errs <- err
+			s.debugger.Pause()
+			return ""
+		case packet := <-c.packets:
+			// Enquanto a Machine roda o gdb só deveria mandar interrupções, o resto é ignorado
+			_ = packet
+		}
+	}
+}
+
+func stopReply(event Chip8.StopEvent) string {
+	if event.Reason == Chip8.StopWatchpoint {
+		kind := "watch"
+		switch event.Breakpoint.Kind {
+		case Chip8.BreakRead:
+			kind = "rwatch"
+		case Chip8.BreakAccess:
+			kind = "awatch"
+		}
+		return fmt.Sprintf("T05%s:%x;", kind, event.Addr)
+	}
+	if event.Reason == Chip8.StopPause {
+		return "S02" // SIGINT
+	}
+	if event.Reason == Chip8.StopFault {
+		if _, ok := event.Err.(Chip8.ErrUnknownOpcode); ok {
+			return "S04" // SIGILL
+		}
+		return "S0b" // SIGSEGV, memoria ou pilha
+	}
+	return "S05" // SIGTRAP
+}
+
+// Descarta stops antigos para o proximo step/continue esperar o stop certo
+func (s *Server) drainStops() {
+	for {
+		select {
+		case <-s.debugger.Stops():
+		default:
+			return
+		}
+	}
+}
+
+func (s *Server) registerValues() []int {
+	state := s.debugger.State()
+	values := make([]int, 0, len(registerNames))
+	for _, v := range state.Registers {
+		values = append(values, int(v))
+	}
+	return append(values,
+		int(state.Index), int(state.ProgramCounter), int(state.StackPointer),
+		int(state.DelayTimer), int(state.SoundTimer))
+}
+
+func (s *Server) readRegisters() string {
+	var b strings.Builder
+	for n, v := range s.registerValues() {
+		b.WriteString(encodeRegister(n, v))
+	}
+	return b.String()
+}
+
+func (s *Server) writeRegisters(data string) string {
+	for n := range registerNames {
+		size := registerSize(n) * 2
+		if len(data) < size {
+			return "E01"
+		}
+		value, err := decodeRegister(data[:size])
+		if err != nil {
+			return "E01"
+		}
+		data = data[size:]
+		if err := s.debugger.SetRegister(registerNames[n], value); err != nil {
+			return "E01"
+		}
+	}
+	return "OK"
+}
+
+func (s *Server) readRegister(args string) string {
+	n, err := strconv.ParseUint(args, 16, 8)
+	if err != nil || int(n) >= len(registerNames) {
+		return "E01"
+	}
+	return encodeRegister(int(n), s.registerValues()[n])
+}
+
+func (s *Server) writeRegister(args string) string {
+	parts := strings.SplitN(args, "=", 2)
+	if len(parts) != 2 {
+		return "E01"
+	}
+	n, err := strconv.ParseUint(parts[0], 16, 8)
+	if err != nil || int(n) >= len(registerNames) {
+		return "E01"
+	}
+	value, err := decodeRegister(parts[1])
+	if err != nil {
+		return "E01"
+	}
+	if err := s.debugger.SetRegister(registerNames[n], value); err != nil {
+		return "E01"
+	}
+	return "OK"
+}
+
+func (s *Server) readMemory(args string) string {
+	addr, length, ok := parseRange(args)
+	if !ok {
+		return "E01"
+	}
+	data := s.debugger.ReadMemory(addr, length)
+	if data == nil {
+		return "E14" // EFAULT
+	}
+	return hex.EncodeToString(data)
+}
+
+func (s *Server) writeMemory(args string) string {
+	parts := strings.SplitN(args, ":", 2)
+	if len(parts) != 2 {
+		return "E01"
+	}
+	addr, length, ok := parseRange(parts[0])
+	if !ok {
+		return "E01"
+	}
+	data, err := hex.DecodeString(parts[1])
+	if err != nil || len(data) != length {
+		return "E01"
+	}
+	if err := s.debugger.WriteMemory(addr, data); err != nil {
+		return "E14"
+	}
+	return "OK"
+}
+
+// Z0 (software), Z1 (hardware), Z2 (write), Z3 (read) e Z4 (access) - "Z<tipo>,<endereço>,<tamanho>"
+func (s *Server) insertBreakpoint(args string) string {
+	parts := strings.Split(args, ",")
+	if len(parts) < 3 {
+		return "E01"
+	}
+	addr, length, ok := parseRange(parts[1] + "," + parts[2])
+	if !ok {
+		return "E01"
+	}
+
+	var bp *Chip8.Breakpoint
+	var err error
+	switch parts[0] {
+	case "0", "1":
+		bp, err = s.debugger.AddBreakpoint(addr, "")
+	case "2":
+		bp, err = s.debugger.AddWatchpoint(addr, addr+length-1, Chip8.BreakWrite, "")
+	case "3":
+		bp, err = s.debugger.AddWatchpoint(addr, addr+length-1, Chip8.BreakRead, "")
+	case "4":
+		bp, err = s.debugger.AddWatchpoint(addr, addr+length-1, Chip8.BreakAccess, "")
+	default:
+		return ""
+	}
+	if err != nil {
+		return "E01"
+	}
+	s.breakpoints[strings.Join(parts[:3], ",")] = bp.ID
+	return "OK"
+}
+
+func (s *Server) removeBreakpoint(args string) string {
+	parts := strings.Split(args, ",")
+	if len(parts) < 3 {
+		return "E01"
+	}
+	key := strings.Join(parts[:3], ",")
+	id, ok := s.breakpoints[key]
+	if !ok {
+		return "OK"
+	}
+	delete(s.breakpoints, key)
+	if err := s.debugger.RemoveBreakpoint(id); err != nil {
+		return "E01"
+	}
+	return "OK"
+}
+
+// Converte "<endereço>,<tamanho>" em hexadecimal
+func parseRange(args string) (int, int, bool) {
+	parts := strings.SplitN(args, ",", 2)
+	if len(parts) != 2 {
+		return 0, 0, false
+	}
+	addr, err := strconv.ParseUint(parts[0], 16, 32)
+	if err != nil {
+		return 0, 0, false
+	}
+	length, err := strconv.ParseUint(parts[1], 16, 32)
+	if err != nil || length == 0 {
+		return 0, 0, false
+	}
+	return int(addr), int(length), true
+}
+
+// Registradores de 16 bits vão em little endian, a ordem que o gdb espera por padrão
+func encodeRegister(n, value int) string {
+	if registerSize(n) == 2 {
+		return fmt.Sprintf("%02x%02x", value&0xFF, value>>8&0xFF)
+	}
+	return fmt.Sprintf("%02x", value&0xFF)
+}
+
+func decodeRegister(text string) (int, error) {
+	data, err := hex.DecodeString(text)
+	if err != nil {
+		return 0, err
+	}
+	value := 0
+	for i := len(data) - 1; i >= 0; i-- {
+		value = value<<8 | int(data[i])
+	}
+	return value, nil
+}
+
+// Responde um qXfer com o pedaço "<offset>,<tamanho>" do documento
+func xfer(document, args string) string {
+	offset, length, ok := parseRange(args)
+	if !ok {
+		return "E01"
+	}
+	if offset >= len(document) {
+		return "l"
+	}
+	end := offset + length
+	if end >= len(document) {
+		return "l" + document[offset:]
+	}
+	return "m" + document[offset:end]
+}
@@ -0,0 +1,244 @@
+package GDB
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mellotonio/go-chip8/Chip8"
+)
+
+// Lado do gdb da conexão
+type client struct {
+	t    *testing.T
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+// Envia um pacote e retorna a resposta do servidor, confirmando os dois lados com "+"
+func (c *client) exchange(packet string) string {
+	c.t.Helper()
+	c.conn.SetDeadline(time.Now().Add(5 * time.Second))
+	if _, err := fmt.Fprintf(c.conn, "$%s#%02x", packet, checksum([]byte(packet))); err != nil {
+		c.t.Fatal(err)
+	}
+	if ack, err := c.r.ReadByte(); err != nil || ack != '+' {
+		c.t.Fatalf("%s: ack = %q, %v", packet, ack, err)
+	}
+	if start, err := c.r.ReadByte(); err != nil || start != '$' {
+		c.t.Fatalf("%s: reply starts with %q, %v", packet, start, err)
+	}
+	reply, err := readPacket(c.r)
+	if err != nil {
+		c.t.Fatalf("%s: %v", packet, err)
+	}
+	c.conn.Write([]byte("+"))
+	return reply
+}
+
+// Conecta um servidor em uma Machine com o programa e retorna o cliente e o fim do Serve
+func connect(t *testing.T, words ...uint16) (*Chip8.Machine, *client, <-chan error) {
+	t.Helper()
+	rom := make([]byte, 0, len(words)*2)
+	for _, word := range words {
+		rom = append(rom, byte(word>>8), byte(word))
+	}
+	chip_8 := Chip8.New()
+	if err := chip_8.LoadBytes(rom); err != nil {
+		t.Fatal(err)
+	}
+	d := Chip8.NewDebugger(chip_8)
+	d.Pause()
+
+	serverConn, clientConn := net.Pipe()
+	done := make(chan error, 1)
+	go func() { done <- NewServer(d).Serve(serverConn) }()
+	t.Cleanup(func() { clientConn.Close() })
+	return chip_8, &client{t: t, conn: clientConn, r: bufio.NewReader(clientConn)}, done
+}
+
+func TestRegisters(t *testing.T) {
+	// V0 = 12, I = 2A4
+	chip_8, c, _ := connect(t, 0x6012, 0xA2A4, 0x1204)
+	if err := chip_8.Step(); err != nil {
+		t.Fatal(err)
+	}
+	if err := chip_8.Step(); err != nil {
+		t.Fatal(err)
+	}
+
+	// V0-VF, I e PC em little endian, SP, DT e ST
+	want := "12" + strings.Repeat("00", 15) + "a402" + "0402" + "000000"
+	if got := c.exchange("g"); got != want {
+		t.Errorf("g = %s\nwant %s", got, want)
+	}
+	if got := c.exchange("p10"); got != "a402" {
+		t.Errorf("p10 (I) = %s, want a402", got)
+	}
+
+	regs := "12" + "34" + strings.Repeat("00", 14) + "2301" + "0002" + "000705"
+	if got := c.exchange("G" + regs); got != "OK" {
+		t.Fatalf("G = %s", got)
+	}
+	if v := chip_8.Registers(); v[1] != 0x34 || chip_8.Index() != 0x123 || chip_8.ProgramCounter() != 0x200 || chip_8.DelayTimer != 7 || chip_8.SoundTimer != 5 {
+		t.Errorf("after G: V1 = %02X, I = %03X, PC = %03X, DT = %d, ST = %d", v[1], chip_8.Index(), chip_8.ProgramCounter(), chip_8.DelayTimer, chip_8.SoundTimer)
+	}
+	if got := c.exchange("g"); got != regs {
+		t.Errorf("g after G = %s\nwant %s", got, regs)
+	}
+
+	if got := c.exchange("P11=0602"); got != "OK" || chip_8.ProgramCounter() != 0x206 {
+		t.Errorf("P11=0602 = %s, PC = %03X, want OK, 206", got, chip_8.ProgramCounter())
+	}
+	for _, packet := range []string{"p15", "P15=00", "Pzz", "G12"} {
+		if got := c.exchange(packet); got != "E01" {
+			t.Errorf("%s = %q, want E01", packet, got)
+		}
+	}
+}
+
+func TestMemory(t *testing.T) {
+	chip_8, c, _ := connect(t, 0x6012, 0xA2A4)
+	for _, test := range []struct {
+		packet, want string
+	}{
+		{"m200,4", "6012a2a4"},
+		{"M300,3:abcd01", "OK"},
+		{"m2ff,5", "00abcd0100"},
+		{"M300,2:ab", "E01"},
+		{"m200,0", "E01"},
+		{"m10000,2", "E14"},
+		{"M10000,1:00", "E14"},
+	} {
+		if got := c.exchange(test.packet); got != test.want {
+			t.Errorf("%s = %q, want %q", test.packet, got, test.want)
+		}
+	}
+	if data := chip_8.Debugger().ReadMemory(0x300, 3); string(data) != "\xab\xcd\x01" {
+		t.Errorf("memory at 300 = % X", data)
+	}
+}
+
+func TestBreakpoints(t *testing.T) {
+	chip_8, c, _ := connect(t, 0x6000, 0x7001, 0x1202)
+	for _, test := range []struct {
+		packet, want string
+	}{
+		{"Z0,202,2", "OK"},
+		{"Z2,300,4", "OK"},
+		{"Z3,300,1", "OK"},
+		{"Z4,310,2", "OK"},
+		{"Z9,300,1", ""},
+		{"Z0,zz,2", "E01"},
+		{"Z0,202", "E01"},
+		{"z3,300,1", "OK"},
+		{"z3,300,1", "OK"}, // Já removido
+	} {
+		if got := c.exchange(test.packet); got != test.want {
+			t.Errorf("%s = %q, want %q", test.packet, got, test.want)
+		}
+	}
+	var kinds []string
+	for _, bp := range chip_8.Debugger().Breakpoints() {
+		kinds = append(kinds, fmt.Sprintf("%s %X-%X", bp.Kind, bp.Addr, bp.End))
+	}
+	if want := "exec 202-0, write 300-303, access 310-311"; strings.Join(kinds, ", ") != want {
+		t.Errorf("breakpoints = %s, want %s", strings.Join(kinds, ", "), want)
+	}
+}
+
+func TestTargetXML(t *testing.T) {
+	_, c, _ := connect(t)
+	if got := c.exchange("qSupported:multiprocess+"); !strings.Contains(got, "qXfer:features:read+") {
+		t.Errorf("qSupported = %q", got)
+	}
+	// O documento é lido em pedaços, "m" tem mais e "l" é o ultimo
+	var document string
+	for offset := 0; ; offset += 0x100 {
+		reply := c.exchange(fmt.Sprintf("qXfer:features:read:target.xml:%x,100", offset))
+		document += reply[1:]
+		if reply[0] == 'l' {
+			break
+		}
+		if reply[0] != 'm' || len(reply) != 0x101 {
+			t.Fatalf("reply at offset %x = %q", offset, reply)
+		}
+	}
+	if document != targetXML {
+		t.Errorf("target.xml read in parts differs from the document")
+	}
+	if got := c.exchange(fmt.Sprintf("qXfer:features:read:target.xml:%x,10", len(targetXML)+5)); got != "l" {
+		t.Errorf("read past the end = %q, want l", got)
+	}
+	if got := c.exchange("qXfer:features:read:target.xml:zz"); got != "E01" {
+		t.Errorf("bad offset = %q, want E01", got)
+	}
+}
+
+func TestStepContinue(t *testing.T) {
+	chip_8, c, done := connect(t, 0x6000, 0x7001, 0x1202)
+	if got := c.exchange("?"); got != "S05" {
+		t.Errorf("? = %q, want S05", got)
+	}
+	if got := c.exchange("s"); got != "S05" || chip_8.ProgramCounter() != 0x202 {
+		t.Errorf("s = %q, PC = %03X, want S05, 202", got, chip_8.ProgramCounter())
+	}
+
+	go chip_8.Run()
+	defer chip_8.Stop()
+	if got := c.exchange("Z0,204,2"); got != "OK" {
+		t.Fatalf("Z0 = %q", got)
+	}
+	if got := c.exchange("c"); got != "S05" || chip_8.ProgramCounter() != 0x204 {
+		t.Errorf("c = %q, PC = %03X, want S05, 204", got, chip_8.ProgramCounter())
+	}
+	if got := c.exchange("D"); got != "OK" {
+		t.Errorf("D = %q, want OK", got)
+	}
+	if err := <-done; err != nil {
+		t.Errorf("Serve = %v after detaching", err)
+	}
+}
+
+func TestStopReply(t *testing.T) {
+	for _, test := range []struct {
+		event Chip8.StopEvent
+		want  string
+	}{
+		{Chip8.StopEvent{Reason: Chip8.StopBreakpoint}, "S05"},
+		{Chip8.StopEvent{Reason: Chip8.StopStep}, "S05"},
+		{Chip8.StopEvent{Reason: Chip8.StopPause}, "S02"},
+		{Chip8.StopEvent{Reason: Chip8.StopFault, Err: Chip8.ErrUnknownOpcode{}}, "S04"},
+		{Chip8.StopEvent{Reason: Chip8.StopFault, Err: Chip8.ErrStackUnderflow{}}, "S0b"},
+		{Chip8.StopEvent{Reason: Chip8.StopWatchpoint, Addr: 0x301, Breakpoint: &Chip8.Breakpoint{Kind: Chip8.BreakWrite}}, "T05watch:301;"},
+		{Chip8.StopEvent{Reason: Chip8.StopWatchpoint, Addr: 0x302, Breakpoint: &Chip8.Breakpoint{Kind: Chip8.BreakRead}}, "T05rwatch:302;"},
+		{Chip8.StopEvent{Reason: Chip8.StopWatchpoint, Addr: 0x303, Breakpoint: &Chip8.Breakpoint{Kind: Chip8.BreakAccess}}, "T05awatch:303;"},
+	} {
+		if got := stopReply(test.event); got != test.want {
+			t.Errorf("stopReply(%+v) = %q, want %q", test.event, got, test.want)
+		}
+	}
+}
+
+func TestPacketEscape(t *testing.T) {
+	for _, data := range []string{"plain", "a$b#c}d*e"} {
+		var out strings.Builder
+		if err := (&connection{w: &out}).send(data); err != nil {
+			t.Fatal(err)
+		}
+		r := bufio.NewReader(strings.NewReader(out.String()[1:]))
+		if got, err := readPacket(r); err != nil || got != data {
+			t.Errorf("send/readPacket(%q) = %q, %v", data, got, err)
+		}
+	}
+	if _, err := readPacket(bufio.NewReader(strings.NewReader("g#00"))); err != errChecksum {
+		t.Errorf("readPacket with a bad checksum = %v, want errChecksum", err)
+	}
+	if _, err := readPacket(bufio.NewReader(strings.NewReader("g#6"))); err != io.ErrUnexpectedEOF {
+		t.Errorf("readPacket of a truncated packet = %v", err)
+	}
+}
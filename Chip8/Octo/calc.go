@@ -0,0 +1,131 @@
+package Octo
+
+import (
+	"math"
+)
+
+// Expressões do :calc, :byte { } e :pointer { }. Como no Octo, não existe precedencia:
+// a expressão é avaliada da direita para a esquerda e parenteses agrupam.
+
+var unaryOps = map[string]func(float64) float64{
+	"-":     func(x float64) float64 { return -x },
+	"~":     func(x float64) float64 { return float64(^int64(x)) },
+	"!":     func(x float64) float64 { return boolValue(x == 0) },
+	"sin":   math.Sin,
+	"cos":   math.Cos,
+	"tan":   math.Tan,
+	"exp":   math.Exp,
+	"log":   math.Log,
+	"abs":   math.Abs,
+	"sqrt":  math.Sqrt,
+	"ceil":  math.Ceil,
+	"floor": math.Floor,
+	"sign": func(x float64) float64 {
+		switch {
+		case x > 0:
+			return 1
+		case x < 0:
+			return -1
+		}
+		return 0
+	},
+}
+
+var binaryOps = map[string]func(a, b float64) float64{
+	"-":   func(a, b float64) float64 { return a - b },
+	"+":   func(a, b float64) float64 { return a + b },
+	"*":   func(a, b float64) float64 { return a * b },
+	"/":   func(a, b float64) float64 { return a / b },
+	"%":   func(a, b float64) float64 { return float64(int64(a) % nonZero(int64(b))) },
+	"pow": math.Pow,
+	"min": math.Min,
+	"max": math.Max,
+	"&":   func(a, b float64) float64 { return float64(int64(a) & int64(b)) },
+	"|":   func(a, b float64) float64 { return float64(int64(a) | int64(b)) },
+	"^":   func(a, b float64) float64 { return float64(int64(a) ^ int64(b)) },
+	"<<":  func(a, b float64) float64 { return float64(int64(a) << uint64(b)) },
+	">>":  func(a, b float64) float64 { return float64(int64(a) >> uint64(b)) },
+	"<":   func(a, b float64) float64 { return boolValue(a < b) },
+	">":   func(a, b float64) float64 { return boolValue(a > b) },
+	"<=":  func(a, b float64) float64 { return boolValue(a <= b) },
+	">=":  func(a, b float64) float64 { return boolValue(a >= b) },
+	"==":  func(a, b float64) float64 { return boolValue(a == b) },
+	"!=":  func(a, b float64) float64 { return boolValue(a != b) },
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// Evita o panic do Go no resto por zero, o Octo devolve NaN e aqui o resultado vira 0
+func nonZero(b int64) int64 {
+	if b == 0 {
+		return 1
+	}
+	return b
+}
+
+// Lê "{ expr }" a partir do token atual
+func (c *compiler) calcBlock() float64 {
+	open := c.next()
+	if open.text != "{" {
+		c.fail(open, "expected { to start an expression")
+	}
+	value := c.calcExpr()
+	if end := c.next(); end.text != "}" {
+		c.fail(end, "expected } to end the expression, got %q", end.text)
+	}
+	return value
+}
+
+func (c *compiler) calcExpr() float64 {
+	left := c.calcTerm()
+	if op, ok := binaryOps[c.peek().text]; ok {
+		c.next()
+		return op(left, c.calcExpr())
+	}
+	return left
+}
+
+func (c *compiler) calcTerm() float64 {
+	tok := c.next()
+	if tok.text == "(" {
+		value := c.calcExpr()
+		if end := c.next(); end.text != ")" {
+			c.fail(end, "expected ), got %q", end.text)
+		}
+		return value
+	}
+	if op, ok := unaryOps[tok.text]; ok {
+		return op(c.calcTerm())
+	}
+	if tok.text == "@" {
+		addr := int(c.calcTerm())
+		if addr < 0 || addr >= len(c.memory) {
+			c.fail(tok, "address %d is outside the memory", addr)
+		}
+		return float64(c.memory[addr])
+	}
+	switch tok.text {
+	case "HERE":
+		return float64(c.here)
+	case "PI":
+		return math.Pi
+	case "E":
+		return math.E
+	}
+	if n, ok := parseNumber(tok.text); ok {
+		return float64(n)
+	}
+	if value, ok := c.constants[tok.text]; ok {
+		return value
+	}
+	if addr, ok := c.labels[tok.text]; ok {
+		return float64(addr)
+	}
+	c.fail(tok, "undefined name %q in expression", tok.text)
+	return 0
+}
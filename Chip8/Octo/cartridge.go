@@ -0,0 +1,185 @@
+package Octo
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/gif"
+	"strconv"
+	"strings"
+
+	"github.com/mellotonio/go-chip8/Chip8"
+)
+
+func init() {
+	Chip8.RegisterFormat(".gif", func(path string, data []byte) (*Chip8.Program, error) {
+		return LoadCartridge(path, data)
+	})
+	// O .json só é lido ao lado de programas do Octo, um game.json ao lado de game.ch8 pode ser
+	// qualquer outra coisa
+	Chip8.RegisterCompanion(".json", func(path string, data []byte, program *Chip8.Program) error {
+		options, err := ParseOptions(data)
+		if err != nil {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+		options.Apply(program)
+		return nil
+	}, ".8o", ".gif")
+}
+
+// Options são as configurações de um programa no formato do Octo, as mesmas que ficam
+// dentro dos cartuchos e nos arquivos .json exportados por ele
+type Options struct {
+	TickRate        int    `json:"tickrate"` // Instruções por quadro
+	BackgroundColor string `json:"backgroundColor"`
+	FillColor       string `json:"fillColor"`
+	FillColor2      string `json:"fillColor2"`
+	BlendColor      string `json:"blendColor"`
+	ShiftQuirks     *bool  `json:"shiftQuirks"`
+	LoadStoreQuirks *bool  `json:"loadStoreQuirks"` // No Octo o quirk é o I NÃO avançar
+	JumpQuirks      *bool  `json:"jumpQuirks"`
+	LogicQuirks     *bool  `json:"logicQuirks"`
+	ClipQuirks      *bool  `json:"clipQuirks"`
+	VBlankQuirks    *bool  `json:"vBlankQuirks"`
+	MaxSize         int    `json:"maxSize"` // 3216 = SUPER-CHIP, 3583/3584 = CHIP-8, 65024 = XO-CHIP
+}
+
+// ParseOptions lê as opções de um arquivo .json do Octo
+func ParseOptions(data []byte) (*Options, error) {
+	var options Options
+	if err := json.Unmarshal(data, &options); err != nil {
+		return nil, fmt.Errorf("invalid Octo options: %v", err)
+	}
+	return &options, nil
+}
+
+// Apply copia para program as opções que estiverem presentes
+func (options *Options) Apply(program *Chip8.Program) {
+	if options.TickRate > 0 {
+		program.CyclesPerFrame = options.TickRate
+	}
+
+	switch {
+	case options.MaxSize > 4096-0x200:
+		mode := Chip8.XOChipMode
+		program.Mode = &mode
+	case options.MaxSize > 0 && options.MaxSize <= 3216:
+		mode := Chip8.SuperChipMode
+		program.Mode = &mode
+	case options.MaxSize > 0:
+		mode := Chip8.Chip8Mode
+		program.Mode = &mode
+	}
+
+	quirks := Chip8.QuirksProfiles[Chip8.DefaultQuirksProfile]
+	if program.Quirks != nil {
+		quirks = *program.Quirks
+	}
+	set := false
+	apply := func(field *bool, value *bool, invert bool) {
+		if value != nil {
+			*field = *value != invert
+			set = true
+		}
+	}
+	apply(&quirks.Shift, options.ShiftQuirks, false)
+	apply(&quirks.LoadStore, options.LoadStoreQuirks, true)
+	apply(&quirks.Jump, options.JumpQuirks, false)
+	apply(&quirks.VFReset, options.LogicQuirks, false)
+	apply(&quirks.Clipping, options.ClipQuirks, false)
+	apply(&quirks.DisplayWait, options.VBlankQuirks, false)
+	if set {
+		program.Quirks = &quirks
+	}
+
+	palette := Chip8.DefaultPalette
+	if program.Palette != nil {
+		palette = *program.Palette
+	}
+	set = false
+	for i, value := range []string{options.BackgroundColor, options.FillColor, options.FillColor2, options.BlendColor} {
+		if c, ok := parseColor(value); ok {
+			palette[i] = c
+			set = true
+		}
+	}
+	if set {
+		program.Palette = &palette
+	}
+}
+
+// Cores do Octo são "#RRGGBB" ou "#RGB"
+func parseColor(value string) (color.RGBA, bool) {
+	hex := strings.TrimPrefix(value, "#")
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	if len(hex) != 6 || hex == value {
+		return color.RGBA{}, false
+	}
+	rgb, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return color.RGBA{}, false
+	}
+	return color.RGBA{byte(rgb >> 16), byte(rgb >> 8), byte(rgb), 0xFF}, true
+}
+
+// Conteudo de um cartucho: o código fonte e as opções
+type cartridge struct {
+	Program string  `json:"program"`
+	Options Options `json:"options"`
+}
+
+// LoadCartridge extrai o programa e as opções de um cartucho GIF do Octo e compila o programa.
+//
+// O Octo guarda os dados nos 2 bits mais baixos do indice de cada pixel, 4 pixels por byte
+// (bits mais altos primeiro), seguindo os quadros em ordem. Os 4 primeiros bytes são o tamanho
+// (big-endian) de um JSON {"program": "...", "options": {...}}.
+func LoadCartridge(path string, data []byte) (*Chip8.Program, error) {
+	img, err := gif.DecodeAll(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%s: not an Octo cartridge: %v", path, err)
+	}
+
+	payload := cartridgeBytes(img.Image)
+	if len(payload) < 4 {
+		return nil, fmt.Errorf("%s: not an Octo cartridge: no payload", path)
+	}
+	size := int(payload[0])<<24 | int(payload[1])<<16 | int(payload[2])<<8 | int(payload[3])
+	if size <= 0 || size > len(payload)-4 {
+		return nil, fmt.Errorf("%s: not an Octo cartridge: payload size %d is invalid", path, size)
+	}
+
+	var cart cartridge
+	if err := json.Unmarshal(payload[4:4+size], &cart); err != nil {
+		return nil, fmt.Errorf("%s: not an Octo cartridge: %v", path, err)
+	}
+
+	program, err := Compile(path, []byte(cart.Program))
+	if err != nil {
+		return nil, err
+	}
+	cart.Options.Apply(program)
+	return program, nil
+}
+
+func cartridgeBytes(frames []*image.Paletted) []byte {
+	var payload []byte
+	var current byte
+	count := 0
+	for _, frame := range frames {
+		bounds := frame.Bounds()
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			for x := bounds.Min.X; x < bounds.Max.X; x++ {
+				current = current<<2 | frame.ColorIndexAt(x, y)&3
+				if count++; count == 4 {
+					payload = append(payload, current)
+					current, count = 0, 0
+				}
+			}
+		}
+	}
+	return payload
+}
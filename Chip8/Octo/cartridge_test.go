@@ -0,0 +1,186 @@
+package Octo
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/gif"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mellotonio/go-chip8/Chip8"
+)
+
+func boolPtr(value bool) *bool { return &value }
+
+func TestParseOptions(t *testing.T) {
+	for _, test := range []struct {
+		src  string
+		want Options
+	}{
+		{`{}`, Options{}},
+		{`{"tickrate": 20, "maxSize": 3216, "extra": 1}`, Options{TickRate: 20, MaxSize: 3216}},
+		{`{"fillColor": "#F00", "backgroundColor": "#000000"}`, Options{FillColor: "#F00", BackgroundColor: "#000000"}},
+		{`{"shiftQuirks": true, "loadStoreQuirks": false}`, Options{ShiftQuirks: boolPtr(true), LoadStoreQuirks: boolPtr(false)}},
+	} {
+		options, err := ParseOptions([]byte(test.src))
+		if err != nil {
+			t.Errorf("ParseOptions(%s): %v", test.src, err)
+			continue
+		}
+		if options.TickRate != test.want.TickRate || options.MaxSize != test.want.MaxSize ||
+			options.FillColor != test.want.FillColor || options.BackgroundColor != test.want.BackgroundColor ||
+			!sameBool(options.ShiftQuirks, test.want.ShiftQuirks) || !sameBool(options.LoadStoreQuirks, test.want.LoadStoreQuirks) {
+			t.Errorf("ParseOptions(%s) = %+v, want %+v", test.src, options, test.want)
+		}
+	}
+	for _, src := range []string{`[]`, `{"tickrate": "fast"}`, `{`} {
+		if _, err := ParseOptions([]byte(src)); err == nil || !strings.Contains(err.Error(), "invalid Octo options") {
+			t.Errorf("ParseOptions(%s) = %v, want an invalid Octo options error", src, err)
+		}
+	}
+}
+
+func sameBool(a, b *bool) bool {
+	return (a == nil) == (b == nil) && (a == nil || *a == *b)
+}
+
+func TestOptionsApply(t *testing.T) {
+	modern := Chip8.QuirksProfiles[Chip8.DefaultQuirksProfile]
+	for _, test := range []struct {
+		name    string
+		options Options
+		mode    *Chip8.Mode
+		quirks  *Chip8.Quirks
+	}{
+		{"nothing", Options{}, nil, nil},
+		{"chip8 size", Options{MaxSize: 3584}, modePtr(Chip8.Chip8Mode), nil},
+		{"schip size", Options{MaxSize: 3216}, modePtr(Chip8.SuperChipMode), nil},
+		{"xochip size", Options{MaxSize: 65024}, modePtr(Chip8.XOChipMode), nil},
+		// No Octo o loadStoreQuirks ligado é o I(ndex) não avançar
+		{"loadStoreQuirks on", Options{LoadStoreQuirks: boolPtr(true)}, nil, &Chip8.Quirks{Clipping: true}},
+		{"loadStoreQuirks off", Options{LoadStoreQuirks: boolPtr(false)}, nil, &modern},
+		{"other quirks", Options{ShiftQuirks: boolPtr(true), JumpQuirks: boolPtr(true), LogicQuirks: boolPtr(true), ClipQuirks: boolPtr(false), VBlankQuirks: boolPtr(true)},
+			nil, &Chip8.Quirks{Shift: true, LoadStore: true, Jump: true, VFReset: true, DisplayWait: true}},
+	} {
+		program := &Chip8.Program{}
+		test.options.Apply(program)
+		if (program.Mode == nil) != (test.mode == nil) || program.Mode != nil && *program.Mode != *test.mode {
+			t.Errorf("%s: mode = %v, want %v", test.name, program.Mode, test.mode)
+		}
+		if (program.Quirks == nil) != (test.quirks == nil) || program.Quirks != nil && *program.Quirks != *test.quirks {
+			t.Errorf("%s: quirks = %+v, want %+v", test.name, program.Quirks, test.quirks)
+		}
+	}
+
+	// As opções mudam os quirks e as cores que o programa já tinha
+	quirks, palette := Chip8.Quirks{Jump: true}, Chip8.DefaultPalette
+	program := &Chip8.Program{Quirks: &quirks, Palette: &palette, CyclesPerFrame: 10}
+	(&Options{TickRate: 30, ShiftQuirks: boolPtr(true), FillColor: "#0F0", BlendColor: "not a colour"}).Apply(program)
+	if program.CyclesPerFrame != 30 || *program.Quirks != (Chip8.Quirks{Shift: true, Jump: true}) {
+		t.Errorf("program = %+v, quirks = %+v", program, *program.Quirks)
+	}
+	if program.Palette[1] != (color.RGBA{0, 0xFF, 0, 0xFF}) || program.Palette[0] != palette[0] || program.Palette[3] != palette[3] {
+		t.Errorf("palette = %v", *program.Palette)
+	}
+}
+
+func modePtr(mode Chip8.Mode) *Chip8.Mode { return &mode }
+
+// Guarda data em um GIF como o Octo: 2 bits por pixel, os mais altos primeiro
+func encodeCartridge(t *testing.T, data []byte) []byte {
+	t.Helper()
+	const width = 32
+	img := image.NewPaletted(image.Rect(0, 0, width, (len(data)*4+width-1)/width+1),
+		color.Palette{color.Black, color.White, color.Gray{0x55}, color.Gray{0xAA}})
+	for i, b := range data {
+		for j := 0; j < 4; j++ {
+			img.Pix[i*4+j] = b >> uint(6-2*j) & 3
+		}
+	}
+	var out bytes.Buffer
+	if err := gif.EncodeAll(&out, &gif.GIF{Image: []*image.Paletted{img}, Delay: []int{0}}); err != nil {
+		t.Fatal(err)
+	}
+	return out.Bytes()
+}
+
+func TestLoadCartridge(t *testing.T) {
+	data, err := ioutil.ReadFile("testdata/cartridge.gif")
+	if err != nil {
+		t.Fatal(err)
+	}
+	program, err := LoadCartridge("cartridge.gif", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// jump main  v0 := 1  i := main  save v0  loop again
+	if want := []byte{0x12, 0x02, 0x60, 0x01, 0xA2, 0x02, 0xF0, 0x55, 0x12, 0x08}; !bytes.Equal(program.ROM, want) {
+		t.Errorf("ROM = % X, want % X", program.ROM, want)
+	}
+	if program.CyclesPerFrame != 15 || program.Mode == nil || *program.Mode != Chip8.SuperChipMode {
+		t.Errorf("cycles per frame = %d, mode = %v", program.CyclesPerFrame, program.Mode)
+	}
+	if program.Quirks == nil || !program.Quirks.Shift || program.Quirks.LoadStore {
+		t.Errorf("quirks = %+v, want shift on and loadstore off", program.Quirks)
+	}
+	if program.Palette == nil || program.Palette[1] != (color.RGBA{0xFF, 0, 0, 0xFF}) {
+		t.Errorf("palette = %v", program.Palette)
+	}
+}
+
+func TestLoadCartridgeErrors(t *testing.T) {
+	payload := func(size int, body string) []byte {
+		return encodeCartridge(t, append([]byte{byte(size >> 24), byte(size >> 16), byte(size >> 8), byte(size)}, body...))
+	}
+	for _, test := range []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"not a gif", []byte("GIF89a?"), "not an Octo cartridge"},
+		{"empty", encodeCartridge(t, nil), "payload size 0 is invalid"},
+		{"too large", payload(1000, `{}`), "payload size 1000 is invalid"},
+		{"bad json", payload(3, `{"p`), "not an Octo cartridge"},
+		{"bad program", payload(22, `{"program": "v0 := "}`), "cart.gif:"},
+	} {
+		_, err := LoadCartridge("cart.gif", test.data)
+		if err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("%s: LoadCartridge = %v, want an error containing %q", test.name, err, test.want)
+		}
+	}
+}
+
+func TestOptionsCompanion(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, data string) string {
+		path := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	write("game.json", `{"tickrate": 25}`)
+	write("other.json", `[1, 2, 3]`)
+
+	// Ao lado de um programa do Octo o .json é aplicado
+	chip_8 := Chip8.New()
+	if err := chip_8.LoadROM(write("game.8o", ": main loop again")); err != nil {
+		t.Fatal(err)
+	}
+	if chip_8.CyclesPerFrame() != 25 {
+		t.Errorf("cycles per frame = %d, want the 25 of game.json", chip_8.CyclesPerFrame())
+	}
+
+	// Ao lado de uma ROM binaria ele é ignorado, mesmo se não for um arquivo de opções
+	chip_8 = Chip8.New()
+	cycles := chip_8.CyclesPerFrame()
+	if err := chip_8.LoadROM(write("other.ch8", "\x12\x00")); err != nil {
+		t.Errorf("LoadROM with an unrelated JSON file: %v", err)
+	}
+	if err := chip_8.LoadROM(write("game.ch8", "\x12\x00")); err != nil || chip_8.CyclesPerFrame() != cycles {
+		t.Errorf("LoadROM(game.ch8) = %v, cycles per frame = %d, want the options ignored", err, chip_8.CyclesPerFrame())
+	}
+}
@@ -0,0 +1,884 @@
+// Package Octo compila código fonte Octo (.8o) para bytecode CHIP-8, SUPER-CHIP e XO-CHIP.
+//
+// Suporta labels (": nome"), atribuições ("v0 := 5", "i := sprite"), "if ... then",
+// "if ... begin ... else ... end", "loop ... while ... again", :const, :alias, :macro, :calc,
+// :byte, :pointer, :org, :next, :unpack e :call. Importar o pacote registra no LoadROM o formato ".8o",
+// os cartuchos ".gif" e os arquivos de opções ".json" que acompanham as ROMs.
+package Octo
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/mellotonio/go-chip8/Chip8"
+	"github.com/mellotonio/go-chip8/Chip8/Assembler"
+)
+
+func init() {
+	Chip8.RegisterFormat(".8o", func(path string, data []byte) (*Chip8.Program, error) {
+		return Compile(path, data)
+	})
+}
+
+// Endereço onde o programa é carregado
+const origin = 0x200
+
+// Limite de expansões de macros, para macros que se chamam sem fim
+const maxExpansions = 100000
+
+// CompileFile compila o arquivo .8o em path
+func CompileFile(path string) (*Chip8.Program, error) {
+	src, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Compile(path, src)
+}
+
+// Compile compila o código Octo src. Como no Octo, a compilação para no primeiro erro,
+// que é um *Assembler.Error com o arquivo, a linha e a coluna.
+// O programa é marcado como XO-CHIP com os quirks padrão do Octo, já que ele pode usar
+// as instruções do SUPER-CHIP e do XO-CHIP; um arquivo de opções pode trocar os dois.
+func Compile(name string, src []byte) (program *Chip8.Program, err error) {
+	c := &compiler{
+		file:      name,
+		tokens:    tokenize(name, string(src)),
+		here:      origin,
+		end:       origin,
+		labels:    map[string]int{},
+		constants: map[string]float64{},
+		aliases:   map[string]int{},
+		macros:    map[string]*macro{},
+		symbols:   &Chip8.SymbolMap{Labels: map[string]uint16{}},
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			compileErr, ok := r.(*Assembler.Error)
+			if !ok {
+				panic(r)
+			}
+			program, err = nil, compileErr
+		}
+	}()
+
+	c.compile()
+	mode, quirks := Chip8.XOChipMode, Chip8.QuirksProfiles["xochip"]
+	return &Chip8.Program{
+		ROM:     append([]byte(nil), c.memory[origin:c.end]...),
+		Symbols: c.symbols,
+		Mode:    &mode,
+		Quirks:  &quirks,
+	}, nil
+}
+
+type macro struct {
+	args  []string
+	body  []token
+	calls int
+}
+
+// Referencia a um label que ainda não foi definido, resolvida no fim da compilação
+type fixup struct {
+	addr int
+	kind fixupKind
+	name token
+}
+
+type fixupKind int
+
+const (
+	fixAddr12 fixupKind = iota // NNN das instruções 1NNN, 2NNN, ANNN e BNNN
+	fixAddr16                  // Word de 16 bits (i := long, :pointer)
+	fixUnpack                  // Os dois 6XNN gerados pelo :unpack
+)
+
+// Blocos abertos por if ... begin, else e loop
+type block struct {
+	kind   string // "begin", "else" ou "loop"
+	addr   int    // Endereço do jump a ser corrigido (begin/else) ou do inicio do loop
+	whiles []int  // Jumps dos while de um loop, corrigidos pelo again
+	tok    token
+}
+
+type compiler struct {
+	file      string
+	tokens    []token
+	pos       int
+	memory    [65536]byte
+	here      int // Endereço da proxima instrução
+	end       int // Fim do programa
+	labels    map[string]int
+	constants map[string]float64
+	aliases   map[string]int
+	macros    map[string]*macro
+	fixups    []fixup
+	blocks    []*block
+	expanded  int
+	symbols   *Chip8.SymbolMap
+}
+
+// Para a compilação com um erro na posição do token
+func (c *compiler) fail(tok token, format string, args ...interface{}) {
+	file, line, col := tok.file, tok.line, tok.col
+	if file == "" {
+		file, line, col = c.file, c.lastLine(), 1
+	}
+	panic(&Assembler.Error{File: file, Line: line, Col: col, Msg: fmt.Sprintf(format, args...)})
+}
+
+func (c *compiler) lastLine() int {
+	if len(c.tokens) == 0 {
+		return 1
+	}
+	return c.tokens[len(c.tokens)-1].line
+}
+
+func (c *compiler) peek() token {
+	if c.pos >= len(c.tokens) {
+		return token{}
+	}
+	return c.tokens[c.pos]
+}
+
+func (c *compiler) next() token {
+	tok := c.peek()
+	if tok.text == "" {
+		c.fail(tok, "unexpected end of file")
+	}
+	c.pos++
+	return tok
+}
+
+func (c *compiler) expect(text string) {
+	if tok := c.next(); tok.text != text {
+		c.fail(tok, "expected %q, got %q", text, tok.text)
+	}
+}
+
+func (c *compiler) compile() {
+	// O Octo começa executando no label main
+	c.emit(0x1000)
+
+	for c.pos < len(c.tokens) {
+		c.statement()
+	}
+
+	if len(c.blocks) > 0 {
+		b := c.blocks[len(c.blocks)-1]
+		if b.kind == "loop" {
+			c.fail(b.tok, "this loop is missing its again")
+		}
+		c.fail(b.tok, "this if is missing its end")
+	}
+
+	main, ok := c.labels["main"]
+	if !ok {
+		c.fail(token{}, "this program is missing a 'main' label")
+	}
+	c.patch12(origin, main)
+
+	for _, f := range c.fixups {
+		addr, ok := c.labels[f.name.text]
+		if !ok {
+			c.fail(f.name, "undefined name %q", f.name.text)
+		}
+		switch f.kind {
+		case fixAddr12:
+			if addr > 0xFFF {
+				c.fail(f.name, "%s (0x%X) is beyond the 12-bit address space, use i := long", f.name.text, addr)
+			}
+			c.patch12(f.addr, addr)
+		case fixAddr16:
+			c.memory[f.addr] = byte(addr >> 8)
+			c.memory[f.addr+1] = byte(addr)
+		case fixUnpack:
+			c.memory[f.addr+1] |= byte(addr>>8) & 0x0F
+			c.memory[f.addr+3] = byte(addr)
+		}
+	}
+
+	for name, addr := range c.labels {
+		c.symbols.Labels[name] = uint16(addr)
+	}
+}
+
+// Escreve um byte no endereço atual
+func (c *compiler) emitByte(tok token, b byte) {
+	if c.here >= len(c.memory) {
+		c.fail(tok, "the program does not fit in memory")
+	}
+	c.memory[c.here] = b
+	c.here++
+	if c.here > c.end {
+		c.end = c.here
+	}
+}
+
+func (c *compiler) emit(word uint16) {
+	tok := c.peek()
+	if c.pos > 0 {
+		tok = c.tokens[c.pos-1]
+	}
+	c.emitByte(tok, byte(word>>8))
+	c.emitByte(tok, byte(word))
+}
+
+// Registra a linha do código fonte que gerou a instrução em addr, para o debugger
+func (c *compiler) markLine(tok token, addr int) {
+	if tok.file != "" {
+		c.symbols.Lines = append(c.symbols.Lines, Chip8.SourceLine{File: tok.file, Line: tok.line, Addr: uint16(addr)})
+	}
+}
+
+func (c *compiler) patch12(addr, target int) {
+	c.memory[addr] = c.memory[addr]&0xF0 | byte(target>>8)&0x0F
+	c.memory[addr+1] = byte(target)
+}
+
+// Um statement: diretiva, instrução, controle de fluxo, dado ou chamada
+func (c *compiler) statement() {
+	tok := c.next()
+	start := c.here
+	if !strings.HasPrefix(tok.text, ":") || tok.text == ":call" || tok.text == ":unpack" {
+		defer func() {
+			if c.here > start {
+				c.markLine(tok, start)
+			}
+		}()
+	}
+
+	if c.directive(tok) || c.control(tok) {
+		return
+	}
+
+	switch tok.text {
+	case ";", "return":
+		c.emit(0x00EE)
+	case "clear":
+		c.emit(0x00E0)
+	case "hires":
+		c.emit(0x00FF)
+	case "lores":
+		c.emit(0x00FE)
+	case "exit":
+		c.emit(0x00FD)
+	case "scroll-left":
+		c.emit(0x00FC)
+	case "scroll-right":
+		c.emit(0x00FB)
+	case "audio":
+		c.emit(0xF002)
+	case "scroll-down":
+		c.emit(0x00C0 | uint16(c.nibble()))
+	case "scroll-up":
+		c.emit(0x00D0 | uint16(c.nibble()))
+	case "plane":
+		c.emit(0xF001 | uint16(c.nibble())<<8)
+	case "bcd":
+		c.emit(0xF033 | c.register()<<8)
+	case "saveflags":
+		c.emit(0xF075 | c.register()<<8)
+	case "loadflags":
+		c.emit(0xF085 | c.register()<<8)
+	case "save", "load":
+		x := c.register()
+		if c.peek().text == "-" {
+			c.next()
+			y := c.register()
+			if tok.text == "save" {
+				c.emit(0x5002 | x<<8 | y<<4)
+			} else {
+				c.emit(0x5003 | x<<8 | y<<4)
+			}
+			return
+		}
+		if tok.text == "save" {
+			c.emit(0xF055 | x<<8)
+		} else {
+			c.emit(0xF065 | x<<8)
+		}
+	case "sprite":
+		x, y := c.register(), c.register()
+		c.emit(0xD000 | x<<8 | y<<4 | uint16(c.nibble()))
+	case "jump":
+		c.emitAddr(0x1000)
+	case "jump0":
+		c.emitAddr(0xB000)
+	case "native":
+		c.emitAddr(0x0000)
+	case "delay", "buzzer", "pitch":
+		c.expect(":=")
+		x := c.register()
+		switch tok.text {
+		case "delay":
+			c.emit(0xF015 | x<<8)
+		case "buzzer":
+			c.emit(0xF018 | x<<8)
+		default:
+			c.emit(0xF03A | x<<8)
+		}
+	case "i":
+		c.indexStatement()
+	default:
+		if _, ok := c.registerValue(tok); ok {
+			c.pos--
+			c.registerStatement()
+			return
+		}
+		if n, ok := parseNumber(tok.text); ok {
+			if n < -128 || n > 255 {
+				c.fail(tok, "byte value %d is out of range", n)
+			}
+			c.emitByte(tok, byte(n))
+			return
+		}
+		if m, ok := c.macros[tok.text]; ok {
+			c.expand(tok, m)
+			return
+		}
+		if _, ok := c.constants[tok.text]; ok {
+			c.emitByte(tok, byte(c.constantValue(tok)))
+			return
+		}
+		if !isName(tok.text) {
+			c.fail(tok, "unexpected %q", tok.text)
+		}
+		// Um nome sozinho chama a subrotina com aquele label
+		c.pos--
+		c.emitAddr(0x2000)
+	}
+}
+
+// Diretivas que começam com ":"
+func (c *compiler) directive(tok token) bool {
+	switch tok.text {
+	case ":":
+		name := c.name()
+		c.define(name, c.here)
+	case ":next":
+		name := c.name()
+		c.define(name, c.here+1)
+	case ":const":
+		name := c.name()
+		c.defineConstant(name, float64(c.number()))
+	case ":calc":
+		name := c.name()
+		c.defineConstant(name, c.calcBlock())
+	case ":alias":
+		name := c.name()
+		if c.peek().text == "{" {
+			value := int(c.calcBlock())
+			if value < 0 || value > 0xF {
+				c.fail(name, "alias %s must be a register index from 0 to 15", name.text)
+			}
+			c.aliases[name.text] = value
+			return true
+		}
+		c.aliases[name.text] = int(c.register())
+	case ":org":
+		addr := c.valueOrCalc()
+		if addr < 0 || addr >= len(c.memory) {
+			c.fail(tok, "address %d is outside the memory", addr)
+		}
+		c.here = addr
+	case ":byte":
+		if c.peek().text == "{" {
+			c.emitByte(tok, byte(int(c.calcBlock())))
+			return true
+		}
+		value := c.next()
+		if n, ok := parseNumber(value.text); ok {
+			c.emitByte(value, byte(n))
+		} else {
+			c.emitByte(value, byte(c.constantValue(value)))
+		}
+	case ":pointer":
+		if c.peek().text == "{" {
+			value := int(c.calcBlock())
+			c.emitByte(tok, byte(value>>8))
+			c.emitByte(tok, byte(value))
+			return true
+		}
+		c.addr16(c.next())
+	case ":call":
+		c.emitAddr(0x2000)
+	case ":unpack":
+		nibble := c.number()
+		name := c.next()
+		hi, lo := c.aliasOr("unpack-hi", 0), c.aliasOr("unpack-lo", 1)
+		c.emit(0x6000 | uint16(hi)<<8 | uint16(nibble&0xF)<<4)
+		c.emit(0x6000 | uint16(lo)<<8)
+		if addr, ok := c.addressOf(name); ok {
+			c.memory[c.here-3] |= byte(addr>>8) & 0x0F
+			c.memory[c.here-1] = byte(addr)
+		} else {
+			c.fixups = append(c.fixups, fixup{c.here - 4, fixUnpack, name})
+		}
+	case ":macro":
+		c.defineMacro()
+	case ":breakpoint":
+		c.name()
+	case ":monitor":
+		c.next()
+		c.next()
+	case ":assert":
+		if c.peek().text != "{" {
+			c.next()
+		}
+		if c.calcBlock() == 0 {
+			c.fail(tok, "assertion failed")
+		}
+	case ":stringmode", ":include", ":sprite", ":segment":
+		c.fail(tok, "%s is not supported", tok.text)
+	default:
+		if strings.HasPrefix(tok.text, ":") && len(tok.text) > 1 {
+			c.fail(tok, "unknown directive %s", tok.text)
+		}
+		return false
+	}
+	return true
+}
+
+// if, else, end, loop, while e again
+func (c *compiler) control(tok token) bool {
+	switch tok.text {
+	case "if":
+		// O if...then pula a proxima instrução quando a condição é falsa.
+		// O if...begin faz o contrario e a proxima instrução é um jump para o else/end.
+		begin := c.beginsBlock()
+		c.conditional(begin)
+		word := c.next()
+		if word.text != "then" && word.text != "begin" {
+			c.fail(word, "expected then or begin after the condition, got %q", word.text)
+		}
+		if begin {
+			c.blocks = append(c.blocks, &block{kind: "begin", addr: c.here, tok: tok})
+			c.emit(0x1000)
+		}
+	case "else":
+		b := c.popBlock(tok, "begin", "else without if ... begin")
+		c.blocks = append(c.blocks, &block{kind: "else", addr: c.here, tok: tok})
+		c.emit(0x1000)
+		c.patch12(b.addr, c.here)
+	case "end":
+		b := c.popBlock(tok, "", "end without if ... begin")
+		if b.kind == "loop" {
+			c.fail(tok, "end closes a loop, use again")
+		}
+		c.patch12(b.addr, c.here)
+	case "loop":
+		c.blocks = append(c.blocks, &block{kind: "loop", addr: c.here, tok: tok})
+	case "while":
+		b := c.innerLoop(tok)
+		c.conditional(true)
+		b.whiles = append(b.whiles, c.here)
+		c.emit(0x1000)
+	case "again":
+		b := c.popBlock(tok, "loop", "again without loop")
+		c.emit(0x1000 | uint16(b.addr))
+		for _, addr := range b.whiles {
+			c.patch12(addr, c.here)
+		}
+	default:
+		return false
+	}
+	return true
+}
+
+func (c *compiler) popBlock(tok token, kind, msg string) *block {
+	if len(c.blocks) == 0 {
+		c.fail(tok, "%s", msg)
+	}
+	b := c.blocks[len(c.blocks)-1]
+	if kind != "" && b.kind != kind {
+		c.fail(tok, "%s", msg)
+	}
+	if kind == "" && b.kind == "loop" {
+		c.fail(tok, "end closes a loop, use again")
+	}
+	c.blocks = c.blocks[:len(c.blocks)-1]
+	return b
+}
+
+func (c *compiler) innerLoop(tok token) *block {
+	for i := len(c.blocks) - 1; i >= 0; i-- {
+		if c.blocks[i].kind == "loop" {
+			return c.blocks[i]
+		}
+	}
+	c.fail(tok, "while outside of a loop")
+	return nil
+}
+
+// Procura o then ou begin que termina a condição do if (no maximo 3 tokens depois dele)
+func (c *compiler) beginsBlock() bool {
+	for i := c.pos; i < len(c.tokens) && i <= c.pos+3; i++ {
+		switch c.tokens[i].text {
+		case "then":
+			return false
+		case "begin":
+			return true
+		}
+	}
+	return false
+}
+
+// Gera as instruções que pulam a proxima quando a condição é falsa (ou verdadeira, se negated)
+func (c *compiler) conditional(negated bool) {
+	x := c.register()
+	op := c.next()
+	comparison := op.text
+	if negated {
+		comparison = map[string]string{
+			"==": "!=", "!=": "==", "key": "-key", "-key": "key",
+			"<": ">=", ">": "<=", ">=": "<", "<=": ">",
+		}[op.text]
+	}
+
+	switch comparison {
+	case "==", "!=":
+		if y, ok := c.registerValue(c.peek()); ok {
+			c.next()
+			if comparison == "==" {
+				c.emit(0x9000 | x<<8 | uint16(y)<<4)
+			} else {
+				c.emit(0x5000 | x<<8 | uint16(y)<<4)
+			}
+			return
+		}
+		nn := c.byteValue()
+		if comparison == "==" {
+			c.emit(0x4000 | x<<8 | nn)
+		} else {
+			c.emit(0x3000 | x<<8 | nn)
+		}
+	case "key":
+		c.emit(0xE0A1 | x<<8)
+	case "-key":
+		c.emit(0xE09E | x<<8)
+	case "<", ">", "<=", ">=":
+		// Compara usando VF: vf := valor, depois vf -= vx ou vf =- vx, e testa o borrow
+		temp := uint16(c.aliasOr("compare-temp", 0xF))
+		if y, ok := c.registerValue(c.peek()); ok {
+			c.next()
+			c.emit(0x8000 | temp<<8 | uint16(y)<<4)
+		} else {
+			c.emit(0x6000 | temp<<8 | c.byteValue())
+		}
+		switch comparison {
+		case ">":
+			c.emit(0x8005 | temp<<8 | x<<4)
+			c.emit(0x3F01)
+		case "<":
+			c.emit(0x8007 | temp<<8 | x<<4)
+			c.emit(0x3F01)
+		case ">=":
+			c.emit(0x8007 | temp<<8 | x<<4)
+			c.emit(0x4F01)
+		case "<=":
+			c.emit(0x8005 | temp<<8 | x<<4)
+			c.emit(0x4F01)
+		}
+	default:
+		c.fail(op, "expected a comparison (==, !=, <, >, <=, >=, key or -key), got %q", op.text)
+	}
+}
+
+// i := ..., i += vx
+func (c *compiler) indexStatement() {
+	op := c.next()
+	switch op.text {
+	case "+=":
+		c.emit(0xF01E | c.register()<<8)
+	case ":=":
+		switch c.peek().text {
+		case "hex":
+			c.next()
+			c.emit(0xF029 | c.register()<<8)
+		case "bighex":
+			c.next()
+			c.emit(0xF030 | c.register()<<8)
+		case "long":
+			c.next()
+			c.emit(0xF000)
+			c.addr16(c.next())
+		default:
+			c.emitAddr(0xA000)
+		}
+	default:
+		c.fail(op, "expected := or += after i, got %q", op.text)
+	}
+}
+
+// vx := ..., vx += ..., etc
+func (c *compiler) registerStatement() {
+	x := c.register()
+	op := c.next()
+	if op.text == ":=" {
+		switch c.peek().text {
+		case "random":
+			c.next()
+			c.emit(0xC000 | x<<8 | c.byteValue())
+			return
+		case "key":
+			c.next()
+			c.emit(0xF00A | x<<8)
+			return
+		case "delay":
+			c.next()
+			c.emit(0xF007 | x<<8)
+			return
+		}
+	}
+
+	y, isRegister := c.registerValue(c.peek())
+	if isRegister {
+		c.next()
+		alu := map[string]uint16{":=": 0x0, "|=": 0x1, "&=": 0x2, "^=": 0x3, "+=": 0x4, "-=": 0x5, ">>=": 0x6, "=-": 0x7, "<<=": 0xE}
+		n, ok := alu[op.text]
+		if !ok {
+			c.fail(op, "unknown operator %q", op.text)
+		}
+		c.emit(0x8000 | x<<8 | uint16(y)<<4 | n)
+		return
+	}
+
+	switch op.text {
+	case ":=":
+		c.emit(0x6000 | x<<8 | c.byteValue())
+	case "+=":
+		c.emit(0x7000 | x<<8 | c.byteValue())
+	case "-=":
+		c.emit(0x7000 | x<<8 | (0x100-c.byteValue())&0xFF)
+	case "|=", "&=", "^=", "=-", ">>=", "<<=":
+		c.fail(c.peek(), "%s needs a register on the right side", op.text)
+	default:
+		c.fail(op, "unknown operator %q", op.text)
+	}
+}
+
+// Emite uma instrução com endereço de 12 bits, que pode ser um label definido mais tarde
+func (c *compiler) emitAddr(base uint16) {
+	tok := c.next()
+	if addr, ok := c.addressOf(tok); ok {
+		if addr < 0 || addr > 0xFFF {
+			c.fail(tok, "address 0x%X is beyond the 12-bit address space", addr)
+		}
+		c.emit(base | uint16(addr))
+		return
+	}
+	c.fixups = append(c.fixups, fixup{c.here, fixAddr12, tok})
+	c.emit(base)
+}
+
+// Emite um endereço de 16 bits, que pode ser um label definido mais tarde
+func (c *compiler) addr16(tok token) {
+	if addr, ok := c.addressOf(tok); ok {
+		c.emitByte(tok, byte(addr>>8))
+		c.emitByte(tok, byte(addr))
+		return
+	}
+	c.fixups = append(c.fixups, fixup{c.here, fixAddr16, tok})
+	c.emitByte(tok, 0)
+	c.emitByte(tok, 0)
+}
+
+// Valor de um numero, constante ou label já definido. ok = false para nomes ainda não definidos.
+func (c *compiler) addressOf(tok token) (int, bool) {
+	if n, ok := parseNumber(tok.text); ok {
+		return n, true
+	}
+	if value, ok := c.constants[tok.text]; ok {
+		return int(math.Floor(value)), true
+	}
+	if addr, ok := c.labels[tok.text]; ok {
+		return addr, true
+	}
+	if !isName(tok.text) {
+		c.fail(tok, "expected an address, got %q", tok.text)
+	}
+	return 0, false
+}
+
+func (c *compiler) define(name token, addr int) {
+	if _, ok := c.labels[name.text]; ok {
+		c.fail(name, "the name %q has already been defined", name.text)
+	}
+	if _, ok := c.constants[name.text]; ok {
+		c.fail(name, "the name %q has already been defined", name.text)
+	}
+	c.labels[name.text] = addr
+}
+
+func (c *compiler) defineConstant(name token, value float64) {
+	if _, ok := c.labels[name.text]; ok {
+		c.fail(name, "the name %q has already been defined", name.text)
+	}
+	c.constants[name.text] = value
+}
+
+// :macro nome args... { corpo }
+func (c *compiler) defineMacro() {
+	name := c.name()
+	m := &macro{}
+	for c.peek().text != "{" {
+		m.args = append(m.args, c.name().text)
+	}
+	open := c.next()
+	depth := 1
+	for {
+		tok := c.peek()
+		if tok.text == "" {
+			c.fail(open, "this macro is missing its closing }")
+		}
+		c.pos++
+		if tok.text == "{" {
+			depth++
+		} else if tok.text == "}" {
+			depth--
+			if depth == 0 {
+				break
+			}
+		}
+		m.body = append(m.body, tok)
+	}
+	c.macros[name.text] = m
+}
+
+// Troca a chamada da macro pelo corpo dela, com os argumentos substituidos
+func (c *compiler) expand(call token, m *macro) {
+	c.expanded++
+	if c.expanded > maxExpansions {
+		c.fail(call, "too many macro expansions (recursive macro?)")
+	}
+	args := map[string]string{}
+	for _, arg := range m.args {
+		args[arg] = c.next().text
+	}
+	m.calls++
+
+	body := make([]token, len(m.body))
+	for i, tok := range m.body {
+		if value, ok := args[tok.text]; ok {
+			tok.text = value
+		} else if tok.text == "CALLS" {
+			tok.text = strconv.Itoa(m.calls - 1)
+		}
+		body[i] = tok
+	}
+
+	rest := append(body, c.tokens[c.pos:]...)
+	c.tokens = append(c.tokens[:c.pos:c.pos], rest...)
+}
+
+// Operandos
+
+func (c *compiler) name() token {
+	tok := c.next()
+	if !isName(tok.text) {
+		c.fail(tok, "expected a name, got %q", tok.text)
+	}
+	return tok
+}
+
+func isName(text string) bool {
+	if text == "" || strings.HasPrefix(text, ":") {
+		return false
+	}
+	if _, ok := parseNumber(text); ok {
+		return false
+	}
+	return !strings.ContainsAny(text, "{}()\"")
+}
+
+// registerValue reconhece v0 - vF e os aliases
+func (c *compiler) registerValue(tok token) (int, bool) {
+	text := tok.text
+	if len(text) == 2 && (text[0] == 'v' || text[0] == 'V') {
+		if n, err := strconv.ParseUint(text[1:], 16, 8); err == nil {
+			return int(n), true
+		}
+	}
+	n, ok := c.aliases[text]
+	return n, ok
+}
+
+func (c *compiler) register() uint16 {
+	tok := c.next()
+	n, ok := c.registerValue(tok)
+	if !ok {
+		c.fail(tok, "expected a register, got %q", tok.text)
+	}
+	return uint16(n)
+}
+
+func (c *compiler) aliasOr(name string, register int) int {
+	if n, ok := c.aliases[name]; ok {
+		return n
+	}
+	return register
+}
+
+func (c *compiler) constantValue(tok token) int {
+	if n, ok := parseNumber(tok.text); ok {
+		return n
+	}
+	value, ok := c.constants[tok.text]
+	if !ok {
+		c.fail(tok, "undefined name %q", tok.text)
+	}
+	return int(math.Floor(value))
+}
+
+func (c *compiler) number() int {
+	return c.constantValue(c.next())
+}
+
+func (c *compiler) valueOrCalc() int {
+	if c.peek().text == "{" {
+		return int(c.calcBlock())
+	}
+	return c.number()
+}
+
+func (c *compiler) byteValue() uint16 {
+	tok := c.peek()
+	n := c.number()
+	if n < -128 || n > 255 {
+		c.fail(tok, "value %d does not fit in a byte", n)
+	}
+	return uint16(n) & 0xFF
+}
+
+func (c *compiler) nibble() int {
+	tok := c.peek()
+	n := c.number()
+	if n < 0 || n > 15 {
+		c.fail(tok, "value %d does not fit in 4 bits", n)
+	}
+	return n
+}
+
+// Numeros do Octo: decimal, 0x hexadecimal e 0b binario, com sinal opcional
+func parseNumber(text string) (int, bool) {
+	sign := 1
+	if strings.HasPrefix(text, "-") {
+		sign, text = -1, text[1:]
+	}
+	base := 10
+	switch {
+	case strings.HasPrefix(text, "0x") || strings.HasPrefix(text, "0X"):
+		base, text = 16, text[2:]
+	case strings.HasPrefix(text, "0b") || strings.HasPrefix(text, "0B"):
+		base, text = 2, text[2:]
+	}
+	n, err := strconv.ParseInt(text, base, 32)
+	if err != nil {
+		return 0, false
+	}
+	return sign * int(n), true
+}
@@ -0,0 +1,47 @@
+package Octo
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/mellotonio/go-chip8/Chip8"
+)
+
+func TestCompileSaveLoad(t *testing.T) {
+	program, err := CompileFile("testdata/save_load.8o")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []byte{
+		0x12, 0x06, // jump main
+		0x00, 0x00, 0x00, 0x00, // data
+		0x60, 0x01, 0x61, 0x02, 0x62, 0x03, // v0 := 1  v1 := 2  v2 := 3
+		0xA2, 0x02, 0xF2, 0x55, // i := data  save v2
+		0xA2, 0x02, 0xF1, 0x65, // i := data  load v1
+		0x51, 0x22, 0x50, 0x13, // save v1 - v2  load v0 - v1
+		0x22, 0x1C, 0x12, 0x1A, // copy  loop again
+		0xA2, 0x22, 0xF0, 0x55, 0x00, 0xEE, // copy
+		0x00, // buffer
+	}
+	if !bytes.Equal(program.ROM, want) {
+		t.Errorf("ROM = % X\nwant  % X", program.ROM, want)
+	}
+	for label, addr := range map[string]uint16{"data": 0x202, "main": 0x206, "copy": 0x21C, "buffer": 0x222} {
+		if program.Symbols.Labels[label] != addr {
+			t.Errorf("label %s = %03X, want %03X", label, program.Symbols.Labels[label], addr)
+		}
+	}
+	if line, ok := program.Symbols.LineForAddr(0x214); !ok || line.Line != 14 {
+		t.Errorf("line of 0x214 = %+v, want line 14", line)
+	}
+	if program.Mode == nil || *program.Mode != Chip8.XOChipMode || program.Quirks == nil || *program.Quirks != Chip8.QuirksProfiles["xochip"] {
+		t.Errorf("mode = %v, quirks = %v, want XO-CHIP with the xochip quirks", program.Mode, program.Quirks)
+	}
+}
+
+func TestCompileUndefinedLabel(t *testing.T) {
+	_, err := Compile("game.8o", []byte(": main\n\tv0 := 1\n\tjump nowhere\n"))
+	if err == nil || err.Error() != `game.8o:3:7: undefined name "nowhere"` {
+		t.Errorf("Compile = %v", err)
+	}
+}
@@ -0,0 +1,57 @@
+package Octo
+
+import (
+	"strings"
+)
+
+// token é uma palavra do código fonte. No Octo os tokens são separados por espaços,
+// strings ficam entre aspas e comentarios vão do "#" até o fim da linha.
+type token struct {
+	text string
+	file string
+	line int
+	col  int
+}
+
+func tokenize(file string, src string) []token {
+	var tokens []token
+	line, col := 1, 1
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == '\n':
+			line, col = line+1, 1
+			i++
+		case c == ' ' || c == '\t' || c == '\r':
+			col++
+			i++
+		case c == '#':
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+		case c == '"':
+			end := i + 1
+			for end < len(src) && src[end] != '"' && src[end] != '\n' {
+				if src[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end < len(src) && src[end] == '"' {
+				end++
+			}
+			tokens = append(tokens, token{src[i:end], file, line, col})
+			col += end - i
+			i = end
+		default:
+			end := i
+			for end < len(src) && !strings.ContainsRune(" \t\r\n", rune(src[end])) {
+				end++
+			}
+			tokens = append(tokens, token{src[i:end], file, line, col})
+			col += len([]rune(src[i:end]))
+			i = end
+		}
+	}
+	return tokens
+}
@@ -0,0 +1,73 @@
+package Terminal
+
+import (
+	"bufio"
+	"io"
+
+	"github.com/mellotonio/go-chip8/Chip8"
+)
+
+// Renderer desenha a tela do Chip-8 em um terminal com suporte a ANSI.
+// Cada caractere representa 2 linhas de pixels usando meio bloco (▀ ▄ █).
+type Renderer struct {
+	out     *bufio.Writer
+	cleared bool
+}
+
+func NewRenderer(out io.Writer) *Renderer {
+	return &Renderer{out: bufio.NewWriter(out)}
+}
+
+func (r *Renderer) Render(frame Chip8.Frame) {
+	if !r.cleared {
+		r.out.WriteString("\x1b[2J") // Limpa o terminal antes do primeiro quadro
+		r.cleared = true
+	}
+	r.out.WriteString("\x1b[H") // Volta o cursor para o canto superior esquerdo
+
+	writeFrame(r.out, frame, "\r\n") // O terminal pode estar em modo raw
+	r.out.Flush()
+}
+
+// WriteFrame escreve o quadro como texto, sem códigos ANSI, 2 linhas de pixels por linha de texto
+func WriteFrame(w io.Writer, frame Chip8.Frame) error {
+	out := bufio.NewWriter(w)
+	writeFrame(out, frame, "\n")
+	return out.Flush()
+}
+
+func writeFrame(out *bufio.Writer, frame Chip8.Frame, newline string) {
+	for y := 0; y < frame.Height; y += 2 {
+		for x := 0; x < frame.Width; x++ {
+			top := frame.Pixels[y*frame.Width+x] != 0
+			bottom := y+1 < frame.Height && frame.Pixels[(y+1)*frame.Width+x] != 0
+
+			switch {
+			case top && bottom:
+				out.WriteString("█")
+			case top:
+				out.WriteString("▀")
+			case bottom:
+				out.WriteString("▄")
+			default:
+				out.WriteByte(' ')
+			}
+		}
+		out.WriteString(newline)
+	}
+}
+
+// Bell é um AudioSink que toca o sino do terminal quando o tom liga
+type Bell struct {
+	out io.Writer
+}
+
+func NewBell(out io.Writer) *Bell {
+	return &Bell{out: out}
+}
+
+func (b *Bell) SetTone(on bool) {
+	if on {
+		b.out.Write([]byte{'\a'})
+	}
+}
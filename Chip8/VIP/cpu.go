@@ -0,0 +1,294 @@
+// Package VIP emula o computador COSMAC VIP no nivel do hardware: o processador RCA CDP1802,
+// o chip de video CDP1861 e o teclado hexadecimal. Ele roda o interpretador original de
+// Chip-8 (512 bytes em 0x000) e a ROM do sistema operacional do VIP, que precisam ser
+// fornecidos pelo usuário, e serve de referência para comparar com o Chip8.Machine.
+package VIP
+
+// Bus é o que o CPU enxerga fora dele: memoria, portas de entrada e saida e as flags EF1-EF4
+type Bus interface {
+	Read(addr uint16) byte
+	Write(addr uint16, value byte)
+	Output(port int, value byte) // OUT 1-7
+	Input(port int) byte         // INP 1-7
+	EF(flag int) bool            // EF1-EF4, true = ativa
+}
+
+// CPU é um RCA CDP1802. Cada ciclo de máquina leva 8 pulsos de clock.
+type CPU struct {
+	R  [16]uint16 // Registradores de 16 bits, qualquer um pode ser o program counter
+	P  byte       // Registrador que é o program counter
+	X  byte       // Registrador que aponta para a memoria das operações com M(R(X))
+	D  byte       // Acumulador
+	DF bool       // Carry / not borrow
+	T  byte       // X e P salvos pela interrupção
+	IE bool       // Interrupções ligadas
+	Q  bool       // Saida Q, no VIP liga o som
+
+	idle bool // IDL, parado até um DMA ou interrupção
+}
+
+// Reset coloca o CPU no estado de quando o botão de reset é solto: P, X e R0 zerados e interrupções ligadas
+func (cpu *CPU) Reset() {
+	cpu.P, cpu.X, cpu.R[0] = 0, 0, 0
+	cpu.Q = false
+	cpu.IE = true
+	cpu.idle = false
+}
+
+// Interrupt atende uma interrupção se elas estiverem ligadas, retorna os ciclos gastos
+func (cpu *CPU) Interrupt() int {
+	if !cpu.IE {
+		return 0
+	}
+	cpu.T = cpu.X<<4 | cpu.P
+	cpu.P, cpu.X = 1, 2
+	cpu.IE = false
+	cpu.idle = false
+	return 1
+}
+
+// DMAOut lê o byte apontado por R0 para um periférico (o CDP1861) e avança o R0, gasta 1 ciclo
+func (cpu *CPU) DMAOut(bus Bus) byte {
+	value := bus.Read(cpu.R[0])
+	cpu.R[0]++
+	cpu.idle = false
+	return value
+}
+
+// Idle indica que o CPU executou um IDL e está esperando um DMA ou interrupção
+func (cpu *CPU) Idle() bool {
+	return cpu.idle
+}
+
+// Le o byte no program counter e avança ele
+func (cpu *CPU) fetch(bus Bus) byte {
+	value := bus.Read(cpu.R[cpu.P])
+	cpu.R[cpu.P]++
+	return value
+}
+
+// Step executa uma instrução e retorna quantos ciclos de máquina ela levou
+// (2, ou 3 nos desvios longos). Parado no IDL, gasta 1 ciclo sem fazer nada.
+func (cpu *CPU) Step(bus Bus) int {
+	if cpu.idle {
+		return 1
+	}
+
+	opcode := cpu.fetch(bus)
+	i, n := opcode>>4, opcode&0xF
+	rx := &cpu.R[cpu.X]
+
+	switch i {
+	case 0x0:
+		if n == 0 {
+			cpu.idle = true // IDL
+		} else {
+			cpu.D = bus.Read(cpu.R[n]) // LDN
+		}
+	case 0x1:
+		cpu.R[n]++ // INC
+	case 0x2:
+		cpu.R[n]-- // DEC
+	case 0x3:
+		// Desvios curtos: trocam o byte baixo do program counter
+		target := bus.Read(cpu.R[cpu.P])
+		if n == 0x8 { // SKP
+			cpu.R[cpu.P]++
+		} else if cpu.condition(bus, n&7) != (n&8 != 0) {
+			cpu.R[cpu.P] = cpu.R[cpu.P]&0xFF00 | uint16(target)
+		} else {
+			cpu.R[cpu.P]++
+		}
+	case 0x4:
+		cpu.D = bus.Read(cpu.R[n]) // LDA
+		cpu.R[n]++
+	case 0x5:
+		bus.Write(cpu.R[n], cpu.D) // STR
+	case 0x6:
+		switch {
+		case n == 0: // IRX
+			*rx++
+		case n < 8: // OUT 1-7
+			bus.Output(int(n), bus.Read(*rx))
+			*rx++
+		case n > 8: // INP 1-7
+			cpu.D = bus.Input(int(n - 8))
+			bus.Write(*rx, cpu.D)
+		}
+	case 0x7:
+		cpu.execute7(bus, n)
+	case 0x8:
+		cpu.D = byte(cpu.R[n]) // GLO
+	case 0x9:
+		cpu.D = byte(cpu.R[n] >> 8) // GHI
+	case 0xA:
+		cpu.R[n] = cpu.R[n]&0xFF00 | uint16(cpu.D) // PLO
+	case 0xB:
+		cpu.R[n] = cpu.R[n]&0x00FF | uint16(cpu.D)<<8 // PHI
+	case 0xC:
+		cpu.executeLong(bus, n)
+		return 3
+	case 0xD:
+		cpu.P = n // SEP
+	case 0xE:
+		cpu.X = n // SEX
+	case 0xF:
+		// F0-F7 usam M(R(X)), F8-FF o byte imediato (exceto SHR e SHL)
+		var operand byte
+		switch {
+		case n == 0x6 || n == 0xE:
+		case n < 8:
+			operand = bus.Read(*rx)
+		default:
+			operand = cpu.fetch(bus)
+		}
+		op := n & 7
+		if n == 0xE {
+			op = 0xE // SHL
+		}
+		cpu.alu(op, operand, false)
+	}
+	return 2
+}
+
+// Instruções 7N: retorno de interrupção, pilha, aritmética com carry e a saida Q
+func (cpu *CPU) execute7(bus Bus, n byte) {
+	rx := &cpu.R[cpu.X]
+	switch n {
+	case 0x0, 0x1: // RET, DIS
+		value := bus.Read(*rx)
+		*rx++
+		cpu.X, cpu.P = value>>4, value&0xF
+		cpu.IE = n == 0
+	case 0x2: // LDXA
+		cpu.D = bus.Read(*rx)
+		*rx++
+	case 0x3: // STXD
+		bus.Write(*rx, cpu.D)
+		*rx--
+	case 0x4, 0x5, 0x7: // ADC, SDB, SMB
+		cpu.alu(n, bus.Read(*rx), true)
+	case 0x6: // SHRC
+		cpu.alu(0x6, 0, true)
+	case 0x8: // SAV
+		bus.Write(*rx, cpu.T)
+	case 0x9: // MARK
+		cpu.T = cpu.X<<4 | cpu.P
+		bus.Write(cpu.R[2], cpu.T)
+		cpu.X = cpu.P
+		cpu.R[2]--
+	case 0xA: // REQ
+		cpu.Q = false
+	case 0xB: // SEQ
+		cpu.Q = true
+	case 0xC, 0xD, 0xF: // ADCI, SDBI, SMBI
+		cpu.alu(n&7, cpu.fetch(bus), true)
+	case 0xE: // SHLC
+		cpu.alu(0x6|0x8, 0, true)
+	}
+}
+
+// Operações da ALU. op é o N das instruções F0-F7 (OR, AND, XOR, ADD, SD, SHR, SM);
+// com carry, ADD/SD/SM/SHR usam o DF (ADC, SDB, SMB, SHRC) e op 0xE é o SHL(C).
+func (cpu *CPU) alu(op byte, operand byte, carry bool) {
+	in := 0
+	if carry && cpu.DF {
+		in = 1
+	}
+	switch op {
+	case 0x0: // LDX / LDI
+		cpu.D = operand
+	case 0x1:
+		cpu.D |= operand
+	case 0x2:
+		cpu.D &= operand
+	case 0x3:
+		cpu.D ^= operand
+	case 0x4: // D = M + D (+ DF)
+		sum := int(operand) + int(cpu.D) + in
+		cpu.D, cpu.DF = byte(sum), sum > 0xFF
+	case 0x5: // D = M - D (- not DF)
+		cpu.subtract(operand, cpu.D, carry)
+	case 0x6: // SHR / SHRC
+		out := cpu.D&1 != 0
+		cpu.D = cpu.D>>1 | byte(in)<<7
+		cpu.DF = out
+	case 0x7: // D = D - M (- not DF)
+		cpu.subtract(cpu.D, operand, carry)
+	case 0xE: // SHL / SHLC
+		out := cpu.D&0x80 != 0
+		cpu.D = cpu.D<<1 | byte(in)
+		cpu.DF = out
+	}
+}
+
+// a - b, com o borrow do DF nas instruções com carry. DF = 1 quando não houve borrow.
+func (cpu *CPU) subtract(a, b byte, carry bool) {
+	diff := int(a) - int(b)
+	if carry && !cpu.DF {
+		diff--
+	}
+	cpu.D, cpu.DF = byte(diff), diff >= 0
+}
+
+// Condição dos desvios: 0 = sempre, 1 = Q, 2 = D zero, 3 = DF, 4-7 = EF1-EF4
+func (cpu *CPU) condition(bus Bus, c byte) bool {
+	switch c {
+	case 0:
+		return true
+	case 1:
+		return cpu.Q
+	case 2:
+		return cpu.D == 0
+	case 3:
+		return cpu.DF
+	}
+	return bus.EF(int(c - 3))
+}
+
+// Desvios e skips longos (CN), todos levam 3 ciclos
+func (cpu *CPU) executeLong(bus Bus, n byte) {
+	pc := &cpu.R[cpu.P]
+	var test bool
+	switch n & 3 {
+	case 0:
+		test = true // LBR (C4, C8 e CC não usam o teste)
+	case 1:
+		test = cpu.Q
+	case 2:
+		test = cpu.D == 0
+	case 3:
+		test = cpu.DF
+	}
+
+	switch n {
+	case 0x0, 0x1, 0x2, 0x3: // LBR, LBQ, LBZ, LBDF
+		cpu.longBranch(bus, test)
+	case 0x9, 0xA, 0xB: // LBNQ, LBNZ, LBNF
+		cpu.longBranch(bus, !test)
+	case 0x4: // NOP
+	case 0x5, 0x6, 0x7: // LSNQ, LSNZ, LSNF
+		if !test {
+			*pc += 2
+		}
+	case 0x8: // LSKP
+		*pc += 2
+	case 0xC: // LSIE
+		if cpu.IE {
+			*pc += 2
+		}
+	case 0xD, 0xE, 0xF: // LSQ, LSZ, LSDF
+		if test {
+			*pc += 2
+		}
+	}
+}
+
+func (cpu *CPU) longBranch(bus Bus, taken bool) {
+	pc := &cpu.R[cpu.P]
+	if !taken {
+		*pc += 2
+		return
+	}
+	*pc = uint16(bus.Read(*pc))<<8 | uint16(bus.Read(*pc+1))
+}
@@ -0,0 +1,87 @@
+package VIP
+
+import "testing"
+
+// Bus de teste: 64KB de RAM e as flags EF controladas pelo teste
+type testBus struct {
+	memory [0x10000]byte
+	ef     [5]bool
+	out    [8]byte
+}
+
+func (bus *testBus) Read(addr uint16) byte         { return bus.memory[addr] }
+func (bus *testBus) Write(addr uint16, value byte) { bus.memory[addr] = value }
+func (bus *testBus) Output(port int, value byte)   { bus.out[port] = value }
+func (bus *testBus) Input(port int) byte           { return 0 }
+func (bus *testBus) EF(flag int) bool              { return bus.ef[flag] }
+
+func TestCPUCycles(t *testing.T) {
+	bus := &testBus{}
+	copy(bus.memory[:], []byte{
+		0xF8, 0x90, // 00: LDI 90
+		0xFC, 0x80, // 02: ADI 80, D = 10 com carry
+		0x7B,       // 04: SEQ
+		0x33, 0x09, // 05: BDF 09
+		0x00, 0x00, // 07: (pulado)
+		0xC0, 0x12, 0x34, // 09: LBR 1234
+	})
+	copy(bus.memory[0x1234:], []byte{
+		0xC8,       // 1234: LSKP
+		0x00, 0x00, // 1235: (pulado)
+		0xC6,       // 1237: LSNZ, D != 0, pula
+		0x00, 0x00, // 1238: (pulado)
+		0x00, // 123A: IDL
+	})
+	var cpu CPU
+	cpu.Reset()
+	for _, test := range []struct {
+		name   string
+		cycles int
+		pc     uint16
+	}{
+		{"LDI", 2, 0x02},
+		{"ADI", 2, 0x04},
+		{"SEQ", 2, 0x05},
+		{"BDF", 2, 0x09},
+		{"LBR", 3, 0x1234},
+		{"LSKP", 3, 0x1237},
+		{"LSNZ", 3, 0x123A},
+		{"IDL", 2, 0x123B},
+		{"idle", 1, 0x123B},
+	} {
+		if cycles := cpu.Step(bus); cycles != test.cycles || cpu.R[cpu.P] != test.pc {
+			t.Errorf("%s: %d cycles, PC = %04X, want %d, %04X", test.name, cycles, cpu.R[cpu.P], test.cycles, test.pc)
+		}
+	}
+	if cpu.D != 0x10 || !cpu.DF || !cpu.Q || !cpu.Idle() {
+		t.Errorf("D = %02X, DF = %v, Q = %v, idle = %v, want 10, true, true, true", cpu.D, cpu.DF, cpu.Q, cpu.Idle())
+	}
+
+	// A interrupção tira o CPU do IDL e troca para P = 1, X = 2
+	if cycles := cpu.Interrupt(); cycles != 1 || cpu.P != 1 || cpu.X != 2 || cpu.T != 0x00 || cpu.IE || cpu.Idle() {
+		t.Errorf("Interrupt: %d cycles, P = %d, X = %d, T = %02X, IE = %v", cycles, cpu.P, cpu.X, cpu.T, cpu.IE)
+	}
+	if cycles := cpu.Interrupt(); cycles != 0 {
+		t.Errorf("Interrupt with interrupts off took %d cycles", cycles)
+	}
+}
+
+func TestCPUBranchOnFlag(t *testing.T) {
+	bus := &testBus{}
+	copy(bus.memory[:], []byte{
+		0x34, 0x10, // 00: B1 10
+		0x3C, 0x20, // 02: BN1 20
+	})
+	var cpu CPU
+	cpu.Reset()
+	bus.ef[1] = true
+	cpu.Step(bus)
+	if cpu.R[0] != 0x10 {
+		t.Errorf("B1 with EF1 active went to %04X, want 0010", cpu.R[0])
+	}
+	cpu.R[0] = 0x02
+	cpu.Step(bus)
+	if cpu.R[0] != 0x04 {
+		t.Errorf("BN1 with EF1 active went to %04X, want 0004", cpu.R[0])
+	}
+}
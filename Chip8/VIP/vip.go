@@ -0,0 +1,337 @@
+package VIP
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/mellotonio/go-chip8/Chip8"
+)
+
+// Tamanhos das imagens que o usuário precisa fornecer
+const (
+	InterpreterSize = 512 // Interpretador de Chip-8, carregado em 0x000
+	MonitorSize     = 512 // ROM do sistema operacional do VIP, em 0x8000
+	ramSize         = 4096
+)
+
+// Temporização do CDP1861 em ciclos de máquina do 1802 (1,76064MHz / 8): cada linha de
+// video leva 14 ciclos e um quadro tem 262 linhas, 3668 ciclos, 60 quadros por segundo
+const (
+	lineCycles   = 14
+	frameLines   = 262
+	frameCycles  = lineCycles * frameLines
+	displayStart = 80  // Primeira linha com DMA, a interrupção é pedida nas 2 linhas antes dela
+	displayLines = 128 // Linhas com DMA, cada linha da tela do Chip-8 é mostrada 4 vezes
+	lineBytes    = 8   // Bytes lidos por DMA em cada linha (64 pixels)
+)
+
+// Machine é um COSMAC VIP com 4KB de RAM rodando o interpretador original de Chip-8
+type Machine struct {
+	CPU CPU
+
+	ram         [ramSize]byte
+	monitor     [MonitorSize]byte
+	interpreter []byte
+	program     []byte
+	romAtZero   bool // Depois do reset a ROM aparece em 0x0000 até o primeiro acesso acima de 0x8000
+
+	cycle     int  // Ciclo de máquina dentro do quadro atual
+	displayOn bool // Ligado pelo INP 1, desligado pelo OUT 1
+	dmaLine   int  // Ultima linha que já recebeu DMA (-1 = nenhuma)
+	screen    [displayLines * lineBytes]byte
+	changed   bool // A tela mudou desde o ultimo Render
+
+	keyLatch byte // Tecla selecionada pelo OUT 2, lida pelo EF3
+	key      [16]bool
+
+	renderer Chip8.Renderer
+	input    Chip8.InputSource
+	audio    Chip8.AudioSink
+	toneOn   bool
+	stop     chan struct{} // Fechado pelo Stop para o Run retornar
+	stopOnce sync.Once
+}
+
+// Option configura uma Machine criada por New
+type Option func(*Machine)
+
+// WithRenderer conecta aonde a tela do CDP1861 será desenhada
+func WithRenderer(renderer Chip8.Renderer) Option {
+	return func(vip *Machine) {
+		vip.renderer = renderer
+	}
+}
+
+// WithInputSource conecta a fonte do estado das teclas do teclado hexadecimal
+func WithInputSource(input Chip8.InputSource) Option {
+	return func(vip *Machine) {
+		vip.input = input
+	}
+}
+
+// WithAudioSink conecta quem toca o som, ligado pela saida Q do 1802
+func WithAudioSink(audio Chip8.AudioSink) Option {
+	return func(vip *Machine) {
+		vip.audio = audio
+	}
+}
+
+// New cria um VIP com o interpretador de Chip-8 (até 512 bytes) e a ROM do sistema
+// operacional (512 bytes), que tem a rotina de interrupção usada pelo interpretador
+func New(interpreter, monitor []byte, opts ...Option) (*Machine, error) {
+	if len(interpreter) == 0 || len(interpreter) > InterpreterSize {
+		return nil, fmt.Errorf("the CHIP-8 interpreter must have 1 to %d bytes, got %d", InterpreterSize, len(interpreter))
+	}
+	if len(monitor) != MonitorSize {
+		return nil, fmt.Errorf("the VIP monitor ROM must have %d bytes, got %d", MonitorSize, len(monitor))
+	}
+	vip := &Machine{
+		interpreter: append([]byte(nil), interpreter...),
+		stop:        make(chan struct{}),
+	}
+	copy(vip.monitor[:], monitor)
+	for _, opt := range opts {
+		opt(vip)
+	}
+	vip.Reset()
+	return vip, nil
+}
+
+// LoadBytes carrega um programa de Chip-8 em 0x200 e reinicia o VIP
+func (vip *Machine) LoadBytes(rom []byte) error {
+	// O interpretador guarda as variaveis e a tela nos ultimos 352 bytes da RAM
+	if max := 0xEA0 - 0x200; len(rom) > max {
+		return fmt.Errorf("ROM too large: %d bytes, the maximum for the COSMAC VIP is %d", len(rom), max)
+	}
+	vip.program = append([]byte(nil), rom...)
+	vip.Reset()
+	return nil
+}
+
+// Reset limpa a RAM, carrega o interpretador e o programa e solta o botão de reset
+func (vip *Machine) Reset() {
+	vip.ram = [ramSize]byte{}
+	copy(vip.ram[:], vip.interpreter)
+	copy(vip.ram[0x200:], vip.program)
+	vip.CPU = CPU{}
+	vip.CPU.Reset()
+	vip.romAtZero = true
+	vip.cycle = 0
+	vip.displayOn = false
+	vip.dmaLine = -1
+	vip.screen = [displayLines * lineBytes]byte{}
+	vip.changed = true
+	vip.keyLatch = 0
+}
+
+// Read implementa o Bus: 4KB de RAM repetidos até 0x7FFF e a ROM repetida de 0x8000 até 0xFFFF
+func (vip *Machine) Read(addr uint16) byte {
+	if addr&0x8000 != 0 {
+		vip.romAtZero = false
+		return vip.monitor[addr%MonitorSize]
+	}
+	if vip.romAtZero {
+		return vip.monitor[addr%MonitorSize]
+	}
+	return vip.ram[addr%ramSize]
+}
+
+// Write implementa o Bus, a ROM não pode ser escrita
+func (vip *Machine) Write(addr uint16, value byte) {
+	if addr&0x8000 != 0 {
+		vip.romAtZero = false
+		return
+	}
+	vip.ram[addr%ramSize] = value
+}
+
+// Output implementa o Bus: OUT 1 desliga o CDP1861 e OUT 2 seleciona a tecla lida pelo EF3
+func (vip *Machine) Output(port int, value byte) {
+	switch port {
+	case 1:
+		vip.displayOn = false
+	case 2:
+		vip.keyLatch = value & 0xF
+	}
+}
+
+// Input implementa o Bus: INP 1 liga o CDP1861
+func (vip *Machine) Input(port int) byte {
+	if port == 1 {
+		vip.displayOn = true
+	}
+	return 0
+}
+
+// EF implementa o Bus: EF1 avisa que a tela vai começar ou acabar, EF3 que a tecla selecionada está apertada
+func (vip *Machine) EF(flag int) bool {
+	switch flag {
+	case 1:
+		line := vip.cycle / lineCycles
+		return vip.displayOn && (line >= displayStart-4 && line < displayStart ||
+			line >= displayStart+displayLines-4 && line < displayStart+displayLines)
+	case 3:
+		return vip.key[vip.keyLatch]
+	}
+	return false
+}
+
+// Step avança o VIP por uma instrução do 1802 (ou um DMA ou interrupção do CDP1861)
+func (vip *Machine) Step() {
+	line := vip.cycle / lineCycles
+	cycles := 0
+	switch {
+	case vip.displayOn && line >= displayStart && line < displayStart+displayLines && line != vip.dmaLine:
+		// O CDP1861 pede 8 DMAs por linha, que o 1802 atende entre as instruções
+		vip.dmaLine = line
+		row := vip.screen[(line-displayStart)*lineBytes:][:lineBytes]
+		for i := range row {
+			value := vip.CPU.DMAOut(vip)
+			if row[i] != value {
+				row[i] = value
+				vip.changed = true
+			}
+		}
+		cycles = lineBytes
+	case vip.displayOn && line >= displayStart-2 && line < displayStart && vip.CPU.IE:
+		cycles = vip.CPU.Interrupt()
+	default:
+		cycles = vip.CPU.Step(vip)
+	}
+
+	vip.cycle += cycles
+	if vip.cycle >= frameCycles {
+		vip.cycle -= frameCycles
+		vip.dmaLine = -1
+	}
+}
+
+// RunFrame executa um quadro de 60Hz (3668 ciclos de máquina)
+func (vip *Machine) RunFrame() {
+	start := vip.cycle
+	for {
+		vip.Step()
+		if vip.cycle < start {
+			return
+		}
+		start = vip.cycle
+	}
+}
+
+// Run executa o VIP em tempo real até a janela fechar ou o Stop, como o Chip8.Machine.Run
+func (vip *Machine) Run() {
+	const frameTime = time.Second / 60
+	next := time.Now()
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		select {
+		case <-timer.C:
+		case <-vip.stop:
+			return
+		}
+		if vip.input != nil {
+			if vip.input.Closed() {
+				break
+			}
+			vip.key = vip.input.KeyState()
+		}
+		vip.RunFrame()
+		if vip.renderer != nil && vip.changed {
+			vip.renderer.Render(vip.Frame())
+		}
+		vip.changed = false
+		if vip.audio != nil && vip.CPU.Q != vip.toneOn {
+			vip.toneOn = vip.CPU.Q
+			vip.audio.SetTone(vip.toneOn)
+		}
+
+		next = next.Add(frameTime)
+		wait := time.Until(next)
+		if wait < -5*frameTime {
+			next, wait = time.Now(), 0
+		}
+		timer.Reset(wait)
+	}
+}
+
+// Stop pede para o Run retornar, pode ser chamado de qualquer goroutine e mais de uma vez
+func (vip *Machine) Stop() {
+	vip.stopOnce.Do(func() { close(vip.stop) })
+}
+
+// SetKeyDown marca uma tecla do teclado hexadecimal como apertada
+func (vip *Machine) SetKeyDown(index byte) {
+	vip.key[index&0xF] = true
+}
+
+// SetKeyUp marca uma tecla do teclado hexadecimal como solta
+func (vip *Machine) SetKeyUp(index byte) {
+	vip.key[index&0xF] = false
+}
+
+// Frame retorna a tela mostrada pelo CDP1861 em 64x32. Ele mostra cada linha do Chip-8
+// 4 vezes, a primeira das 4 é usada.
+func (vip *Machine) Frame() Chip8.Frame {
+	return newFrame(vip.screen[:], 4*lineBytes)
+}
+
+// Monta um quadro de 64x32 com 1 bit por pixel, cada linha começando stride bytes depois da anterior
+func newFrame(display []byte, stride int) Chip8.Frame {
+	frame := Chip8.Frame{Width: 64, Height: 32, Pixels: make([]byte, 64*32), Palette: Chip8.DefaultPalette}
+	for y := 0; y < 32; y++ {
+		row := display[y*stride:][:lineBytes]
+		for x := 0; x < 64; x++ {
+			frame.Pixels[y*64+x] = row[x/8] >> (7 - x%8) & 1
+		}
+	}
+	return frame
+}
+
+// Onde o interpretador original guarda o estado do Chip-8 (com 4KB de RAM)
+const (
+	registersAddr = 0xEF0 // V0 - VF
+	displayAddr   = 0xF00 // Tela, 256 bytes
+)
+
+// State é o estado do programa de Chip-8 lido da RAM e dos registradores do 1802,
+// para comparar com o Chip8.Machine
+type State struct {
+	Registers      [16]byte
+	Index          uint16 // RA
+	ProgramCounter uint16 // R5
+	DelayTimer     byte   // R8.1, decrementado pela interrupção
+	SoundTimer     byte   // R8.0
+	Display        [256]byte
+}
+
+// State retorna o estado do programa de Chip-8 rodando no interpretador original
+func (vip *Machine) State() State {
+	var state State
+	copy(state.Registers[:], vip.ram[registersAddr:])
+	copy(state.Display[:], vip.ram[displayAddr:])
+	state.Index = vip.CPU.R[0xA]
+	state.ProgramCounter = vip.CPU.R[5]
+	state.DelayTimer = byte(vip.CPU.R[8] >> 8)
+	state.SoundTimer = byte(vip.CPU.R[8])
+	return state
+}
+
+// Compare diz se a tela e os registradores do interpretador original são iguais aos do
+// chip_8, e retorna uma descrição da primeira diferença
+func (vip *Machine) Compare(chip_8 *Chip8.Machine) (bool, string) {
+	state := vip.State()
+	if registers := chip_8.Registers(); registers != state.Registers {
+		return false, fmt.Sprintf("registers differ: VIP %X, Machine %X", state.Registers, registers)
+	}
+	if index := chip_8.Index(); index != state.Index {
+		return false, fmt.Sprintf("I differs: VIP %03X, Machine %03X", state.Index, index)
+	}
+	vipFrame, frame := newFrame(state.Display[:], lineBytes), chip_8.Frame()
+	if frame.Width != vipFrame.Width || !bytes.Equal(frame.Pixels, vipFrame.Pixels) {
+		return false, "the screens differ"
+	}
+	return true, ""
+}
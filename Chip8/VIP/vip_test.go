@@ -0,0 +1,82 @@
+package VIP
+
+import "testing"
+
+// ROM do sistema operacional de teste: como a do VIP, ela começa rodando em 0x0000,
+// pula para o seu endereço real em 0x8000 e dali entra no interpretador em 0x0000
+func testMonitor() []byte {
+	monitor := make([]byte, MonitorSize)
+	copy(monitor, []byte{
+		0xC0, 0x80, 0x03, // 8000: LBR 8003
+		0xC0, 0x00, 0x00, // 8003: LBR 0000
+	})
+	return monitor
+}
+
+// Interpretador de teste: aponta o R5 (program counter do Chip-8) para 0x200
+// e lê a primeira instrução do programa no R6
+var testInterpreter = []byte{
+	0xF8, 0x02, // 00: LDI 02
+	0xB5,       // 02: PHI R5
+	0xF8, 0x00, // 03: LDI 00
+	0xA5, // 05: PLO R5
+	0x45, // 06: LDA R5
+	0xB6, // 07: PHI R6
+	0x45, // 08: LDA R5
+	0xA6, // 09: PLO R6
+	0x00, // 0A: IDL
+}
+
+func TestBoot(t *testing.T) {
+	vip, err := New(testInterpreter, testMonitor())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := vip.LoadBytes([]byte{0x60, 0x12, 0x12, 0x02}); err != nil {
+		t.Fatal(err)
+	}
+	// Depois do reset a ROM aparece em 0x0000
+	if vip.CPU.P != 0 || vip.CPU.R[0] != 0 || !vip.CPU.IE || !vip.romAtZero {
+		t.Fatalf("after reset P = %d, R0 = %04X, IE = %v", vip.CPU.P, vip.CPU.R[0], vip.CPU.IE)
+	}
+
+	for i := 0; i < 100 && !vip.CPU.Idle(); i++ {
+		vip.Step()
+	}
+	if !vip.CPU.Idle() {
+		t.Fatal("the interpreter did not run")
+	}
+	// 2 desvios longos da ROM (3 ciclos) e 9 instruções do interpretador (2 ciclos)
+	if vip.cycle != 2*3+9*2 {
+		t.Errorf("booting took %d cycles, want %d", vip.cycle, 2*3+9*2)
+	}
+	if vip.CPU.R[6] != 0x6012 || vip.State().ProgramCounter != 0x202 {
+		t.Errorf("first instruction = %04X, CHIP-8 PC = %03X, want 6012, 202", vip.CPU.R[6], vip.State().ProgramCounter)
+	}
+	if vip.romAtZero || vip.Read(0x0000) != 0xF8 || vip.Read(0x8000) != 0xC0 {
+		t.Errorf("after the jump to 0x8000 the RAM is not back at 0x0000")
+	}
+}
+
+func TestNew(t *testing.T) {
+	for _, test := range []struct {
+		name                 string
+		interpreter, monitor []byte
+	}{
+		{"no interpreter", nil, testMonitor()},
+		{"large interpreter", make([]byte, InterpreterSize+1), testMonitor()},
+		{"short monitor", testInterpreter, make([]byte, MonitorSize-1)},
+	} {
+		if _, err := New(test.interpreter, test.monitor); err == nil {
+			t.Errorf("%s: New did not fail", test.name)
+		}
+	}
+
+	vip, err := New(testInterpreter, testMonitor())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := vip.LoadBytes(make([]byte, 0xEA0-0x200+1)); err == nil {
+		t.Errorf("LoadBytes of a ROM over the VIP limit did not fail")
+	}
+}
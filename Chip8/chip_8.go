@@ -1,141 +1,372 @@
 package Chip8
 
 import (
-	"fmt"
+	"image/color"
+	"io"
 	"io/ioutil"
 	"math/rand"
-	"os"
+	"sync"
 	"time"
-
-	"github.com/faiface/beep/mp3"
-	"github.com/faiface/beep/speaker"
-	"github.com/mellotonio/go-chip8/Chip8/Display"
 )
 
 // Uso de memória
 // 0x000-0x1FF - Reservado para o interpretador do Chip-8 -> 0 ~ 512 (bits)
-// 0x050-0x0A0 - "Used for the built in 4x5 pixel font set (0-F)"" -> 80 ~ 160 (bits)
+// 0x000-0x050 - "Used for the built in 4x5 pixel font set (0-F)"" -> 0 ~ 80 (bits)
+// 0x050-0x0F0 - Fonte grande 8x10 do SUPER-CHIP (FX30) -> 80 ~ 240 (bits)
 // 0x200-0xFFF - Reservado para os programas e funcionalidades -> 512 ~ 4095 (bits)
 
-type chip_8_VM struct {
-	opcode          uint16        // Referência de instrução do processador
-	memory          [4096]byte    // O Chip-8, originalmente, é capaz de acessar 4096 bytes de RAM (4KB)
-	Vx              [16]byte      // Registradores de proposito geral, Vx aonde x é um hexadecimal z (0 até F)
-	index           uint16        // Registrador de indice
-	program_counter uint16        // Usado para guardar o endereço atual da instrução que está sendo executada (0x000 - 0 => 0xFFF - 4095)
-	stack           [16]uint16    // Stack para "acumular" instruções
-	stack_pointer   uint16        // Registro que guarda o ultimo endereço requisitado na pilha
-	DelayTimer      byte          // 8-bit delay timer que conta de 60 até 0 (hertz)
-	SoundTimer      byte          // 8-bit sound timer que conta de 60 até 0 (hertz)
-	timerSpeed      uint16        // timer speed
-	gfx             [64 * 32]byte // Pixels da tela
-	key             [16]byte      // "16-key hexadecimal keypad for input"
+// Machine é o núcleo do Chip-8. Ele não sabe nada sobre janelas, áudio ou teclado,
+// esses são conectados através das interfaces Renderer, AudioSink e InputSource (frontend.go).
+type Machine struct {
+	opcode          uint16         // Referência de instrução do processador
+	memory          [65536]byte    // O Chip-8, originalmente, é capaz de acessar 4096 bytes de RAM (4KB), o XO-CHIP 64KB
+	mode            Mode           // Plataforma emulada (Chip-8, SUPER-CHIP ou XO-CHIP)
+	Vx              [16]byte       // Registradores de proposito geral, Vx aonde x é um hexadecimal z (0 até F)
+	index           uint16         // Registrador de indice
+	program_counter uint16         // Usado para guardar o endereço atual da instrução que está sendo executada (0x000 - 0 => 0xFFF - 4095)
+	stack           [16]uint16     // Stack para "acumular" instruções
+	stack_pointer   uint16         // Registro que guarda o ultimo endereço requisitado na pilha
+	DelayTimer      byte           // 8-bit delay timer que conta de 60 até 0 (hertz)
+	SoundTimer      byte           // 8-bit sound timer que conta de 60 até 0 (hertz)
+	gfx             [128 * 64]byte // Pixels da tela, cada linha tem width() pixels
+	hires           bool           // Modo de alta resolução do SUPER-CHIP (128x64)
+	halted          bool           // 00FD -> O programa pediu para sair do interpretador
+	rpl             [16]byte       // "RPL user flags" do SUPER-CHIP (FX75/FX85)
+	plane           byte           // Bitplanes selecionados pelo FN01 do XO-CHIP (1 = primeiro, 2 = segundo, 3 = ambos)
+	palette         [4]color.RGBA  // Cores de cada combinação de bitplanes
+	pattern         [16]byte       // "Audio pattern buffer" do XO-CHIP (F002), 128 amostras de 1 bit
+	pitch           byte           // Registrador de pitch do XO-CHIP (FX3A)
+	key             [16]byte       // "16-key hexadecimal keypad for input"
 	drawFlag        bool
-	Window          *Display.Window
-	Clock           *time.Ticker
-	BeepChan        chan struct{}
-	audioChan       chan struct{} // channel for pushing audio events
-	Shutdown        chan struct{} // shutdown signal channel
+	screenChanged   bool          // A tela mudou desde o ultimo Render
+	vblank          bool          // Verdadeiro quando um novo quadro começou, usado pelo quirk DisplayWait
+	cyclesPerFrame  int           // Instruções executadas em cada quadro de 60Hz
+	timing          Timing        // Como as instruções são divididas em quadros
+	frameCycle      int           // Custo já gasto no quadro atual (instruções ou ciclos do VIP, ver frameBudget)
+	quirks          Quirks        // Comportamento das instruções ambíguas
+	faultPolicy     FaultPolicy   // O que fazer quando uma instrução falha (errors.go)
+	faultLog        io.Writer     // Aonde o FaultLog escreve as falhas (nil = em lugar nenhum)
+	fault           error         // Falha da instrução atual, tratada pelo handleFault
+	err             error         // Falha que parou o Run
+	rom             []byte        // Cópia da ROM carregada, usada pelo Reset
+	romPath         string        // Caminho da ROM carregada pelo LoadROM
+	symbols         *SymbolMap    // Símbolos do programa, quando ele foi compilado pelo LoadROM
+	info            *ROMInfo      // O que o banco de dados sabe sobre a ROM carregada
+	database        *Database     // Banco consultado pelo LoadROM (nil = desligado)
+	random          *rand.Rand    // Gerador usado pelo CXNN
+	stateFile       string        // Arquivo de save state usado pelos hotkeys
+	commandLog      io.Writer     // Aonde os hotkeys de save state escrevem o resultado (nil = em lugar nenhum)
+	rewind          *rewindBuffer // Ultimos quadros, para voltar no tempo (nil = desligado)
+	debugger        *Debugger     // Debugger conectado (opcional)
+	running         bool          // Verdadeiro enquanto o Run estiver executando
+	mu              sync.Mutex    // Segurado pelo Run a cada quadro, permite que o debugger acesse a Machine de outra goroutine
+	renderer        Renderer      // Aonde os graficos são desenhados (opcional)
+	input           InputSource   // De onde vem o estado das teclas (opcional)
+	audio           AudioSink     // Quem toca o som enquanto o sound timer estiver ativo (opcional)
+	toneOn          bool          // Ultimo estado enviado para o AudioSink
+	stop            chan struct{} // Fechado pelo Stop para o Run retornar
+	stopOnce        sync.Once
 }
 
-const keyRepeatDur = time.Second / 5
-const refreshRate = 180
+// Quadros por segundo: a cada quadro o Run executa cyclesPerFrame instruções e desenha a tela uma vez
+const refreshRate = 60
 
-// Inicialização do Chip8 com a fonte inicializada nos primeiros 80 bytes
-func Start(pathToROM string) (*chip_8_VM, error) {
+// Frequencia dos timers (delay e sound), eles decrementam timerSpeed / refreshRate vezes por quadro
+const timerSpeed = 60
 
-	window, err := Display.NewWindow()
+// Frequencia padrão do clock da Machine, em instruções por segundo
+const defaultClockSpeed = 300
 
-	if err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+// Quantos quadros o Run pode ficar atrasado antes de desistir de recuperá-los (ex: depois de uma pausa)
+const maxFrameLag = 5
+
+// New cria uma Machine com a fonte inicializada nos primeiros 80 bytes,
+// sem nenhuma ROM carregada.
+func New(opts ...Option) *Machine {
+	chip_8 := &Machine{
+		stop:           make(chan struct{}),
+		quirks:         QuirksProfiles[DefaultQuirksProfile],
+		palette:        DefaultPalette,
+		database:       DefaultDatabase,
+		random:         rand.New(rand.NewSource(time.Now().UnixNano())),
+		cyclesPerFrame: defaultClockSpeed / refreshRate,
 	}
 
-	chip8_INIT := chip_8_VM{
-		memory:          [4096]byte{},
-		Vx:              [16]byte{},
-		program_counter: 0x200, // Começa no byte 512, já reservado para o inicio dos programas
-		stack:           [16]uint16{},
-		gfx:             [64 * 32]byte{},
-		key:             [16]byte{},
-		Window:          window,
-		Clock:           time.NewTicker(time.Second / 300),
-		audioChan:       make(chan struct{}),
-		Shutdown:        make(chan struct{}),
+	for _, opt := range opts {
+		opt(chip_8)
 	}
 
-	chip8_INIT.loadFontSet()
+	chip_8.Reset()
 
-	// Tenta iniciar a ROM
-	if err := chip8_INIT.LoadROM(pathToROM); err != nil {
-		return nil, err
-	}
+	return chip_8
+}
+
+// Reset coloca a Machine de volta no estado inicial, recarregando a ROM atual (se houver)
+func (chip_8 *Machine) Reset() {
+	chip_8.opcode = 0
+	chip_8.memory = [65536]byte{}
+	chip_8.Vx = [16]byte{}
+	chip_8.index = 0
+	chip_8.program_counter = 0x200 // Começa no byte 512, já reservado para o inicio dos programas
+	chip_8.stack = [16]uint16{}
+	chip_8.stack_pointer = 0
+	chip_8.DelayTimer = 0
+	chip_8.SoundTimer = 0
+	chip_8.gfx = [128 * 64]byte{}
+	chip_8.hires = false
+	chip_8.halted = false
+	chip_8.plane = 1
+	chip_8.pattern = [16]byte{}
+	chip_8.pitch = 64
+	chip_8.key = [16]byte{}
+	chip_8.drawFlag = false
+	chip_8.vblank = false
+	chip_8.frameCycle = 0
+	chip_8.fault = nil
+	chip_8.err = nil
 
-	return &chip8_INIT, nil
+	chip_8.loadFontSet()
 
+	for i := 0; i < len(chip_8.rom); i++ {
+		chip_8.memory[0x200+i] = chip_8.rom[i]
+	}
+
+	if chip_8.rewind != nil {
+		chip_8.rewind = newRewindBuffer(len(chip_8.rewind.deltas))
+	}
 }
 
-func (chip_8 *chip_8_VM) Run() {
+// Run executa a Machine em tempo real até o programa sair (00FD), a janela fechar, o Stop
+// ou uma instrução falhar com o FaultHalt sem debugger conectado, quando a falha é retornada.
+// A cada quadro de 60Hz são executadas cyclesPerFrame instruções, os timers andam e a tela é
+// desenhada uma vez. Os quadros são agendados a partir do inicio, então um quadro atrasado
+// encurta a espera do seguinte e a velocidade não se perde com o tempo.
+func (chip_8 *Machine) Run() error {
+	chip_8.setRunning(true)
+	defer chip_8.setRunning(false)
+
+	const frameTime = time.Second / refreshRate
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	next := time.Now()
 	for {
 		select {
-		case <-chip_8.Clock.C:
-			if !chip_8.Window.Closed() {
-				chip_8.MachineCycle()
-				chip_8.drawOrUpdate()
-				chip_8.HandleKeyInput()
-				chip_8.delayTimerTick()
-				chip_8.soundTimerTick()
-				continue
-			}
-			break
-		case <-chip_8.Shutdown:
+		case <-timer.C:
+		case <-chip_8.stop:
+			return nil
+		}
+		if chip_8.input != nil && chip_8.input.Closed() || !chip_8.frame() {
 			break
 		}
-		break
+
+		next = next.Add(frameTime)
+		wait := time.Until(next)
+		if wait < -maxFrameLag*frameTime {
+			// Muito atrasado (debugger, janela sendo arrastada...), recomeça a contar de agora
+			next, wait = time.Now(), 0
+		}
+		timer.Reset(wait)
+	}
+	return chip_8.Err()
+}
+
+// Stop pede para o Run retornar no inicio do proximo quadro. Pode ser chamado de qualquer
+// goroutine e mais de uma vez; depois dele o Run sempre retorna logo.
+func (chip_8 *Machine) Stop() {
+	chip_8.stopOnce.Do(func() { close(chip_8.stop) })
+}
+
+// SetClockSpeed muda quantas instruções por segundo o Run executa, arredondado para
+// um número inteiro de instruções por quadro. Pode ser feito a qualquer momento.
+func (chip_8 *Machine) SetClockSpeed(hz int) {
+	chip_8.SetCyclesPerFrame((hz + refreshRate/2) / refreshRate)
+}
+
+// SetCyclesPerFrame muda quantas instruções são executadas a cada quadro de 60Hz (o "tickrate" do Octo)
+func (chip_8 *Machine) SetCyclesPerFrame(cycles int) {
+	if cycles < 1 {
+		cycles = 1
+	}
+	chip_8.cyclesPerFrame = cycles
+}
+
+// CyclesPerFrame retorna quantas instruções são executadas a cada quadro de 60Hz
+func (chip_8 *Machine) CyclesPerFrame() int {
+	return chip_8.cyclesPerFrame
+}
+
+// Executa um quadro do Run, retorna false quando o programa saiu do interpretador (00FD) ou falhou.
+// Com um debugger conectado a falha só pausa a Machine.
+func (chip_8 *Machine) frame() bool {
+	chip_8.mu.Lock()
+	defer chip_8.mu.Unlock()
+
+	chip_8.HandleKeyInput()
+	// Enquanto o estado é carregado, o rewind está ativo ou o debugger está pausado, a Machine não anda para frente.
+	// Se o debugger parar no meio do quadro, o resto dele é executado quando a Machine voltar a andar.
+	if !chip_8.handleCommands() {
+		for !chip_8.halted && chip_8.debugger.beforeStep() {
+			ended, err := chip_8.step()
+			if err != nil {
+				if chip_8.debugger == nil {
+					chip_8.err = err
+				}
+				chip_8.debugger.fault(err)
+				break
+			}
+			chip_8.debugger.afterStep()
+			if ended {
+				break
+			}
+		}
 	}
-	chip_8.signalShutdown("Received signal - gracefully shutting down...")
+	chip_8.drawOrUpdate()
+	chip_8.updateTone()
+
+	return !chip_8.halted && chip_8.err == nil
+}
+
+func (chip_8 *Machine) setRunning(running bool) {
+	chip_8.mu.Lock()
+	chip_8.running = running
+	chip_8.mu.Unlock()
+}
+
+// Step executa uma única instrução. Quando as instruções completam um quadro (ver Timing)
+// os timers são atualizados, então os timers andam na mesma proporção com ou sem o Run.
+// Se a instrução falhar, o erro é tratado pela FaultPolicy e retornado no FaultHalt.
+func (chip_8 *Machine) Step() error {
+	_, err := chip_8.step()
+	return err
+}
+
+// Executa uma instrução, retorna true se ela terminou o quadro.
+// No FaultHalt a instrução que falhou não conta no quadro e o PC continua nela.
+func (chip_8 *Machine) step() (bool, error) {
+	cost := chip_8.instructionCost()
+	chip_8.MachineCycle()
+	if err := chip_8.handleFault(); err != nil {
+		return false, err
+	}
+	chip_8.screenChanged = chip_8.screenChanged || chip_8.drawFlag
+	chip_8.frameCycle += cost
+	if chip_8.frameCycle < chip_8.frameBudget() {
+		return false, nil
+	}
+	chip_8.endFrame()
+	return true, nil
+}
+
+// Fim de um quadro de 60Hz: os timers andam, começa o vblank e o quadro é guardado para o rewind.
+// O que passou do quadro fica para o proximo.
+func (chip_8 *Machine) endFrame() {
+	chip_8.frameCycle -= chip_8.frameBudget()
+	if chip_8.frameCycle >= chip_8.frameBudget() {
+		chip_8.frameCycle = 0
+	}
+	for i := 0; i < timerSpeed/refreshRate; i++ {
+		chip_8.delayTimerTick()
+		chip_8.soundTimerTick()
+	}
+	chip_8.vblank = true
+	chip_8.recordRewind()
 }
 
 // Carrega a font nos primeiros 80 bytes de memoria
-func (chip_8 *chip_8_VM) loadFontSet() {
+func (chip_8 *Machine) loadFontSet() {
 	for i := 0; i < 80; i++ {
-		chip_8.memory[i] = Display.FontSet[i]
+		chip_8.memory[i] = FontSet[i]
 	}
+	copy(chip_8.memory[bigFontAddr:], BigFontSet[:])
 }
 
-// Pega o caminho da ROM e carrega ela no Chip8
-func (chip_8 *chip_8_VM) LoadROM(path string) error {
+// Pega o caminho da ROM e carrega ela no Chip8.
+// Arquivos com a extensão de um formato registrado pelo RegisterFormat (ex: ".8o") são convertidos antes.
+func (chip_8 *Machine) LoadROM(path string) error {
 	rom, err := ioutil.ReadFile(path)
 
 	if err != nil {
 		return err
 	}
 
-	if len(rom) >= 3585 {
-		panic("ERROR: ROM TOO LARGE - MAX SIZE: 3584") // Se a ROM ultrapassar o espaço dedicado para o interpretador ocorrerá um "panic"
+	program := &Program{ROM: rom}
+	if format := formatFor(path); format != nil {
+		if program, err = format(path, rom); err != nil {
+			return err
+		}
+	}
+	chip_8.database.fill(program)
+	if err := loadCompanions(path, program); err != nil {
+		return err
+	}
+
+	// A ROM é conferida com a plataforma do programa antes de qualquer configuração mudar
+	mode := chip_8.mode
+	if program.Mode != nil {
+		mode = *program.Mode
+	}
+	if err := chip_8.loadBytes(program.ROM, mode); err != nil {
+		return err
 	}
+	chip_8.applyProgram(program)
+	chip_8.romPath = path
+	chip_8.symbols = program.Symbols
+	chip_8.info = program.Info
+
+	return nil
+}
 
-	for i := 0; i < len(rom); i++ {
-		chip_8.memory[0x200+i] = rom[i] // Memoria começa 0x200 (512) + x, tirando espaço reservado para as fontes (512 bits)
+// ROM retorna uma cópia da ROM carregada (já convertida, no caso de código fonte ou cartucho)
+func (chip_8 *Machine) ROM() []byte {
+	return append([]byte(nil), chip_8.rom...)
+}
+
+// LoadBytes carrega uma ROM que já está em memoria e reinicia a Machine
+func (chip_8 *Machine) LoadBytes(rom []byte) error {
+	return chip_8.loadBytes(rom, chip_8.mode)
+}
+
+// Carrega a ROM na plataforma mode, que só é trocada se a ROM couber na memoria dela
+func (chip_8 *Machine) loadBytes(rom []byte, mode Mode) error {
+	// A ROM tem que caber no espaço depois do interpretador
+	if max := mode.MemorySize() - 0x200; len(rom) > max {
+		return ErrROMTooLarge{Size: len(rom), Max: max, Mode: mode}
 	}
 
+	chip_8.mode = mode
+	chip_8.rom = append([]byte(nil), rom...)
+	chip_8.romPath = ""
+	chip_8.symbols = nil
+	chip_8.info = nil
+	chip_8.Reset() // Memoria começa 0x200 (512) + x, tirando espaço reservado para as fontes (512 bits)
+
 	return nil
 }
 
-func (chip_8 *chip_8_VM) MachineCycle() {
+func (chip_8 *Machine) MachineCycle() {
 	// Um opcode tem 2 bytes (16bit) de comprimento, por exemplo 0xA2F0 -> (0xA2 e 0xF0) -> e então transformar ele em um opcode válido
 	// Primeiro temos de realizar uma operação de shift na instrução atual, ex: 10100010 - 8bit => 10100010 <<8 => 1010001000000000
 	// Após isso temos de realizar uma operação OR para então termos os 16 bits necessarios para ser um opcode.
 
 	// Operação OR vai pegar os "0" do lado direito e transformar no valor correspondente do byte
-	chip_8.opcode = uint16(chip_8.memory[chip_8.program_counter])<<8 | uint16(chip_8.memory[chip_8.program_counter+1])
+	if chip_8.halted {
+		return
+	}
+
+	// A instrução (2 bytes) tem que estar dentro da memoria da plataforma
+	if chip_8.outOfBounds(int(chip_8.program_counter), 2) >= 0 {
+		chip_8.fail(ErrMemoryOutOfBounds{Addr: chip_8.program_counter, Target: int(chip_8.program_counter), Size: chip_8.mode.MemorySize()})
+		return
+	}
+
+	chip_8.opcode = chip_8.nextOpcode()
 	chip_8.drawFlag = false
 
 	chip_8.parseOpcode()
 
 }
 
-func (chip_8 *chip_8_VM) parseOpcode() {
+func (chip_8 *Machine) parseOpcode() {
 	// Chip_8 Variables
 	x := (chip_8.opcode & 0x0F00) >> 8 // 4 menores bits da instrução de maior nivel, como é um valor de 4 bits precisamos jogar ele pra ponta, tirando 8 zeros
 	y := (chip_8.opcode & 0x00F0) >> 4 // 4 maiores bits da instrução de menor nivel, como é um valor de 4 bits precisamos jogar ele pra ponta, tirando 4 zeros
@@ -152,18 +383,77 @@ func (chip_8 *chip_8_VM) parseOpcode() {
 	case 0x0000:
 		// Bit mask para os primeiros 8 bits - 11111111 == 255, ou seja, este switch abrange de 0 até 255
 		// 4(0)4(0)4(1)4(1) - 0x00FF => 0000000011111111
+		// 00CN -> Rola a tela N linhas para baixo (SUPER-CHIP)
+		if chip_8.opcode&0xFFF0 == 0x00C0 {
+			if chip_8.requireMode(SuperChipMode) {
+				chip_8.scrollDown(int(chip_8.opcode & 0x000F))
+				chip_8.program_counter += 2
+			}
+			break
+		}
+		// 00DN -> Rola a tela N linhas para cima (XO-CHIP)
+		if chip_8.opcode&0xFFF0 == 0x00D0 {
+			if chip_8.requireMode(XOChipMode) {
+				chip_8.scrollUp(int(chip_8.opcode & 0x000F))
+				chip_8.program_counter += 2
+			}
+			break
+		}
+
 		switch chip_8.opcode & 0x00FF {
 		// Case 224
 		case 0x00E0:
-			// Comando que limpa a tela
-			chip_8.gfx = [64 * 32]byte{}
+			// Comando que limpa a tela (apenas os bitplanes selecionados)
+			for i := range chip_8.gfx {
+				chip_8.gfx[i] &^= chip_8.plane
+			}
+			chip_8.debugger.screenCleared()
+			chip_8.drawFlag = true
 			chip_8.program_counter += 2
 		// Case 238
 		case 0x00EE:
 			// Retorna de uma subrotina
 			// The interpreter sets the program counter to the address at the top of the stack, then subtracts 1 from the stack pointer.
-			chip_8.program_counter = chip_8.stack[chip_8.stack_pointer] + 2
-			chip_8.stack_pointer--
+			if addr, ok := chip_8.popStack(); ok {
+				chip_8.program_counter = addr + 2
+			}
+		case 0x00FB:
+			// 00FB -> Rola a tela 4 pixels para a direita (SUPER-CHIP)
+			if !chip_8.requireMode(SuperChipMode) {
+				break
+			}
+			chip_8.scrollRight(4)
+			chip_8.program_counter += 2
+		case 0x00FC:
+			// 00FC -> Rola a tela 4 pixels para a esquerda (SUPER-CHIP)
+			if !chip_8.requireMode(SuperChipMode) {
+				break
+			}
+			chip_8.scrollLeft(4)
+			chip_8.program_counter += 2
+		case 0x00FD:
+			// 00FD -> Sai do interpretador (SUPER-CHIP)
+			if !chip_8.requireMode(SuperChipMode) {
+				break
+			}
+			chip_8.halted = true
+		case 0x00FE:
+			// 00FE -> Desliga o modo de alta resolução, tela 64x32 (SUPER-CHIP)
+			if !chip_8.requireMode(SuperChipMode) {
+				break
+			}
+			chip_8.setHighResolution(false)
+			chip_8.program_counter += 2
+		case 0x00FF:
+			// 00FF -> Liga o modo de alta resolução, tela 128x64 (SUPER-CHIP)
+			if !chip_8.requireMode(SuperChipMode) {
+				break
+			}
+			chip_8.setHighResolution(true)
+			chip_8.program_counter += 2
+		default:
+			// 0NNN -> Subrotinas em linguagem de máquina não são emuladas
+			chip_8.fail(ErrUnknownOpcode{Addr: chip_8.program_counter, Op: chip_8.opcode})
 		}
 
 	// ex: irá ser comparado os 4 primeiros recebidos do bitwise do opcode com os 4 primeiros desse case, no caso = (0001)...
@@ -173,14 +463,14 @@ func (chip_8 *chip_8_VM) parseOpcode() {
 	case 0x2000:
 		// 2NNN -> Executa subrotina começando no endereço NNN
 		// The interpreter increments the stack pointer, then puts the current PC on the top of the stack. The PC is then set to nnn.
-		chip_8.stack_pointer++
-		chip_8.stack[chip_8.stack_pointer] = chip_8.program_counter
-		chip_8.program_counter = nnn
+		if chip_8.pushStack(chip_8.program_counter) {
+			chip_8.program_counter = nnn
+		}
 	case 0x3000:
 		// 3NNN -> Pula a proxima instrução se o valor do registrador Vx == NN
 		// The interpreter increments the stack pointer, then puts the current PC on the top of the stack. The PC is then set to nnn.
 		if chip_8.Vx[x] == nn {
-			chip_8.program_counter += 4
+			chip_8.skipNext()
 		} else {
 			chip_8.program_counter += 2
 		}
@@ -189,17 +479,48 @@ func (chip_8 *chip_8_VM) parseOpcode() {
 		// The interpreter compares register Vx to kk, and if they are not equal, increments the program counter by 2.
 
 		if chip_8.Vx[x] != nn {
-			chip_8.program_counter += 4
+			chip_8.skipNext()
 		} else {
 			chip_8.program_counter += 2
 		}
 	case 0x5000:
-		// 5XY0 -> Pula a proxima instrução se o valor do registrador Vx != Vy
-		// The interpreter compares register Vx to register Vy, and if they are equal, increments the program counter by 2.
-		if chip_8.Vx[x] == chip_8.Vx[y] {
-			chip_8.program_counter += 4
-		} else {
+		switch chip_8.opcode & 0x000F {
+		case 0x0000:
+			// 5XY0 -> Pula a proxima instrução se o valor do registrador Vx != Vy
+			// The interpreter compares register Vx to register Vy, and if they are equal, increments the program counter by 2.
+			if chip_8.Vx[x] == chip_8.Vx[y] {
+				chip_8.skipNext()
+			} else {
+				chip_8.program_counter += 2
+			}
+		case 0x0002:
+			// 5XY2 -> Guarda os registradores Vx até Vy na memoria a partir do I(ndex), sem alterar o I (XO-CHIP)
+			if !chip_8.requireMode(XOChipMode) {
+				break
+			}
+			regs := registerRange(x, y)
+			if !chip_8.checkMemory(int(chip_8.index), len(regs)) {
+				break
+			}
+			for i, reg := range regs {
+				chip_8.writeMemory(int(chip_8.index)+i, chip_8.Vx[reg])
+			}
 			chip_8.program_counter += 2
+		case 0x0003:
+			// 5XY3 -> Preenche os registradores Vx até Vy com a memoria a partir do I(ndex), sem alterar o I (XO-CHIP)
+			if !chip_8.requireMode(XOChipMode) {
+				break
+			}
+			regs := registerRange(x, y)
+			if !chip_8.checkMemory(int(chip_8.index), len(regs)) {
+				break
+			}
+			for i, reg := range regs {
+				chip_8.Vx[reg] = chip_8.readMemory(int(chip_8.index) + i)
+			}
+			chip_8.program_counter += 2
+		default:
+			chip_8.fail(ErrUnknownOpcode{Addr: chip_8.program_counter, Op: chip_8.opcode})
 		}
 	case 0x6000:
 		// 6XNN -> Guarda o numero NN no registrador Vx
@@ -224,66 +545,65 @@ func (chip_8 *chip_8_VM) parseOpcode() {
 		case 0x0001:
 			// 8XY1 -> Transforma Vx em Vx ou Vy
 			chip_8.Vx[x] |= chip_8.Vx[y]
+			chip_8.resetVF()
 			chip_8.program_counter += 2
 		case 0x0002:
 			// 8XY2 -> Transforma Vx em Vx e Vy
 			chip_8.Vx[x] &= chip_8.Vx[y]
+			chip_8.resetVF()
 			chip_8.program_counter += 2
 		case 0x0003:
 			// 8XY3 -> Transforma Vx em Vx xor Vy
 			chip_8.Vx[x] ^= chip_8.Vx[y]
+			chip_8.resetVF()
 			chip_8.program_counter += 2
 		case 0x0004: // LEARN WHATS HAPPENING HERE ?
 			// 8XY4 -> Set Vx = Vx + Vy, set VF = carry.
 			// se o resultado for acima de 8 bits, a flag sera setada = 1, senão 0; Apenas os 8 "menores" bits sao mantidos no Vx
-			if chip_8.Vx[y] > (0xFF - chip_8.Vx[x]) {
-				chip_8.Vx[0xF] = 1
-			} else {
-				chip_8.Vx[0xF] = 0
-			}
+			// O VF é escrito depois do resultado, então ele também vale quando X = F
+			carry := boolByte(int(chip_8.Vx[x])+int(chip_8.Vx[y]) > 0xFF)
 			chip_8.Vx[x] += chip_8.Vx[y]
+			chip_8.Vx[0xF] = carry
 			chip_8.program_counter += 2
 		case 0x0005:
 			// 8XY5 -> Set Vx = Vx - Vy, set VF = NOT borrow.
-			// Se Vx > Vy, a flag sera setada = 1, senão a flag será 0, resultado guardado em Vx
-			if chip_8.Vx[x] > chip_8.Vx[y] {
-				chip_8.Vx[0xF] = 1
-			} else {
-				chip_8.Vx[0xF] = 0
-			}
+			// Se Vx >= Vy, a flag sera setada = 1, senão a flag será 0, resultado guardado em Vx
+			notBorrow := boolByte(chip_8.Vx[x] >= chip_8.Vx[y])
 			chip_8.Vx[x] -= chip_8.Vx[y]
+			chip_8.Vx[0xF] = notBorrow
 			chip_8.program_counter += 2
 		case 0x0006:
 			// 8XY6 -> Guarda o valor do registro Vy shifted 1 bit para direita no registro Vx
 			// Seta a flag para o "least significant" bit no shift
-			chip_8.Vx[x] = chip_8.Vx[y] >> 1 // divide by 2
-			chip_8.Vx[0xF] = chip_8.Vx[y] & 0x01
+			// Com o quirk Shift, o proprio Vx é deslocado
+			src := chip_8.shiftSource(x, y)
+			chip_8.Vx[x] = src >> 1 // divide by 2
+			chip_8.Vx[0xF] = src & 0x01
 			chip_8.program_counter += 2
 		case 0x0007:
 			// 8XY7 -> Set Vx = Vy - Vx, set VF = NOT borrow.
-			// Vy > Vx, flag = 1, senão flag = 0, então Vx - Vy, guarda em Vx
-			if chip_8.Vx[y] > chip_8.Vx[x] {
-				chip_8.Vx[0xF] = 1
-			} else {
-				chip_8.Vx[0xF] = 0
-			}
-			chip_8.Vx[x] = chip_8.Vx[x] - chip_8.Vx[y]
+			// Vy >= Vx, flag = 1, senão flag = 0, então Vy - Vx, guarda em Vx
+			notBorrow := boolByte(chip_8.Vx[y] >= chip_8.Vx[x])
+			chip_8.Vx[x] = chip_8.Vx[y] - chip_8.Vx[x]
+			chip_8.Vx[0xF] = notBorrow
 			chip_8.program_counter += 2
 
 		case 0x000E:
 			// 8XYE -> Store the value of register VY shifted left one bit in register VX
 			// Set register VF to the most significant bit prior to the shift
-			chip_8.Vx[x] = chip_8.Vx[y] << 1     // multiply by 2
-			chip_8.Vx[0xF] = chip_8.Vx[y] & 0x80 // most significant bit (bitmasking)
+			// Com o quirk Shift, o proprio Vx é deslocado
+			src := chip_8.shiftSource(x, y)
+			chip_8.Vx[x] = src << 1            // multiply by 2
+			chip_8.Vx[0xF] = (src & 0x80) >> 7 // most significant bit (bitmasking)
 			chip_8.program_counter += 2
 
 		default:
-			fmt.Printf("unknown opcode: %x\n", chip_8.opcode&0x000F)
+			chip_8.fail(ErrUnknownOpcode{Addr: chip_8.program_counter, Op: chip_8.opcode})
 		}
 	case 0x9000:
 		// 9XY0 -> Pula a proxima instrução se o valor de Vx != valor de Vy
 		if chip_8.Vx[x] != chip_8.Vx[y] {
-			chip_8.program_counter += 4
+			chip_8.skipNext()
 		} else {
 			chip_8.program_counter += 2
 		}
@@ -293,86 +613,118 @@ func (chip_8 *chip_8_VM) parseOpcode() {
 		chip_8.program_counter += 2
 	case 0xB000:
 		// BNNN -> Pula para o endereço NNN	+ V0
-		chip_8.program_counter = nnn + uint16(chip_8.Vx[0])
-		chip_8.program_counter += 2
+		// Com o quirk Jump, vira BXNN -> Pula para o endereço XNN + Vx
+		if chip_8.quirks.Jump {
+			chip_8.program_counter = nnn + uint16(chip_8.Vx[x])
+		} else {
+			chip_8.program_counter = nnn + uint16(chip_8.Vx[0])
+		}
 	case 0xC000:
 		// CXNN -> Seta Vx como um numero aleatorio com a mascara de NN
-		chip_8.Vx[x] = byte(rand.Float32()*255) & nn
+		chip_8.Vx[x] = byte(chip_8.random.Float32()*255) & nn
 		chip_8.program_counter += 2
 	case 0xD000:
 		// DXYN -> Desenha um sprite na posição Vx,Vy com N bytes, começando no endereço guardado no I(ndex)
 		// Setar flag como 1 se tem pixels que serão "desligados", se não flag = 0
 
-		x = uint16(chip_8.Vx[x])
-		y = uint16(chip_8.Vx[y])
-
-		var pix uint16
-		height := chip_8.opcode & 0x000F // Pegamos o N do "DXYN" - Indica numero de linhas
-		chip_8.Vx[0xF] = 0               // Reseta flag de colisão
-
-		// A logica do loop se baseia em pegar um determinado numero de linhas (N)
-		// irmos bit por bit dessas linhas e verificar se eles estão ligados (1) ou desligados(0)
-		// se eles tiverem ligados precisamos aplicar uma operação xor, invertendo-os
-		// se ele estiver ligado, e no mesmo lugar da tela já possuem pixels ligados, devemos setar a flag de colisão
-		for yPoint := uint16(0); yPoint < height; yPoint++ {
-			pix = uint16(chip_8.memory[chip_8.index+yPoint]) // Começamos no endereço que está no index, assim como manda a doc.
-			for xPoint := uint16(0); xPoint < 8; xPoint++ {  // Cada sprite tem 8 bits de tamanho
-				ind := (x + xPoint + ((y + yPoint) * 64)) // Posição atual na tela - 64 é o numero de linhas
-				if ind > uint16(len(chip_8.GetGraphics())) {
-					continue
-				}
-				if (pix & (0x80 >> xPoint)) != 0 { // ex: 1010101 & 1000000 -> 1010101 & 0100000 -> ....  verifica se cada pixel esta setado
-					if chip_8.GetGraphics()[ind] == 1 { // Verifica Pixel Collision
-						chip_8.Vx[0xF] = 1 // Seta Colisão como verdadeira
-					}
-					chip_8.gfx[ind] ^= 1 // aplica a operação xor na tela
-				}
-			}
+		// Com o quirk DisplayWait, a instrução é repetida até o proximo quadro começar
+		if chip_8.quirks.DisplayWait && !chip_8.vblank {
+			return
+		}
+
+		// DXY0 -> No SUPER-CHIP desenha um sprite de 16x16 (32 bytes)
+		if !chip_8.drawSprite(chip_8.Vx[x], chip_8.Vx[y], chip_8.opcode&0x000F) {
+			break
+		}
+		chip_8.vblank = false
+		if chip_8.Vx[0xF] == 1 {
+			chip_8.debugger.collision()
 		}
 
 		chip_8.drawFlag = true // Comando para atualizar a tela
 		chip_8.program_counter += 2
 	case 0xE000:
 		// Bitmask com 8 primeiros bits
+		// Só os 4 bits baixos do Vx escolhem a tecla, como no COSMAC VIP
 		switch chip_8.opcode & 0x00FF {
 		case 0x009E:
 			// EX9E -> Pula a proxima instrução se a tecla correspondente ao valor que está no registro Vx é pressionada
-			if chip_8.key[chip_8.Vx[x]] == 1 {
-				chip_8.program_counter += 4
-
-				chip_8.key[chip_8.Vx[x]] = 0
+			if chip_8.key[chip_8.Vx[x]&0xF] == 1 {
+				chip_8.skipNext()
 			} else {
 				chip_8.program_counter += 2
 			}
 
 		case 0x00A1:
 			// EXA1 -> Pula a proxima instrução se a tecla correspondente ao valor que está no registro Vx não é pressionada
-			if chip_8.key[chip_8.Vx[x]] == 0 {
-				chip_8.program_counter += 4
-
+			if chip_8.key[chip_8.Vx[x]&0xF] == 0 {
+				chip_8.skipNext()
 			} else {
-				chip_8.key[chip_8.Vx[x]] = 0
 				chip_8.program_counter += 2
 			}
-
+		default:
+			chip_8.fail(ErrUnknownOpcode{Addr: chip_8.program_counter, Op: chip_8.opcode})
 		}
 	case 0xF000:
 		// Bitmask com 8 primeiros bits
 		switch chip_8.opcode & 0x00FF {
+		case 0x0000:
+			// F000 NNNN -> Guarda o endereço de 16 bits NNNN (proxima word) no I(ndex) (XO-CHIP)
+			if !chip_8.requireMode(XOChipMode) {
+				break
+			}
+			if !chip_8.checkMemory(int(chip_8.program_counter)+2, 2) {
+				break
+			}
+			chip_8.index = chip_8.nextWord()
+
+			chip_8.program_counter += 4
+		case 0x0001:
+			// FN01 -> Seleciona os bitplanes N usados para desenhar, limpar e rolar a tela (XO-CHIP)
+			if !chip_8.requireMode(XOChipMode) {
+				break
+			}
+			chip_8.plane = byte(x) & 0x3
+
+			chip_8.program_counter += 2
+		case 0x0002:
+			// F002 -> Carrega 16 bytes a partir do I(ndex) no audio pattern buffer (XO-CHIP)
+			if !chip_8.requireMode(XOChipMode) {
+				break
+			}
+			if !chip_8.checkMemory(int(chip_8.index), len(chip_8.pattern)) {
+				break
+			}
+			for i := range chip_8.pattern {
+				chip_8.pattern[i] = chip_8.readMemory(int(chip_8.index) + i)
+			}
+			chip_8.patternChanged()
+
+			chip_8.program_counter += 2
+		case 0x003A:
+			// FX3A -> Seta o registrador de pitch do audio para o valor de Vx (XO-CHIP)
+			if !chip_8.requireMode(XOChipMode) {
+				break
+			}
+			chip_8.pitch = chip_8.Vx[x]
+			chip_8.patternChanged()
+
+			chip_8.program_counter += 2
 		case 0x0007:
 			// FX07 -> Guarda o valor atual do delay timer no registrador Vx
 			chip_8.Vx[x] = chip_8.DelayTimer
 			chip_8.program_counter += 2
 		case 0x000A:
 			// FX0A -> Aguarda uma tecla ser pressionada para guardar o resultado no registrador VX
+			// A tecla é solta ao ser lida, só uma nova pressão conta para o proximo FX0A
 			for index, key := range chip_8.key {
 				if key != 0 { // keypress
 					chip_8.Vx[x] = byte(index)
+					chip_8.key[index] = 0
 					chip_8.program_counter += 2
 					break
 				}
 			}
-			chip_8.key[chip_8.Vx[x]] = 0
 		case 0x0015:
 			// FX15 -> Seta o Delay timer para o valor do registro Vx
 			chip_8.DelayTimer = chip_8.Vx[x]
@@ -392,162 +744,200 @@ func (chip_8 *chip_8_VM) parseOpcode() {
 			// FX29 -> Seta o Valor i(ndex) para o endereço de memoria do sprite correspondente ao digito hexadecimal guardado em Vx
 			chip_8.index = uint16(chip_8.Vx[x]) * 5
 
+			chip_8.program_counter += 2
+		case 0x0030:
+			// FX30 -> Seta o I(ndex) para o sprite grande (8x10) do digito em Vx (SUPER-CHIP)
+			if !chip_8.requireMode(SuperChipMode) {
+				break
+			}
+			chip_8.index = bigFontAddr + uint16(chip_8.Vx[x]&0xF)*10
+
 			chip_8.program_counter += 2
 		case 0x0033:
 			// FX33 -> Store the binary-coded decimal equivalent of the value stored in register VX at addresses I, I+1, and I+2
-			chip_8.memory[chip_8.index] = chip_8.Vx[x] / 100          // places the hundreds digit in memory at location in I
-			chip_8.memory[chip_8.index+1] = (chip_8.Vx[x] / 10) % 10  // places the tens digit at location I+1
-			chip_8.memory[chip_8.index+2] = (chip_8.Vx[x] % 100) % 10 // places the ones digit at location I+2
+			i := int(chip_8.index)
+			if !chip_8.checkMemory(i, 3) {
+				break
+			}
+			chip_8.writeMemory(i, chip_8.Vx[x]/100)        // places the hundreds digit in memory at location in I
+			chip_8.writeMemory(i+1, (chip_8.Vx[x]/10)%10)  // places the tens digit at location I+1
+			chip_8.writeMemory(i+2, (chip_8.Vx[x]%100)%10) // places the ones digit at location I+2
 
 			chip_8.program_counter += 2
 		case 0x0055:
 			// FX55 -> Store the values of registers V0 to VX inclusive in memory starting at address I
 			// I is set to I + X + 1 after operation
+			if !chip_8.checkMemory(int(chip_8.index), int(x)+1) {
+				break
+			}
 			for reg_index := uint16(0); reg_index <= x; reg_index++ {
-				chip_8.memory[chip_8.index+reg_index] = chip_8.Vx[reg_index]
+				chip_8.writeMemory(int(chip_8.index)+int(reg_index), chip_8.Vx[reg_index])
 			}
+			chip_8.advanceIndex(x)
 
 			chip_8.program_counter += 2
 		case 0x0065:
 			// FX65 -> Fill registers V0 to VX inclusive with the values stored in memory starting at address I
 			// I is set to I + X + 1 after operation
+			if !chip_8.checkMemory(int(chip_8.index), int(x)+1) {
+				break
+			}
 			for reg_index := uint16(0); reg_index <= x; reg_index++ {
-				chip_8.Vx[reg_index] = chip_8.memory[chip_8.index+reg_index]
+				chip_8.Vx[reg_index] = chip_8.readMemory(int(chip_8.index) + int(reg_index))
 			}
+			chip_8.advanceIndex(x)
+			chip_8.program_counter += 2
+		case 0x0075:
+			// FX75 -> Guarda os registradores V0 até VX nas RPL flags (SUPER-CHIP)
+			if !chip_8.requireMode(SuperChipMode) {
+				break
+			}
+			copy(chip_8.rpl[:x+1], chip_8.Vx[:x+1])
+
+			chip_8.program_counter += 2
+		case 0x0085:
+			// FX85 -> Preenche os registradores V0 até VX com as RPL flags (SUPER-CHIP)
+			if !chip_8.requireMode(SuperChipMode) {
+				break
+			}
+			copy(chip_8.Vx[:x+1], chip_8.rpl[:x+1])
+
 			chip_8.program_counter += 2
 		default:
-			fmt.Printf("unknown opcode: %x\n", chip_8.opcode&0x00FF)
+			chip_8.fail(ErrUnknownOpcode{Addr: chip_8.program_counter, Op: chip_8.opcode})
 		}
 	default:
-		fmt.Printf("unknown opcode: %x\n", chip_8.opcode&0x00FF)
+		chip_8.fail(ErrUnknownOpcode{Addr: chip_8.program_counter, Op: chip_8.opcode})
+
+	}
+}
 
+// Com o quirk VFReset, as operações logicas (8XY1, 8XY2, 8XY3) zeram o VF
+func (chip_8 *Machine) resetVF() {
+	if chip_8.quirks.VFReset {
+		chip_8.Vx[0xF] = 0
 	}
 }
 
-// GetGraphics TODO: doc
-func (chip_8 *chip_8_VM) GetGraphics() [64 * 32]byte {
-	return chip_8.gfx
+// Depois do FX55/FX65, o I(ndex) avança para I + X + 1 com o quirk LoadStore ou para I + X com o LoadStoreX
+func (chip_8 *Machine) advanceIndex(x uint16) {
+	switch {
+	case chip_8.quirks.LoadStore:
+		chip_8.index += x + 1
+	case chip_8.quirks.LoadStoreX:
+		chip_8.index += x
+	}
 }
 
-func (chip_8 *chip_8_VM) DrawFlag() bool {
+// Registrador que será deslocado pelo 8XY6/8XYE, depende do quirk Shift
+func (chip_8 *Machine) shiftSource(x, y uint16) byte {
+	if chip_8.quirks.Shift {
+		return chip_8.Vx[x]
+	}
+	return chip_8.Vx[y]
+}
+
+// GetGraphics retorna uma cópia dos pixels da tela (64x32 ou 128x64).
+// Cada pixel guarda um bit por bitplane, 0 = desligado, 1 = primeiro plano, 2 = segundo e 3 = ambos
+func (chip_8 *Machine) GetGraphics() []byte {
+	pixels := make([]byte, chip_8.width()*chip_8.height())
+	copy(pixels, chip_8.gfx[:])
+	return pixels
+}
+
+// Halted indica que o programa executou o 00FD e a Machine parou
+func (chip_8 *Machine) Halted() bool {
+	return chip_8.halted
+}
+
+func (chip_8 *Machine) DrawFlag() bool {
 	return chip_8.drawFlag
 }
 
 // SetKeyDown marks the specified key as down.
-// Once read, the key state will be reset to up
-func (chip_8 *chip_8_VM) SetKeyDown(index byte) {
-	chip_8.key[index] = 1
+func (chip_8 *Machine) SetKeyDown(index byte) {
+	chip_8.key[index&0xF] = 1
 }
 
-func (chip_8 *chip_8_VM) ManageAudio() {
-	f, err := os.Open("assets/beep.mp3")
-	if err != nil {
-		return
-	}
+// SetKeyUp marks the specified key as up.
+func (chip_8 *Machine) SetKeyUp(index byte) {
+	chip_8.key[index&0xF] = 0
+}
 
-	streamer, format, err := mp3.Decode(f)
-	if err != nil {
-		return
-	}
-	defer streamer.Close()
+// Registers retorna uma cópia dos registradores V0 - VF
+func (chip_8 *Machine) Registers() [16]byte {
+	return chip_8.Vx
+}
 
-	speaker.Init(
-		format.SampleRate,
-		format.SampleRate.N(time.Second/10),
-	)
+// Index retorna o valor do registrador I(ndex)
+func (chip_8 *Machine) Index() uint16 {
+	return chip_8.index
+}
 
-	for range chip_8.audioChan {
-		speaker.Play(streamer)
-	}
+// ProgramCounter retorna o endereço da próxima instrução
+func (chip_8 *Machine) ProgramCounter() uint16 {
+	return chip_8.program_counter
 }
 
-func (chip_8 *chip_8_VM) HandleKeyInput() {
-	for i, key := range chip_8.Window.KeyMap {
-		// Se uma tecla é pressionada
-		if chip_8.Window.JustReleased(key) {
-			if chip_8.Window.KeysDown[i] != nil {
-				chip_8.Window.KeysDown[i].Stop()
-				chip_8.Window.KeysDown[i] = nil
-			}
-		} else if chip_8.Window.JustPressed(key) {
-			if chip_8.Window.KeysDown[i] == nil {
-				chip_8.Window.KeysDown[i] = time.NewTicker(keyRepeatDur)
-			}
-			chip_8.SetKeyDown(byte(i))
-		}
+// Stack retorna uma cópia da pilha e o stack pointer atual
+func (chip_8 *Machine) Stack() ([16]uint16, uint16) {
+	return chip_8.stack, chip_8.stack_pointer
+}
 
-		if chip_8.Window.KeysDown[i] == nil {
-			continue
-		}
+// Opcode retorna a ultima instrução executada
+func (chip_8 *Machine) Opcode() uint16 {
+	return chip_8.opcode
+}
 
-		select {
-		case <-chip_8.Window.KeysDown[i].C:
+// Memory retorna uma cópia da memoria (4KB, ou 64KB no XO-CHIP)
+func (chip_8 *Machine) Memory() []byte {
+	memory := make([]byte, chip_8.mode.MemorySize())
+	copy(memory, chip_8.memory[:])
+	return memory
+}
+
+// HandleKeyInput copia o estado das teclas do InputSource para a Machine
+func (chip_8 *Machine) HandleKeyInput() {
+	if chip_8.input == nil {
+		return
+	}
+	for i, down := range chip_8.input.KeyState() {
+		if down {
 			chip_8.SetKeyDown(byte(i))
-		default:
+		} else {
+			chip_8.SetKeyUp(byte(i))
 		}
 	}
 }
 
-// Se drawflag == true, precisamos renderizar os graficos de novo
-func (chip_8 *chip_8_VM) drawOrUpdate() {
-	if chip_8.DrawFlag() {
-		chip_8.Window.DrawGraphics(chip_8.GetGraphics())
-	} else {
-		chip_8.Window.UpdateInput()
+// Se a tela mudou durante o quadro, precisamos renderizar os graficos de novo
+func (chip_8 *Machine) drawOrUpdate() {
+	if chip_8.renderer != nil && chip_8.screenChanged {
+		chip_8.renderer.Render(chip_8.Frame())
+	}
+	chip_8.screenChanged = false
+}
+
+// Liga o som enquanto o sound timer estiver ativo e desliga quando ele zerar
+func (chip_8 *Machine) updateTone() {
+	on := chip_8.SoundTimer > 0
+	if chip_8.audio == nil || on == chip_8.toneOn {
+		return
 	}
+	chip_8.toneOn = on
+	chip_8.audio.SetTone(on)
 }
 
 // Decrementa o delay timer
-func (chip_8 *chip_8_VM) delayTimerTick() {
+func (chip_8 *Machine) delayTimerTick() {
 	if chip_8.DelayTimer > 0 {
 		chip_8.DelayTimer--
 	}
 }
 
-// Manipula o soundtimer, utiliza channels para passar info
-func (chip_8 *chip_8_VM) soundTimerTick() {
+// Decrementa o sound timer, o AudioSink é avisado pelo updateTone
+func (chip_8 *Machine) soundTimerTick() {
 	if chip_8.SoundTimer > 0 {
-		if chip_8.SoundTimer == 1 {
-			chip_8.audioChan <- struct{}{}
-		}
 		chip_8.SoundTimer--
 	}
 }
-
-// Se recebido sinal de shutdown, fecha o sinal de audio.
-func (chip_8 *chip_8_VM) signalShutdown(msg string) {
-
-	close(chip_8.audioChan)
-	chip_8.Shutdown <- struct{}{}
-}
-
-func (chip_8 *chip_8_VM) debug() {
-	fmt.Printf(`
-	opcode: %x
-	pc: %d
-	sp: %d
-	i: %d
-	Registers:
-	V0: %d
-	V1: %d
-	V2: %d
-	V3: %d
-	V4: %d
-	V5: %d
-	V6: %d
-	V7: %d
-	V8: %d
-	V9: %d
-	VA: %d
-	VB: %d
-	VC: %d
-	VD: %d
-	VE: %d
-	VF: %d`,
-		chip_8.opcode, chip_8.program_counter, chip_8.stack_pointer, chip_8.index,
-		chip_8.Vx[0], chip_8.Vx[1], chip_8.Vx[2], chip_8.Vx[3],
-		chip_8.Vx[4], chip_8.Vx[5], chip_8.Vx[6], chip_8.Vx[7],
-		chip_8.Vx[8], chip_8.Vx[9], chip_8.Vx[10], chip_8.Vx[11],
-		chip_8.Vx[12], chip_8.Vx[13], chip_8.Vx[14], chip_8.Vx[15],
-	)
-}
@@ -0,0 +1,230 @@
+package Chip8
+
+import (
+	"bytes"
+	"crypto/sha1"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"image/color"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+)
+
+// Banco de dados embutido, no formato do programs.json do chip-8-database
+// (https://github.com/chip-8/chip-8-database) com as ROMs que acompanham o projeto
+//
+//go:embed database/programs.json
+var embeddedDatabase []byte
+
+// DefaultDatabase é o banco usado pelo LoadROM quando nenhum outro é escolhido com WithDatabase
+var DefaultDatabase = mustLoadDatabase(embeddedDatabase)
+
+// ROMInfo descreve uma ROM conhecida pelo banco de dados
+type ROMInfo struct {
+	SHA1        string
+	Title       string
+	Authors     []string
+	Release     string
+	Description string
+	Platform    string          // Plataforma escolhida (ex: "superchip"), como no chip-8-database
+	Keys        map[string]byte // Ação do jogo ("up", "a", "player1Up"...) -> tecla do Chip-8
+}
+
+// Database guarda as ROMs conhecidas pelo SHA-1 delas
+type Database struct {
+	roms map[string]*databaseROM
+}
+
+// Entradas do programs.json, só com os campos usados aqui
+type databaseProgram struct {
+	Title       string                  `json:"title"`
+	Description string                  `json:"description"`
+	Release     string                  `json:"release"`
+	Authors     []string                `json:"authors"`
+	ROMs        map[string]*databaseROM `json:"roms"`
+}
+
+type databaseROM struct {
+	program         *databaseProgram
+	Description     string                     `json:"description"`
+	Platforms       []string                   `json:"platforms"`
+	QuirkyPlatforms map[string]map[string]bool `json:"quirkyPlatforms"`
+	TickRate        int                        `json:"tickrate"`
+	Keys            map[string]byte            `json:"keys"`
+	Colors          struct {
+		Pixels []string `json:"pixels"`
+	} `json:"colors"`
+}
+
+// Plataformas do chip-8-database que a Machine emula, com o modo e o perfil de quirks de cada uma
+var databasePlatforms = map[string]struct {
+	mode   Mode
+	quirks string
+}{
+	"originalChip8": {Chip8Mode, "vip"},
+	"hybridVIP":     {Chip8Mode, "vip"},
+	"modernChip8":   {Chip8Mode, "modern"},
+	"chip48":        {Chip8Mode, "chip48"},
+	"superchip1":    {SuperChipMode, "schip"},
+	"superchip":     {SuperChipMode, "schip"},
+	"xochip":        {XOChipMode, "xochip"},
+}
+
+// LoadDatabase lê um programs.json do chip-8-database, permitindo usar o banco completo
+func LoadDatabase(r io.Reader) (*Database, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	var programs []*databaseProgram
+	if err := json.Unmarshal(data, &programs); err != nil {
+		return nil, fmt.Errorf("invalid ROM database: %v", err)
+	}
+
+	db := &Database{roms: map[string]*databaseROM{}}
+	for _, program := range programs {
+		for hash, rom := range program.ROMs {
+			rom.program = program
+			db.roms[strings.ToLower(hash)] = rom
+		}
+	}
+	return db, nil
+}
+
+func mustLoadDatabase(data []byte) *Database {
+	db, err := LoadDatabase(bytes.NewReader(data))
+	if err != nil {
+		panic(err)
+	}
+	return db
+}
+
+// Len retorna quantas ROMs o banco conhece
+func (db *Database) Len() int {
+	return len(db.roms)
+}
+
+// Lookup procura uma ROM pelo conteudo. O resultado tem as informações e as configurações
+// dela, ou nil se a ROM não estiver no banco.
+func (db *Database) Lookup(rom []byte) *Program {
+	sum := sha1.Sum(rom)
+	hash := hex.EncodeToString(sum[:])
+	entry, ok := db.roms[hash]
+	if !ok {
+		return nil
+	}
+
+	info := &ROMInfo{
+		SHA1:        hash,
+		Title:       entry.program.Title,
+		Authors:     entry.program.Authors,
+		Release:     entry.program.Release,
+		Description: entry.program.Description,
+		Keys:        entry.Keys,
+	}
+	if entry.Description != "" {
+		info.Description = entry.Description
+	}
+	program := &Program{ROM: rom, Info: info, CyclesPerFrame: entry.TickRate}
+
+	// A primeira plataforma suportada da lista é a preferida
+	for _, name := range entry.Platforms {
+		platform, ok := databasePlatforms[name]
+		if !ok {
+			continue
+		}
+		mode, quirks := platform.mode, QuirksProfiles[platform.quirks]
+		applyDatabaseQuirks(&quirks, entry.QuirkyPlatforms[name])
+		info.Platform, program.Mode, program.Quirks = name, &mode, &quirks
+		break
+	}
+
+	if len(entry.Colors.Pixels) > 0 {
+		palette := DefaultPalette
+		for i, value := range entry.Colors.Pixels {
+			if c, ok := parseHexColor(value); ok && i < len(palette) {
+				palette[i] = c
+			}
+		}
+		program.Palette = &palette
+	}
+	return program
+}
+
+// Quirks do chip-8-database (quirks.json) que mudam o perfil da plataforma
+func applyDatabaseQuirks(quirks *Quirks, overrides map[string]bool) {
+	for name, value := range overrides {
+		switch name {
+		case "shift":
+			quirks.Shift = value
+		case "memoryLeaveIUnchanged":
+			if value {
+				quirks.LoadStore, quirks.LoadStoreX = false, false
+			}
+		case "memoryIncrementByX":
+			quirks.LoadStoreX = value
+			if value {
+				quirks.LoadStore = false
+			}
+		case "wrap":
+			quirks.Clipping = !value
+		case "jump":
+			quirks.Jump = value
+		case "vblank":
+			quirks.DisplayWait = value
+		case "logic":
+			quirks.VFReset = value
+		}
+	}
+}
+
+// Converte "#RRGGBB" ou "#RGB" em uma cor
+func parseHexColor(value string) (color.RGBA, bool) {
+	hexValue := strings.TrimPrefix(value, "#")
+	if len(hexValue) == 3 {
+		hexValue = string([]byte{hexValue[0], hexValue[0], hexValue[1], hexValue[1], hexValue[2], hexValue[2]})
+	}
+	if len(hexValue) != 6 {
+		return color.RGBA{}, false
+	}
+	rgb, err := strconv.ParseUint(hexValue, 16, 32)
+	if err != nil {
+		return color.RGBA{}, false
+	}
+	return color.RGBA{byte(rgb >> 16), byte(rgb >> 8), byte(rgb), 0xFF}, true
+}
+
+// Completa program com o que o banco sabe sobre a ROM, sem trocar o que o formato já definiu
+func (db *Database) fill(program *Program) {
+	if db == nil {
+		return
+	}
+	known := db.Lookup(program.ROM)
+	if known == nil {
+		return
+	}
+	if program.Info == nil {
+		program.Info = known.Info
+	}
+	if program.Mode == nil {
+		program.Mode = known.Mode
+	}
+	if program.Quirks == nil {
+		program.Quirks = known.Quirks
+	}
+	if program.Palette == nil {
+		program.Palette = known.Palette
+	}
+	if program.CyclesPerFrame == 0 {
+		program.CyclesPerFrame = known.CyclesPerFrame
+	}
+}
+
+// Info retorna o que o banco de dados sabe sobre a ROM carregada, nil se ela for desconhecida
+func (chip_8 *Machine) Info() *ROMInfo {
+	return chip_8.info
+}
@@ -0,0 +1,123 @@
+package Chip8
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"image/color"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Monta um banco de dados com uma entrada para cada ROM, na plataforma dada
+func testDatabase(t *testing.T, roms map[string][]byte) *Database {
+	t.Helper()
+	var programs []string
+	for platform, rom := range roms {
+		programs = append(programs, fmt.Sprintf(`{
+			"title": "Test %[1]s",
+			"authors": ["Someone"],
+			"roms": {"%[2]X": {
+				"platforms": ["unknownPlatform", "%[1]s"],
+				"quirkyPlatforms": {"%[1]s": {"shift": false, "vblank": true}},
+				"tickrate": 20,
+				"keys": {"up": 5},
+				"colors": {"pixels": ["#111", "#222222"]}
+			}}
+		}`, platform, sha1.Sum(rom)))
+	}
+	db, err := LoadDatabase(strings.NewReader("[" + strings.Join(programs, ",") + "]"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// Escreve a ROM em um arquivo temporario e retorna o caminho
+func writeROM(t *testing.T, rom []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "game.ch8")
+	if err := ioutil.WriteFile(path, rom, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestDatabaseLookup(t *testing.T) {
+	rom := []byte{0x12, 0x00}
+	db := testDatabase(t, map[string][]byte{"superchip": rom})
+	if db.Lookup([]byte{0x00, 0xE0}) != nil {
+		t.Errorf("Lookup found an unknown ROM")
+	}
+	program := db.Lookup(rom)
+	if program == nil {
+		t.Fatal("Lookup did not find the ROM")
+	}
+	if program.Info.Title != "Test superchip" || program.Info.Platform != "superchip" || program.Info.Keys["up"] != 5 {
+		t.Errorf("info = %+v", program.Info)
+	}
+	// O perfil schip com o shift desligado e o vblank ligado pelo quirkyPlatforms
+	if want := (Quirks{Jump: true, Clipping: true, DisplayWait: true}); program.Mode == nil || *program.Mode != SuperChipMode || program.Quirks == nil || *program.Quirks != want {
+		t.Errorf("mode = %v, quirks = %+v, want %s, %+v", program.Mode, program.Quirks, SuperChipMode, want)
+	}
+	if program.CyclesPerFrame != 20 {
+		t.Errorf("cycles per frame = %d, want 20", program.CyclesPerFrame)
+	}
+	if program.Palette == nil || program.Palette[0] != (color.RGBA{0x11, 0x11, 0x11, 0xFF}) || program.Palette[1] != (color.RGBA{0x22, 0x22, 0x22, 0xFF}) || program.Palette[2] != DefaultPalette[2] {
+		t.Errorf("palette = %v", program.Palette)
+	}
+}
+
+func TestDatabaseFill(t *testing.T) {
+	rom := []byte{0x12, 0x00}
+	db := testDatabase(t, map[string][]byte{"superchip": rom})
+
+	// O que o formato já definiu vale mais que o banco de dados
+	mode, quirks := XOChipMode, Quirks{Shift: true}
+	program := &Program{ROM: rom, Mode: &mode, Quirks: &quirks, CyclesPerFrame: 50}
+	db.fill(program)
+	if *program.Mode != XOChipMode || *program.Quirks != quirks || program.CyclesPerFrame != 50 {
+		t.Errorf("fill replaced the program settings: %+v", program)
+	}
+	if program.Info == nil || program.Palette == nil {
+		t.Errorf("fill did not complete the missing settings: %+v", program)
+	}
+
+	program = &Program{ROM: []byte{0x00, 0xE0}}
+	db.fill(program)
+	if program.Info != nil || program.Mode != nil || program.Quirks != nil || program.Palette != nil || program.CyclesPerFrame != 0 {
+		t.Errorf("fill changed an unknown ROM: %+v", program)
+	}
+	(*Database)(nil).fill(program)
+}
+
+func TestLoadROMSettings(t *testing.T) {
+	rom := []byte{0x12, 0x00}
+	chip_8 := New(WithDatabase(testDatabase(t, map[string][]byte{"superchip": rom})))
+	if err := chip_8.LoadROM(writeROM(t, rom)); err != nil {
+		t.Fatal(err)
+	}
+	if chip_8.Mode() != SuperChipMode || !chip_8.Quirks().DisplayWait || chip_8.CyclesPerFrame() != 20 {
+		t.Errorf("mode = %s, quirks = %s, cycles per frame = %d", chip_8.Mode(), chip_8.Quirks(), chip_8.CyclesPerFrame())
+	}
+	if chip_8.Palette()[1] != (color.RGBA{0x22, 0x22, 0x22, 0xFF}) {
+		t.Errorf("palette = %v", chip_8.Palette())
+	}
+	if info := chip_8.Info(); info == nil || info.Title != "Test superchip" {
+		t.Errorf("info = %+v", info)
+	}
+}
+
+func TestLoadROMTooLarge(t *testing.T) {
+	// Uma ROM grande demais para a plataforma do banco não muda nenhuma configuração
+	rom := make([]byte, 8192)
+	chip_8 := New(WithMode(XOChipMode), WithDatabase(testDatabase(t, map[string][]byte{"originalChip8": rom})))
+	quirks, palette, cycles := chip_8.Quirks(), chip_8.Palette(), chip_8.CyclesPerFrame()
+	if _, ok := chip_8.LoadROM(writeROM(t, rom)).(ErrROMTooLarge); !ok {
+		t.Fatalf("LoadROM of an 8KB CHIP-8 ROM did not fail with ErrROMTooLarge")
+	}
+	if chip_8.Mode() != XOChipMode || chip_8.Quirks() != quirks || chip_8.Palette() != palette || chip_8.CyclesPerFrame() != cycles {
+		t.Errorf("the failed LoadROM changed the settings: mode %s, quirks %s, cycles per frame %d", chip_8.Mode(), chip_8.Quirks(), chip_8.CyclesPerFrame())
+	}
+}
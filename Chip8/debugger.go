@@ -0,0 +1,711 @@
+package Chip8
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Debugger pausa, executa passo a passo e para a Machine em breakpoints, watchpoints e catchpoints.
+// Todos os métodos podem ser chamados de outra goroutine enquanto o Run estiver executando.
+type Debugger struct {
+	machine     *Machine
+	paused      bool
+	resumed     bool // Ignora o breakpoint do PC atual na primeira instrução depois de continuar
+	breakpoints map[int]*Breakpoint
+	nextID      int
+	until       func() bool // Condição de parada do StepOver/StepOut
+	pending     *StopEvent  // Watchpoint ou catchpoint atingido pela instrução atual
+	stops       chan StopEvent
+}
+
+// BreakpointKind diz o que faz um breakpoint parar a Machine
+type BreakpointKind int
+
+const (
+	BreakExec      BreakpointKind = iota // Antes de executar a instrução no endereço Addr
+	BreakRead                            // Quando uma instrução lê a memoria entre Addr e End
+	BreakWrite                           // Quando uma instrução escreve na memoria entre Addr e End
+	BreakAccess                          // Quando uma instrução lê ou escreve na memoria entre Addr e End
+	BreakCollision                       // Quando o DXYN seta o VF por uma colisão
+	BreakClear                           // Quando o 00E0 limpa a tela
+)
+
+func (kind BreakpointKind) String() string {
+	switch kind {
+	case BreakExec:
+		return "exec"
+	case BreakRead:
+		return "read"
+	case BreakWrite:
+		return "write"
+	case BreakAccess:
+		return "access"
+	case BreakCollision:
+		return "collision"
+	case BreakClear:
+		return "clear"
+	}
+	return fmt.Sprintf("BreakpointKind(%d)", int(kind))
+}
+
+// Breakpoint para a Machine antes de executar a instrução no endereço Addr.
+// Com Addr < 0 o breakpoint vale para qualquer endereço, e só a condição é verificada.
+// Watchpoints (BreakRead, BreakWrite, BreakAccess) param depois da instrução que acessou a memoria entre Addr e End,
+// e catchpoints (BreakCollision, BreakClear) depois da instrução que causou o evento.
+type Breakpoint struct {
+	ID        int
+	Kind      BreakpointKind
+	Addr      int
+	End       int
+	Condition *Condition // Opcional, ex: "V3 == 5"
+}
+
+// Condition compara um registrador com um valor, ex: "V3 == 5", "I >= 0x300", "DT != 0"
+type Condition struct {
+	Register string // V0 - VF, I, PC, SP, DT ou ST
+	Op       string // ==, !=, <, <=, > ou >=
+	Value    int
+	text     string
+}
+
+// StopReason explica porque o debugger parou a Machine
+type StopReason int
+
+const (
+	StopPause StopReason = iota
+	StopStep
+	StopBreakpoint
+	StopWatchpoint
+	StopCatchpoint
+	StopFault // Uma instrução falhou com o FaultHalt, o erro está no StopEvent.Err
+)
+
+func (reason StopReason) String() string {
+	switch reason {
+	case StopPause:
+		return "pause"
+	case StopStep:
+		return "step"
+	case StopBreakpoint:
+		return "breakpoint"
+	case StopWatchpoint:
+		return "watchpoint"
+	case StopCatchpoint:
+		return "catchpoint"
+	case StopFault:
+		return "fault"
+	}
+	return fmt.Sprintf("StopReason(%d)", int(reason))
+}
+
+// StopEvent é enviado pelo Stops sempre que a Machine para
+type StopEvent struct {
+	Reason        StopReason
+	PC            uint16
+	Breakpoint    *Breakpoint // Preenchido quando a Machine parou por um breakpoint, watchpoint ou catchpoint
+	InstructionPC uint16      // Endereço da instrução que acessou a memoria ou causou o evento
+	Addr          uint16      // Endereço de memoria acessado (watchpoints)
+	Write         bool        // O acesso foi uma escrita (watchpoints)
+	Err           error       // Falha da instrução (StopFault)
+}
+
+var (
+	ErrNotPaused       = errors.New("machine is not paused")
+	ErrNotInSubroutine = errors.New("not inside a subroutine")
+)
+
+// NewDebugger conecta um debugger na Machine. A Machine começa rodando normalmente.
+func NewDebugger(chip_8 *Machine) *Debugger {
+	d := &Debugger{
+		machine:     chip_8,
+		breakpoints: map[int]*Breakpoint{},
+		nextID:      1,
+		stops:       make(chan StopEvent, 16),
+	}
+	chip_8.mu.Lock()
+	chip_8.debugger = d
+	chip_8.mu.Unlock()
+	return d
+}
+
+// Debugger retorna o debugger conectado na Machine, ou nil
+func (chip_8 *Machine) Debugger() *Debugger {
+	return chip_8.debugger
+}
+
+// Stops recebe um evento sempre que a Machine para (pause, fim de um step ou breakpoint)
+func (d *Debugger) Stops() <-chan StopEvent {
+	return d.stops
+}
+
+// Paused indica se a Machine está parada
+func (d *Debugger) Paused() bool {
+	d.machine.mu.Lock()
+	defer d.machine.mu.Unlock()
+	return d.paused
+}
+
+// Pause para a Machine antes da proxima instrução
+func (d *Debugger) Pause() {
+	d.machine.mu.Lock()
+	defer d.machine.mu.Unlock()
+	d.until = nil
+	d.stop(StopPause, nil)
+}
+
+// Continue volta a executar a Machine até o proximo breakpoint
+func (d *Debugger) Continue() {
+	d.machine.mu.Lock()
+	defer d.machine.mu.Unlock()
+	d.until = nil
+	d.resume()
+}
+
+// Step executa uma única instrução com a Machine parada
+func (d *Debugger) Step() error {
+	d.machine.mu.Lock()
+	defer d.machine.mu.Unlock()
+	if !d.paused {
+		return ErrNotPaused
+	}
+	if err := d.machine.Step(); err != nil {
+		d.fault(err)
+		return nil
+	}
+	d.stopAfterStep()
+	return nil
+}
+
+// StepOver executa a instrução atual, e se ela for um 2NNN (call) continua até a subrotina retornar
+func (d *Debugger) StepOver() error {
+	d.machine.mu.Lock()
+	defer d.machine.mu.Unlock()
+	if !d.paused {
+		return ErrNotPaused
+	}
+
+	chip_8 := d.machine
+	if chip_8.nextOpcode()&0xF000 != 0x2000 {
+		if err := chip_8.Step(); err != nil {
+			d.fault(err)
+			return nil
+		}
+		d.stopAfterStep()
+		return nil
+	}
+
+	target, depth := chip_8.program_counter+2, chip_8.stack_pointer
+	d.until = func() bool {
+		return chip_8.program_counter == target && chip_8.stack_pointer == depth
+	}
+	d.resume()
+	return nil
+}
+
+// StepOut continua até a subrotina atual retornar (00EE)
+func (d *Debugger) StepOut() error {
+	d.machine.mu.Lock()
+	defer d.machine.mu.Unlock()
+	if !d.paused {
+		return ErrNotPaused
+	}
+
+	chip_8 := d.machine
+	depth := chip_8.stack_pointer
+	if depth == 0 {
+		return ErrNotInSubroutine
+	}
+	d.until = func() bool {
+		return chip_8.stack_pointer < depth
+	}
+	d.resume()
+	return nil
+}
+
+// AddBreakpoint adiciona um breakpoint no endereço addr (addr < 0 = qualquer endereço),
+// com uma condição opcional no formato "V3 == 5"
+func (d *Debugger) AddBreakpoint(addr int, condition string) (*Breakpoint, error) {
+	bp := &Breakpoint{Addr: addr}
+	if condition != "" {
+		cond, err := ParseCondition(condition)
+		if err != nil {
+			return nil, err
+		}
+		bp.Condition = cond
+	} else if addr < 0 {
+		return nil, errors.New("a breakpoint without address needs a condition")
+	}
+
+	d.add(bp)
+	return bp, nil
+}
+
+// AddWatchpoint para a Machine quando uma instrução acessar a memoria entre start e end (inclusive).
+// kind deve ser BreakRead, BreakWrite ou BreakAccess.
+func (d *Debugger) AddWatchpoint(start, end int, kind BreakpointKind, condition string) (*Breakpoint, error) {
+	switch kind {
+	case BreakRead, BreakWrite, BreakAccess:
+	default:
+		return nil, fmt.Errorf("%s is not a watchpoint kind", kind)
+	}
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("invalid memory range %X-%X", start, end)
+	}
+
+	bp := &Breakpoint{Kind: kind, Addr: start, End: end}
+	if err := bp.setCondition(condition); err != nil {
+		return nil, err
+	}
+	d.add(bp)
+	return bp, nil
+}
+
+// AddCatchpoint para a Machine depois de uma colisão no DXYN (BreakCollision) ou do 00E0 (BreakClear)
+func (d *Debugger) AddCatchpoint(kind BreakpointKind, condition string) (*Breakpoint, error) {
+	if kind != BreakCollision && kind != BreakClear {
+		return nil, fmt.Errorf("%s is not a catchpoint kind", kind)
+	}
+
+	bp := &Breakpoint{Kind: kind, Addr: -1}
+	if err := bp.setCondition(condition); err != nil {
+		return nil, err
+	}
+	d.add(bp)
+	return bp, nil
+}
+
+func (bp *Breakpoint) setCondition(condition string) error {
+	if condition == "" {
+		return nil
+	}
+	cond, err := ParseCondition(condition)
+	if err != nil {
+		return err
+	}
+	bp.Condition = cond
+	return nil
+}
+
+func (d *Debugger) add(bp *Breakpoint) {
+	d.machine.mu.Lock()
+	defer d.machine.mu.Unlock()
+	bp.ID = d.nextID
+	d.nextID++
+	d.breakpoints[bp.ID] = bp
+}
+
+// RemoveBreakpoint remove o breakpoint pelo ID
+func (d *Debugger) RemoveBreakpoint(id int) error {
+	d.machine.mu.Lock()
+	defer d.machine.mu.Unlock()
+	if _, ok := d.breakpoints[id]; !ok {
+		return fmt.Errorf("no breakpoint with id %d", id)
+	}
+	delete(d.breakpoints, id)
+	return nil
+}
+
+// Breakpoints retorna os breakpoints ordenados pelo ID
+func (d *Debugger) Breakpoints() []*Breakpoint {
+	d.machine.mu.Lock()
+	defer d.machine.mu.Unlock()
+	return d.sortedBreakpoints()
+}
+
+// DebugState é uma foto dos registradores da Machine
+type DebugState struct {
+	Opcode         uint16
+	Registers      [16]byte
+	Index          uint16
+	ProgramCounter uint16
+	Stack          [16]uint16
+	StackPointer   uint16
+	DelayTimer     byte
+	SoundTimer     byte
+}
+
+// State retorna os registradores atuais
+func (d *Debugger) State() DebugState {
+	d.machine.mu.Lock()
+	defer d.machine.mu.Unlock()
+	chip_8 := d.machine
+	return DebugState{
+		Opcode:         chip_8.opcode,
+		Registers:      chip_8.Vx,
+		Index:          chip_8.index,
+		ProgramCounter: chip_8.program_counter,
+		Stack:          chip_8.stack,
+		StackPointer:   chip_8.stack_pointer,
+		DelayTimer:     chip_8.DelayTimer,
+		SoundTimer:     chip_8.SoundTimer,
+	}
+}
+
+// SetRegister troca o valor de um registrador pelo nome (V0 - VF, I, PC, SP, DT ou ST)
+func (d *Debugger) SetRegister(name string, value int) error {
+	d.machine.mu.Lock()
+	defer d.machine.mu.Unlock()
+	chip_8 := d.machine
+
+	name = strings.ToUpper(name)
+	if len(name) == 2 && name[0] == 'V' {
+		reg, err := strconv.ParseUint(name[1:], 16, 8)
+		if err != nil {
+			return fmt.Errorf("unknown register %q", name)
+		}
+		chip_8.Vx[reg] = byte(value)
+		return nil
+	}
+	switch name {
+	case "I":
+		chip_8.index = uint16(value)
+	case "PC":
+		chip_8.program_counter = uint16(value)
+	case "SP":
+		chip_8.stack_pointer = uint16(value)
+	case "DT":
+		chip_8.DelayTimer = byte(value)
+	case "ST":
+		chip_8.SoundTimer = byte(value)
+	default:
+		return fmt.Errorf("unknown register %q", name)
+	}
+	return nil
+}
+
+// ReadMemory lê n bytes da memoria a partir de addr, sem disparar watchpoints.
+// A leitura é cortada no fim da memoria da plataforma.
+func (d *Debugger) ReadMemory(addr, n int) []byte {
+	d.machine.mu.Lock()
+	defer d.machine.mu.Unlock()
+	size := d.machine.mode.MemorySize()
+	if addr < 0 || addr >= size {
+		return nil
+	}
+	if addr+n > size {
+		n = size - addr
+	}
+	return append([]byte(nil), d.machine.memory[addr:addr+n]...)
+}
+
+// WriteMemory escreve data na memoria a partir de addr, sem disparar watchpoints
+func (d *Debugger) WriteMemory(addr int, data []byte) error {
+	d.machine.mu.Lock()
+	defer d.machine.mu.Unlock()
+	if addr < 0 || addr+len(data) > d.machine.mode.MemorySize() {
+		return fmt.Errorf("memory range %X-%X is out of bounds", addr, addr+len(data)-1)
+	}
+	copy(d.machine.memory[addr:], data)
+	return nil
+}
+
+// View escreve os registradores, a pilha e a disassembly das instruções em volta do program counter
+func (d *Debugger) View(w io.Writer, around int) {
+	state := d.State()
+	d.machine.mu.Lock()
+	memory := d.machine.Memory()
+	d.machine.mu.Unlock()
+
+	fmt.Fprintf(w, "PC: %03X  I: %03X  SP: %d  DT: %d  ST: %d\n",
+		state.ProgramCounter, state.Index, state.StackPointer, state.DelayTimer, state.SoundTimer)
+	for i, v := range state.Registers {
+		fmt.Fprintf(w, "V%X: %02X", i, v)
+		if i%8 == 7 {
+			fmt.Fprintln(w)
+		} else {
+			fmt.Fprint(w, "  ")
+		}
+	}
+	fmt.Fprint(w, "Stack:")
+	for i := uint16(1); i <= state.StackPointer && i < 16; i++ {
+		fmt.Fprintf(w, " %03X", state.Stack[i])
+	}
+	fmt.Fprintln(w)
+
+	breakpoints := map[int]bool{}
+	for _, bp := range d.Breakpoints() {
+		if bp.Kind == BreakExec {
+			breakpoints[bp.Addr] = true
+		}
+	}
+
+	start := int(state.ProgramCounter) - 2*around
+	if start < 0 {
+		start = 0
+	}
+	for addr := start; addr <= int(state.ProgramCounter)+2*around && addr+1 < len(memory); addr += 2 {
+		marker := "  "
+		if breakpoints[addr] {
+			marker = "* "
+		}
+		if addr == int(state.ProgramCounter) {
+			marker = marker[:1] + ">"
+		}
+		word := uint16(memory[addr])<<8 | uint16(memory[addr+1])
+		fmt.Fprintf(w, "%s%03X: %04X  %s\n", marker, addr, word, Mnemonic(word))
+	}
+}
+
+// Chamado pelo Run antes de cada instrução, retorna false se a Machine deve ficar parada
+func (d *Debugger) beforeStep() bool {
+	if d == nil {
+		return true
+	}
+	if d.paused {
+		return false
+	}
+	if d.resumed {
+		d.resumed = false
+		return true
+	}
+	if bp := d.breakpointHit(); bp != nil {
+		d.until = nil
+		d.stop(StopBreakpoint, bp)
+		return false
+	}
+	return true
+}
+
+// Chamado pelo Run depois de cada instrução, para o StepOver/StepOut e para os watchpoints
+func (d *Debugger) afterStep() {
+	if d == nil {
+		return
+	}
+	if d.pending != nil {
+		d.until = nil
+		d.stopAfterStep()
+		return
+	}
+	if d.until != nil && d.until() {
+		d.until = nil
+		d.stop(StopStep, nil)
+	}
+}
+
+// Para depois de uma instrução, informando o watchpoint ou catchpoint atingido por ela, se houver
+func (d *Debugger) stopAfterStep() {
+	event := d.pending
+	d.pending = nil
+	if event == nil {
+		d.stop(StopStep, nil)
+		return
+	}
+	d.paused = true
+	d.resumed = false
+	event.PC = d.machine.program_counter
+	d.send(*event)
+}
+
+// Para a Machine na instrução que falhou, chamado pelo Run e pelos steps
+func (d *Debugger) fault(err error) {
+	if d == nil {
+		return
+	}
+	d.until = nil
+	d.pending = nil
+	d.paused = true
+	d.resumed = false
+	pc := d.machine.program_counter
+	d.send(StopEvent{Reason: StopFault, PC: pc, InstructionPC: pc, Err: err})
+}
+
+// Chamado pelas instruções que acessam a memoria
+func (d *Debugger) memoryAccess(addr uint16, write bool) {
+	if d == nil || d.pending != nil {
+		return
+	}
+	for _, bp := range d.sortedBreakpoints() {
+		switch bp.Kind {
+		case BreakRead:
+			if write {
+				continue
+			}
+		case BreakWrite:
+			if !write {
+				continue
+			}
+		case BreakAccess:
+		default:
+			continue
+		}
+		if int(addr) < bp.Addr || int(addr) > bp.End {
+			continue
+		}
+		if bp.Condition != nil && !bp.Condition.Eval(d.machine) {
+			continue
+		}
+		d.pending = &StopEvent{
+			Reason:        StopWatchpoint,
+			Breakpoint:    bp,
+			InstructionPC: d.machine.program_counter,
+			Addr:          addr,
+			Write:         write,
+		}
+		return
+	}
+}
+
+// Chamado pelo DXYN quando um sprite colide com pixels já ligados
+func (d *Debugger) collision() {
+	d.catch(BreakCollision)
+}
+
+// Chamado pelo 00E0 quando a tela é limpa
+func (d *Debugger) screenCleared() {
+	d.catch(BreakClear)
+}
+
+func (d *Debugger) catch(kind BreakpointKind) {
+	if d == nil || d.pending != nil {
+		return
+	}
+	for _, bp := range d.sortedBreakpoints() {
+		if bp.Kind != kind {
+			continue
+		}
+		if bp.Condition != nil && !bp.Condition.Eval(d.machine) {
+			continue
+		}
+		d.pending = &StopEvent{
+			Reason:        StopCatchpoint,
+			Breakpoint:    bp,
+			InstructionPC: d.machine.program_counter,
+		}
+		return
+	}
+}
+
+// Breakpoints ordenados pelo ID, deve ser chamado com o lock da Machine
+func (d *Debugger) sortedBreakpoints() []*Breakpoint {
+	bps := make([]*Breakpoint, 0, len(d.breakpoints))
+	for _, bp := range d.breakpoints {
+		bps = append(bps, bp)
+	}
+	sort.Slice(bps, func(i, j int) bool { return bps[i].ID < bps[j].ID })
+	return bps
+}
+
+func (d *Debugger) breakpointHit() *Breakpoint {
+	pc := int(d.machine.program_counter)
+	var hit *Breakpoint
+	for _, bp := range d.breakpoints {
+		if bp.Kind != BreakExec {
+			continue
+		}
+		if bp.Addr >= 0 && bp.Addr != pc {
+			continue
+		}
+		if bp.Condition != nil && !bp.Condition.Eval(d.machine) {
+			continue
+		}
+		if hit == nil || bp.ID < hit.ID {
+			hit = bp
+		}
+	}
+	return hit
+}
+
+// Para a Machine e avisa quem estiver escutando o Stops (deve ser chamado com o lock da Machine)
+func (d *Debugger) stop(reason StopReason, bp *Breakpoint) {
+	d.paused = true
+	d.resumed = false
+	d.send(StopEvent{Reason: reason, PC: d.machine.program_counter, Breakpoint: bp, InstructionPC: d.machine.program_counter})
+}
+
+func (d *Debugger) send(event StopEvent) {
+	select {
+	case d.stops <- event:
+	default:
+	}
+}
+
+func (d *Debugger) resume() {
+	d.paused = false
+	d.resumed = true
+}
+
+// ParseCondition converte um texto como "V3 == 5" ou "I >= 0x300" em Condition
+func ParseCondition(text string) (*Condition, error) {
+	fields := strings.Fields(text)
+	if len(fields) != 3 {
+		return nil, fmt.Errorf("invalid condition %q, expected \"<register> <op> <value>\"", text)
+	}
+
+	register := strings.ToUpper(fields[0])
+	if _, ok := registerValue(nil, register); !ok {
+		return nil, fmt.Errorf("unknown register %q", fields[0])
+	}
+	switch fields[1] {
+	case "==", "!=", "<", "<=", ">", ">=":
+	default:
+		return nil, fmt.Errorf("unknown operator %q", fields[1])
+	}
+	value, err := strconv.ParseInt(fields[2], 0, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid value %q", fields[2])
+	}
+
+	return &Condition{Register: register, Op: fields[1], Value: int(value), text: text}, nil
+}
+
+// Eval verifica a condição nos registradores atuais da Machine
+func (cond *Condition) Eval(chip_8 *Machine) bool {
+	v, _ := registerValue(chip_8, cond.Register)
+	switch cond.Op {
+	case "==":
+		return v == cond.Value
+	case "!=":
+		return v != cond.Value
+	case "<":
+		return v < cond.Value
+	case "<=":
+		return v <= cond.Value
+	case ">":
+		return v > cond.Value
+	case ">=":
+		return v >= cond.Value
+	}
+	return false
+}
+
+func (cond *Condition) String() string {
+	return cond.text
+}
+
+// Valor de um registrador pelo nome. Com chip_8 == nil apenas valida o nome.
+func registerValue(chip_8 *Machine, name string) (int, bool) {
+	if len(name) == 2 && name[0] == 'V' {
+		reg, err := strconv.ParseUint(name[1:], 16, 8)
+		if err != nil {
+			return 0, false
+		}
+		if chip_8 == nil {
+			return 0, true
+		}
+		return int(chip_8.Vx[reg]), true
+	}
+	if chip_8 == nil {
+		switch name {
+		case "I", "PC", "SP", "DT", "ST":
+			return 0, true
+		}
+		return 0, false
+	}
+	switch name {
+	case "I":
+		return int(chip_8.index), true
+	case "PC":
+		return int(chip_8.program_counter), true
+	case "SP":
+		return int(chip_8.stack_pointer), true
+	case "DT":
+		return int(chip_8.DelayTimer), true
+	case "ST":
+		return int(chip_8.SoundTimer), true
+	}
+	return 0, false
+}
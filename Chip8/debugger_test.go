@@ -0,0 +1,222 @@
+package Chip8
+
+import "testing"
+
+// Executa quadros até o debugger parar a Machine e retorna o evento
+func nextStop(t *testing.T, chip_8 *Machine, d *Debugger) StopEvent {
+	t.Helper()
+	for i := 0; i < 100; i++ {
+		select {
+		case event := <-d.Stops():
+			return event
+		default:
+		}
+		chip_8.frame()
+	}
+	t.Fatal("the debugger did not stop the machine")
+	return StopEvent{}
+}
+
+// Confere que nenhum evento ficou para trás
+func noStop(t *testing.T, d *Debugger) {
+	t.Helper()
+	select {
+	case event := <-d.Stops():
+		t.Errorf("unexpected stop %+v", event)
+	default:
+	}
+}
+
+func TestBreakpoint(t *testing.T) {
+	// V0 = 0, loop: V0++, jump loop
+	chip_8 := newProgram(t, []uint16{0x6000, 0x7001, 0x1202})
+	d := NewDebugger(chip_8)
+	bp, err := d.AddBreakpoint(0x202, "V0 == 3")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	event := nextStop(t, chip_8, d)
+	if event.Reason != StopBreakpoint || event.Breakpoint != bp || event.PC != 0x202 || chip_8.Vx[0] != 3 {
+		t.Fatalf("stop = %+v with V0 = %d, want the breakpoint at 202 with V0 = 3", event, chip_8.Vx[0])
+	}
+	// Parada, a Machine não anda
+	chip_8.frame()
+	if chip_8.program_counter != 0x202 || !d.Paused() {
+		t.Errorf("the paused machine ran to %03X", chip_8.program_counter)
+	}
+
+	// Sem condição o breakpoint para a cada volta
+	if err := d.RemoveBreakpoint(bp.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.AddBreakpoint(0x202, ""); err != nil {
+		t.Fatal(err)
+	}
+	d.Continue()
+	event = nextStop(t, chip_8, d)
+	if event.Reason != StopBreakpoint || event.PC != 0x202 || chip_8.Vx[0] != 4 {
+		t.Errorf("stop = %+v with V0 = %d, want the breakpoint at 202 with V0 = 4", event, chip_8.Vx[0])
+	}
+	noStop(t, d)
+
+	if _, err := d.AddBreakpoint(-1, ""); err == nil {
+		t.Errorf("a breakpoint without address and condition was accepted")
+	}
+	if err := d.RemoveBreakpoint(99); err == nil {
+		t.Errorf("RemoveBreakpoint of an unknown id did not fail")
+	}
+}
+
+func TestDebuggerStep(t *testing.T) {
+	// call sub, V1 = 2, loop  /  sub: V0 = 1, return
+	chip_8 := newProgram(t, []uint16{0x2206, 0x6102, 0x1204, 0x6001, 0x00EE})
+	d := NewDebugger(chip_8)
+	if err := d.Step(); err != ErrNotPaused {
+		t.Errorf("Step of a running machine = %v, want ErrNotPaused", err)
+	}
+	d.Pause()
+	if event := <-d.Stops(); event.Reason != StopPause || event.PC != 0x200 {
+		t.Errorf("pause = %+v", event)
+	}
+
+	// Step entra na subrotina, StepOut sai dela
+	if err := d.Step(); err != nil {
+		t.Fatal(err)
+	}
+	if event := <-d.Stops(); event.Reason != StopStep || event.PC != 0x206 {
+		t.Errorf("step = %+v, want a stop at 206", event)
+	}
+	if err := d.StepOut(); err != nil {
+		t.Fatal(err)
+	}
+	if event := nextStop(t, chip_8, d); event.Reason != StopStep || event.PC != 0x202 || chip_8.Vx[0] != 1 {
+		t.Errorf("step out = %+v, want a stop at 202 after the subroutine", event)
+	}
+	if err := d.StepOut(); err != ErrNotInSubroutine {
+		t.Errorf("StepOut outside a subroutine = %v, want ErrNotInSubroutine", err)
+	}
+
+	// StepOver passa por cima da chamada inteira
+	if err := d.SetRegister("PC", 0x200); err != nil {
+		t.Fatal(err)
+	}
+	chip_8.Vx[0] = 0
+	if err := d.StepOver(); err != nil {
+		t.Fatal(err)
+	}
+	if event := nextStop(t, chip_8, d); event.Reason != StopStep || event.PC != 0x202 || chip_8.Vx[0] != 1 {
+		t.Errorf("step over = %+v with V0 = %d, want a stop at 202 with V0 = 1", event, chip_8.Vx[0])
+	}
+}
+
+func TestDebuggerFault(t *testing.T) {
+	chip_8 := newProgram(t, []uint16{0x6001, 0xFFFF})
+	d := NewDebugger(chip_8)
+	event := nextStop(t, chip_8, d)
+	if _, ok := event.Err.(ErrUnknownOpcode); event.Reason != StopFault || event.PC != 0x202 || !ok {
+		t.Errorf("stop = %+v, want a fault at 202", event)
+	}
+	if !d.Paused() || chip_8.Err() != nil {
+		t.Errorf("the fault did not just pause the machine (err %v)", chip_8.Err())
+	}
+}
+
+func TestParseCondition(t *testing.T) {
+	chip_8 := newProgram(t, []uint16{0x6305, 0xA300})
+	steps(t, chip_8, 2)
+	for _, test := range []struct {
+		text string
+		want bool
+	}{
+		{"V3 == 5", true},
+		{"v3 != 5", false},
+		{"I >= 0x300", true},
+		{"I < 0x300", false},
+		{"PC > 0x203", true},
+		{"DT <= 0", true},
+	} {
+		cond, err := ParseCondition(test.text)
+		if err != nil {
+			t.Errorf("ParseCondition(%q): %v", test.text, err)
+			continue
+		}
+		if got := cond.Eval(chip_8); got != test.want {
+			t.Errorf("%q = %v, want %v", test.text, got, test.want)
+		}
+	}
+	for _, text := range []string{"", "V3", "VG == 1", "V3 =~ 1", "V3 == x"} {
+		if _, err := ParseCondition(text); err == nil {
+			t.Errorf("ParseCondition(%q) did not fail", text)
+		}
+	}
+}
+
+func TestWatchpoint(t *testing.T) {
+	// I = 300, V0 = 7, BCD de V0 em 300-302, lê 300 em V0, loop
+	chip_8 := newProgram(t, []uint16{0xA300, 0x6007, 0xF033, 0xF065, 0x1208})
+	d := NewDebugger(chip_8)
+	if _, err := d.AddWatchpoint(0x300, 0x302, BreakAccess, "V0 == 9"); err != nil {
+		t.Fatal(err)
+	}
+	read, err := d.AddWatchpoint(0x300, 0x300, BreakRead, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	write, err := d.AddWatchpoint(0x302, 0x310, BreakWrite, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	event := nextStop(t, chip_8, d)
+	if event.Reason != StopWatchpoint || event.Breakpoint != write || event.InstructionPC != 0x204 || event.PC != 0x206 || event.Addr != 0x302 || !event.Write {
+		t.Errorf("stop = %+v, want the write watchpoint on 302 by the FX33 at 204", event)
+	}
+	d.Continue()
+	event = nextStop(t, chip_8, d)
+	if event.Reason != StopWatchpoint || event.Breakpoint != read || event.InstructionPC != 0x206 || event.Addr != 0x300 || event.Write {
+		t.Errorf("stop = %+v, want the read watchpoint on 300 by the FX65 at 206", event)
+	}
+
+	for _, test := range []struct {
+		start, end int
+		kind       BreakpointKind
+	}{
+		{0x300, 0x2FF, BreakRead},
+		{-1, 0x300, BreakWrite},
+		{0x300, 0x300, BreakExec},
+		{0x300, 0x300, BreakClear},
+	} {
+		if _, err := d.AddWatchpoint(test.start, test.end, test.kind, ""); err == nil {
+			t.Errorf("AddWatchpoint(%X, %X, %s) did not fail", test.start, test.end, test.kind)
+		}
+	}
+}
+
+func TestCatchpoint(t *testing.T) {
+	// Desenha o "0" da font duas vezes (colisão), limpa a tela, loop
+	chip_8 := newProgram(t, []uint16{0xA000, 0xD015, 0xD015, 0x00E0, 0x1208})
+	d := NewDebugger(chip_8)
+	collision, err := d.AddCatchpoint(BreakCollision, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cleared, err := d.AddCatchpoint(BreakClear, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	event := nextStop(t, chip_8, d)
+	if event.Reason != StopCatchpoint || event.Breakpoint != collision || event.InstructionPC != 0x204 || event.PC != 0x206 || chip_8.Vx[0xF] != 1 {
+		t.Errorf("stop = %+v, want the collision catchpoint after the DXYN at 204", event)
+	}
+	d.Continue()
+	event = nextStop(t, chip_8, d)
+	if event.Reason != StopCatchpoint || event.Breakpoint != cleared || event.InstructionPC != 0x206 || event.PC != 0x208 {
+		t.Errorf("stop = %+v, want the clear catchpoint after the 00E0 at 206", event)
+	}
+
+	if _, err := d.AddCatchpoint(BreakWrite, ""); err == nil {
+		t.Errorf("AddCatchpoint(BreakWrite) did not fail")
+	}
+}
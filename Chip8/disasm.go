@@ -0,0 +1,250 @@
+package Chip8
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Flow diz para onde a execução segue depois de uma instrução, usado para rastrear o código de uma ROM
+type Flow int
+
+const (
+	FlowNext     Flow = iota // Segue para a proxima instrução
+	FlowJump                 // Pula para Target (1NNN)
+	FlowCall                 // Chama a subrotina em Target e depois segue (2NNN)
+	FlowReturn               // Volta da subrotina (00EE)
+	FlowSkip                 // Pode pular a proxima instrução (3XNN, 4XNN, 5XY0, 9XY0, EX9E, EXA1)
+	FlowIndirect             // Pula para Target + V0, destino desconhecido (BNNN)
+	FlowExit                 // Sai do interpretador (00FD)
+)
+
+// Instruction é uma instrução decodificada
+type Instruction struct {
+	Word        uint16
+	Size        int // Em bytes, 4 no F000 NNNN do XO-CHIP
+	Mnemonic    string
+	Operands    []string
+	Description string
+	Valid       bool // false quando a word não é uma instrução da plataforma (aparece como DW)
+	Flow        Flow
+	Target      uint16 // Destino do JP/CALL, ou endereço carregado em I
+	Data        bool   // Target é um endereço de dados (LD I)
+}
+
+// String retorna a instrução no estilo do "cowgod's chip-8 technical reference", ex: "LD V3, 0x10"
+func (inst Instruction) String() string {
+	if len(inst.Operands) == 0 {
+		return inst.Mnemonic
+	}
+	return inst.Mnemonic + " " + strings.Join(inst.Operands, ", ")
+}
+
+// Mnemonic converte uma instrução no seu mnemonico, aceitando as instruções de todas as plataformas.
+// Instruções desconhecidas aparecem como dados (DW).
+func Mnemonic(word uint16) string {
+	return Disassemble(word, XOChipMode).String()
+}
+
+// Disassemble decodifica uma instrução da plataforma mode. As instruções do SUPER-CHIP só são aceitas
+// no SuperChipMode e no XOChipMode, e as do XO-CHIP só no XOChipMode.
+// O endereço do F000 NNNN fica na word seguinte, use o DisassembleAt para lê-lo.
+func Disassemble(word uint16, mode Mode) Instruction {
+	inst := decode(word, mode)
+	inst.Word = word
+	if inst.Size == 0 {
+		inst.Size = 2
+	}
+	if !inst.Valid {
+		inst.Mnemonic = "DW"
+		inst.Operands = []string{fmt.Sprintf("0x%04X", word)}
+		inst.Description = "Data word"
+	}
+	return inst
+}
+
+// DisassembleAt decodifica a instrução em memory[addr:], incluindo o endereço do F000 NNNN ("LD I, LONG 0x1234")
+func DisassembleAt(memory []byte, addr int, mode Mode) Instruction {
+	if addr+1 >= len(memory) {
+		var word uint16
+		if addr < len(memory) {
+			word = uint16(memory[addr]) << 8
+		}
+		inst := Disassemble(word, mode)
+		inst.Valid = false
+		return inst
+	}
+	inst := Disassemble(uint16(memory[addr])<<8|uint16(memory[addr+1]), mode)
+	if inst.Size == 4 {
+		if addr+3 >= len(memory) {
+			inst.Valid = false
+			return inst
+		}
+		inst.Target = uint16(memory[addr+2])<<8 | uint16(memory[addr+3])
+		inst.Operands = []string{"I", fmt.Sprintf("LONG 0x%04X", inst.Target)}
+		inst.Description = fmt.Sprintf("Set I to 0x%04X", inst.Target)
+	}
+	return inst
+}
+
+func op(mnemonic, description string, operands ...string) Instruction {
+	return Instruction{Mnemonic: mnemonic, Operands: operands, Description: description, Valid: true}
+}
+
+func decode(word uint16, mode Mode) Instruction {
+	x := (word & 0x0F00) >> 8
+	y := (word & 0x00F0) >> 4
+	n := word & 0x000F
+	nn := word & 0x00FF
+	nnn := word & 0x0FFF
+
+	vx, vy := fmt.Sprintf("V%X", x), fmt.Sprintf("V%X", y)
+	byteNN := fmt.Sprintf("0x%02X", nn)
+	addr := fmt.Sprintf("0x%03X", nnn)
+	schip := mode == SuperChipMode || mode == XOChipMode
+	xochip := mode == XOChipMode
+
+	switch word & 0xF000 {
+	case 0x0000:
+		switch {
+		case word == 0x00E0:
+			return op("CLS", "Clear the display")
+		case word == 0x00EE:
+			inst := op("RET", "Return from a subroutine")
+			inst.Flow = FlowReturn
+			return inst
+		case word&0xFFF0 == 0x00C0 && schip:
+			return op("SCD", fmt.Sprintf("Scroll the display down %d pixels", n), fmt.Sprint(n))
+		case word&0xFFF0 == 0x00D0 && xochip:
+			return op("SCU", fmt.Sprintf("Scroll the display up %d pixels", n), fmt.Sprint(n))
+		case word == 0x00FB && schip:
+			return op("SCR", "Scroll the display right 4 pixels")
+		case word == 0x00FC && schip:
+			return op("SCL", "Scroll the display left 4 pixels")
+		case word == 0x00FD && schip:
+			inst := op("EXIT", "Exit the interpreter")
+			inst.Flow = FlowExit
+			return inst
+		case word == 0x00FE && schip:
+			return op("LOW", "Switch to low resolution (64x32)")
+		case word == 0x00FF && schip:
+			return op("HIGH", "Switch to high resolution (128x64)")
+		}
+		return op("SYS", fmt.Sprintf("Call machine code routine at %s (ignored)", addr), addr)
+	case 0x1000:
+		inst := op("JP", fmt.Sprintf("Jump to %s", addr), addr)
+		inst.Flow, inst.Target = FlowJump, nnn
+		return inst
+	case 0x2000:
+		inst := op("CALL", fmt.Sprintf("Call the subroutine at %s", addr), addr)
+		inst.Flow, inst.Target = FlowCall, nnn
+		return inst
+	case 0x3000:
+		return skip(op("SE", fmt.Sprintf("Skip the next instruction if %s == %s", vx, byteNN), vx, byteNN))
+	case 0x4000:
+		return skip(op("SNE", fmt.Sprintf("Skip the next instruction if %s != %s", vx, byteNN), vx, byteNN))
+	case 0x5000:
+		switch {
+		case n == 0x0:
+			return skip(op("SE", fmt.Sprintf("Skip the next instruction if %s == %s", vx, vy), vx, vy))
+		case n == 0x2 && xochip:
+			return op("SAVE", fmt.Sprintf("Store %s through %s in memory starting at I", vx, vy), vx+" - "+vy)
+		case n == 0x3 && xochip:
+			return op("LOAD", fmt.Sprintf("Read %s through %s from memory starting at I", vx, vy), vx+" - "+vy)
+		}
+	case 0x6000:
+		return op("LD", fmt.Sprintf("Set %s to %s", vx, byteNN), vx, byteNN)
+	case 0x7000:
+		return op("ADD", fmt.Sprintf("Add %s to %s (no carry)", byteNN, vx), vx, byteNN)
+	case 0x8000:
+		switch n {
+		case 0x0:
+			return op("LD", fmt.Sprintf("Set %s to %s", vx, vy), vx, vy)
+		case 0x1:
+			return op("OR", fmt.Sprintf("Set %s to %s OR %s", vx, vx, vy), vx, vy)
+		case 0x2:
+			return op("AND", fmt.Sprintf("Set %s to %s AND %s", vx, vx, vy), vx, vy)
+		case 0x3:
+			return op("XOR", fmt.Sprintf("Set %s to %s XOR %s", vx, vx, vy), vx, vy)
+		case 0x4:
+			return op("ADD", fmt.Sprintf("Add %s to %s, VF = carry", vy, vx), vx, vy)
+		case 0x5:
+			return op("SUB", fmt.Sprintf("Set %s to %s - %s, VF = not borrow", vx, vx, vy), vx, vy)
+		case 0x6:
+			return op("SHR", fmt.Sprintf("Shift %s right by one, VF = shifted out bit", vx), vx, vy)
+		case 0x7:
+			return op("SUBN", fmt.Sprintf("Set %s to %s - %s, VF = not borrow", vx, vy, vx), vx, vy)
+		case 0xE:
+			return op("SHL", fmt.Sprintf("Shift %s left by one, VF = shifted out bit", vx), vx, vy)
+		}
+	case 0x9000:
+		if n == 0 {
+			return skip(op("SNE", fmt.Sprintf("Skip the next instruction if %s != %s", vx, vy), vx, vy))
+		}
+	case 0xA000:
+		inst := op("LD", fmt.Sprintf("Set I to %s", addr), "I", addr)
+		inst.Target, inst.Data = nnn, true
+		return inst
+	case 0xB000:
+		inst := op("JP", fmt.Sprintf("Jump to %s + V0", addr), "V0", addr)
+		inst.Flow, inst.Target = FlowIndirect, nnn
+		return inst
+	case 0xC000:
+		return op("RND", fmt.Sprintf("Set %s to a random byte AND %s", vx, byteNN), vx, byteNN)
+	case 0xD000:
+		size := fmt.Sprintf("8x%d", n)
+		if n == 0 && schip {
+			size = "16x16"
+		}
+		return op("DRW", fmt.Sprintf("Draw a %s sprite from I at (%s, %s), VF = collision", size, vx, vy), vx, vy, fmt.Sprint(n))
+	case 0xE000:
+		switch nn {
+		case 0x9E:
+			return skip(op("SKP", fmt.Sprintf("Skip the next instruction if the key in %s is pressed", vx), vx))
+		case 0xA1:
+			return skip(op("SKNP", fmt.Sprintf("Skip the next instruction if the key in %s is not pressed", vx), vx))
+		}
+	case 0xF000:
+		switch {
+		case word == 0xF000 && xochip:
+			inst := op("LD", "Set I to the address in the next word", "I", "LONG")
+			inst.Size, inst.Data = 4, true
+			return inst
+		case nn == 0x01 && xochip:
+			return op("PLANE", fmt.Sprintf("Select drawing planes %d", x), fmt.Sprint(x))
+		case word == 0xF002 && xochip:
+			return op("AUDIO", "Load the 16-byte audio pattern from I")
+		case nn == 0x07:
+			return op("LD", fmt.Sprintf("Set %s to the delay timer", vx), vx, "DT")
+		case nn == 0x0A:
+			return op("LD", fmt.Sprintf("Wait for a key press and store it in %s", vx), vx, "K")
+		case nn == 0x15:
+			return op("LD", fmt.Sprintf("Set the delay timer to %s", vx), "DT", vx)
+		case nn == 0x18:
+			return op("LD", fmt.Sprintf("Set the sound timer to %s", vx), "ST", vx)
+		case nn == 0x1E:
+			return op("ADD", fmt.Sprintf("Add %s to I", vx), "I", vx)
+		case nn == 0x29:
+			return op("LD", fmt.Sprintf("Set I to the font sprite of the digit in %s", vx), "F", vx)
+		case nn == 0x30 && schip:
+			return op("LD", fmt.Sprintf("Set I to the big font sprite of the digit in %s", vx), "HF", vx)
+		case nn == 0x33:
+			return op("LD", fmt.Sprintf("Store the BCD of %s at I, I+1 and I+2", vx), "B", vx)
+		case nn == 0x3A && xochip:
+			return op("PITCH", fmt.Sprintf("Set the audio pitch to %s", vx), vx)
+		case nn == 0x55:
+			return op("LD", fmt.Sprintf("Store V0 through %s in memory starting at I", vx), "[I]", vx)
+		case nn == 0x65:
+			return op("LD", fmt.Sprintf("Read V0 through %s from memory starting at I", vx), vx, "[I]")
+		case nn == 0x75 && schip:
+			return op("LD", fmt.Sprintf("Store V0 through %s in the RPL flags", vx), "R", vx)
+		case nn == 0x85 && schip:
+			return op("LD", fmt.Sprintf("Read V0 through %s from the RPL flags", vx), vx, "R")
+		}
+	}
+	return Instruction{}
+}
+
+func skip(inst Instruction) Instruction {
+	inst.Flow = FlowSkip
+	return inst
+}
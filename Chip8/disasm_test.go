@@ -0,0 +1,77 @@
+package Chip8
+
+import "testing"
+
+func TestDisassemble(t *testing.T) {
+	for _, test := range []struct {
+		word uint16
+		mode Mode
+		want string
+	}{
+		{0x00E0, Chip8Mode, "CLS"},
+		{0x00EE, Chip8Mode, "RET"},
+		{0x00C4, Chip8Mode, "SYS 0x0C4"},
+		{0x00C4, SuperChipMode, "SCD 4"},
+		{0x00D4, SuperChipMode, "SYS 0x0D4"},
+		{0x00D4, XOChipMode, "SCU 4"},
+		{0x1234, Chip8Mode, "JP 0x234"},
+		{0x3A10, Chip8Mode, "SE VA, 0x10"},
+		{0x5120, Chip8Mode, "SE V1, V2"},
+		{0x5122, Chip8Mode, "DW 0x5122"},
+		{0x5122, XOChipMode, "SAVE V1 - V2"},
+		{0x5123, XOChipMode, "LOAD V1 - V2"},
+		{0x8AB6, Chip8Mode, "SHR VA, VB"},
+		{0xB300, Chip8Mode, "JP V0, 0x300"},
+		{0xD125, Chip8Mode, "DRW V1, V2, 5"},
+		{0xE39E, Chip8Mode, "SKP V3"},
+		{0xF30A, Chip8Mode, "LD V3, K"},
+		{0xF355, Chip8Mode, "LD [I], V3"},
+		{0xF330, Chip8Mode, "DW 0xF330"},
+		{0xF330, SuperChipMode, "LD HF, V3"},
+		{0xF201, XOChipMode, "PLANE 2"},
+		{0xFFFF, XOChipMode, "DW 0xFFFF"},
+	} {
+		if got := Disassemble(test.word, test.mode).String(); got != test.want {
+			t.Errorf("Disassemble(%04X, %s) = %q, want %q", test.word, test.mode, got, test.want)
+		}
+	}
+}
+
+func TestDisassembleFlow(t *testing.T) {
+	for _, test := range []struct {
+		word   uint16
+		flow   Flow
+		target uint16
+	}{
+		{0x1234, FlowJump, 0x234},
+		{0x2456, FlowCall, 0x456},
+		{0x00EE, FlowReturn, 0},
+		{0x4A00, FlowSkip, 0},
+		{0xE1A1, FlowSkip, 0},
+		{0xB300, FlowIndirect, 0x300},
+		{0x00FD, FlowExit, 0},
+		{0x6005, FlowNext, 0},
+	} {
+		inst := Disassemble(test.word, XOChipMode)
+		if inst.Flow != test.flow || inst.Target != test.target {
+			t.Errorf("Disassemble(%04X) flow = %d, target = %03X, want %d, %03X", test.word, inst.Flow, inst.Target, test.flow, test.target)
+		}
+	}
+	if inst := Disassemble(0xA321, Chip8Mode); !inst.Data || inst.Target != 0x321 {
+		t.Errorf("LD I, 0x321: Data = %v, Target = %03X", inst.Data, inst.Target)
+	}
+}
+
+func TestDisassembleAt(t *testing.T) {
+	memory := []byte{0xF0, 0x00, 0x12, 0x34, 0x60}
+	inst := DisassembleAt(memory, 0, XOChipMode)
+	if inst.String() != "LD I, LONG 0x1234" || inst.Size != 4 || inst.Target != 0x1234 || !inst.Valid {
+		t.Errorf("DisassembleAt(F000 1234) = %q, size %d, target %04X, valid %v", inst, inst.Size, inst.Target, inst.Valid)
+	}
+	if inst := DisassembleAt(memory[:3], 0, XOChipMode); inst.Valid {
+		t.Errorf("F000 without the address word is valid")
+	}
+	if inst := DisassembleAt(memory, 4, XOChipMode); inst.Valid {
+		t.Errorf("a single byte at the end of memory is valid")
+	}
+}
@@ -0,0 +1,198 @@
+package Chip8
+
+import (
+	"fmt"
+	"image/color"
+	"strings"
+)
+
+// Cores padrão: fundo, primeiro bitplane, segundo bitplane e os dois juntos
+var DefaultPalette = [4]color.RGBA{
+	{0x00, 0x00, 0x00, 0xFF},
+	{0xFF, 0xFF, 0xFF, 0xFF},
+	{0xAA, 0xAA, 0xAA, 0xFF},
+	{0x55, 0x55, 0x55, 0xFF},
+}
+
+// ParsePalette lê uma lista de cores "#RRGGBB" separadas por virgulas: fundo, primeiro bitplane,
+// segundo bitplane e os dois juntos. As cores que faltarem continuam as da DefaultPalette.
+func ParsePalette(spec string) ([4]color.RGBA, error) {
+	palette := DefaultPalette
+	values := strings.Split(spec, ",")
+	if len(values) > len(palette) {
+		return palette, fmt.Errorf("palette has %d colors, the maximum is %d", len(values), len(palette))
+	}
+	for i, value := range values {
+		c, ok := parseHexColor(strings.TrimSpace(value))
+		if !ok {
+			return palette, fmt.Errorf("invalid color %q (expected #RRGGBB)", value)
+		}
+		palette[i] = c
+	}
+	return palette, nil
+}
+
+// Palette retorna as cores usadas para cada combinação de bitplanes
+func (chip_8 *Machine) Palette() [4]color.RGBA {
+	return chip_8.palette
+}
+
+// SetPalette troca as cores usadas para cada combinação de bitplanes
+func (chip_8 *Machine) SetPalette(palette [4]color.RGBA) {
+	chip_8.palette = palette
+	chip_8.drawFlag = true
+}
+
+// Largura da tela atual, 128 no modo de alta resolução do SUPER-CHIP
+func (chip_8 *Machine) width() int {
+	if chip_8.hires {
+		return 128
+	}
+	return 64
+}
+
+// Altura da tela atual, 64 no modo de alta resolução do SUPER-CHIP
+func (chip_8 *Machine) height() int {
+	if chip_8.hires {
+		return 64
+	}
+	return 32
+}
+
+// Troca a resolução da tela e a limpa, já que o tamanho das linhas muda
+func (chip_8 *Machine) setHighResolution(hires bool) {
+	chip_8.hires = hires
+	chip_8.gfx = [128 * 64]byte{}
+	chip_8.drawFlag = true
+}
+
+// Desenha um sprite na posição vx,vy com n linhas, começando no endereço guardado no I(ndex).
+// Com n == 0 o sprite é de 16x16 (SUPER-CHIP), senão é de 8xN; no Chip-8 ele não tem linhas.
+// No XO-CHIP, com os dois bitplanes selecionados, os dados do segundo plano vêm logo depois dos do primeiro.
+// Retorna false, sem desenhar, se o sprite passa do fim da memoria.
+func (chip_8 *Machine) drawSprite(vx, vy byte, n uint16) bool {
+	width, height := chip_8.width(), chip_8.height()
+
+	// A posição inicial sempre "da a volta" na tela
+	x := int(vx) % width
+	y := int(vy) % height
+
+	rows, cols, rowBytes := int(n), 8, 1
+	if n == 0 && chip_8.mode >= SuperChipMode {
+		rows, cols, rowBytes = 16, 16, 2
+	}
+
+	addr := int(chip_8.index)
+	planes := int(chip_8.plane&1 + chip_8.plane>>1&1)
+	if !chip_8.checkMemory(addr, planes*rows*rowBytes) {
+		return false
+	}
+
+	chip_8.Vx[0xF] = 0 // Reseta flag de colisão
+
+	for plane := byte(1); plane <= 2; plane <<= 1 {
+		if chip_8.plane&plane == 0 {
+			continue
+		}
+
+		// A logica do loop se baseia em pegar um determinado numero de linhas (N)
+		// irmos bit por bit dessas linhas e verificar se eles estão ligados (1) ou desligados(0)
+		// se eles tiverem ligados precisamos aplicar uma operação xor, invertendo-os
+		// se ele estiver ligado, e no mesmo lugar da tela já possuem pixels ligados, devemos setar a flag de colisão
+		for yPoint := 0; yPoint < rows; yPoint++ {
+			// Começamos no endereço que está no index, assim como manda a doc.
+			var pix uint16
+			for b := 0; b < rowBytes; b++ {
+				pix = pix<<8 | uint16(chip_8.readMemory(addr+yPoint*rowBytes+b))
+			}
+			for xPoint := 0; xPoint < cols; xPoint++ {
+				px, py := x+xPoint, y+yPoint
+				if px >= width || py >= height {
+					// Com o quirk Clipping o pixel fora da tela é descartado, senão aparece do outro lado
+					if chip_8.quirks.Clipping {
+						continue
+					}
+					px, py = px%width, py%height
+				}
+				ind := px + py*width                   // Posição atual na tela
+				if pix&(1<<uint(cols-1-xPoint)) != 0 { // verifica se cada pixel esta setado
+					if chip_8.gfx[ind]&plane != 0 { // Verifica Pixel Collision
+						chip_8.Vx[0xF] = 1 // Seta Colisão como verdadeira
+					}
+					chip_8.gfx[ind] ^= plane // aplica a operação xor na tela
+				}
+			}
+		}
+		addr += rows * rowBytes
+	}
+	return true
+}
+
+// Move um pixel dos bitplanes selecionados de from para to (from < 0 = pixel vazio)
+func (chip_8 *Machine) movePixel(to, from int) {
+	var p byte
+	if from >= 0 {
+		p = chip_8.gfx[from] & chip_8.plane
+	}
+	chip_8.gfx[to] = chip_8.gfx[to]&^chip_8.plane | p
+}
+
+// Rola os bitplanes selecionados n linhas para baixo, as linhas de cima ficam vazias
+func (chip_8 *Machine) scrollDown(n int) {
+	width, height := chip_8.width(), chip_8.height()
+	for y := height - 1; y >= 0; y-- {
+		for x := 0; x < width; x++ {
+			from := -1
+			if y-n >= 0 {
+				from = (y-n)*width + x
+			}
+			chip_8.movePixel(y*width+x, from)
+		}
+	}
+	chip_8.drawFlag = true
+}
+
+// Rola os bitplanes selecionados n linhas para cima
+func (chip_8 *Machine) scrollUp(n int) {
+	width, height := chip_8.width(), chip_8.height()
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			from := -1
+			if y+n < height {
+				from = (y+n)*width + x
+			}
+			chip_8.movePixel(y*width+x, from)
+		}
+	}
+	chip_8.drawFlag = true
+}
+
+// Rola os bitplanes selecionados n pixels para a direita
+func (chip_8 *Machine) scrollRight(n int) {
+	width, height := chip_8.width(), chip_8.height()
+	for y := 0; y < height; y++ {
+		for x := width - 1; x >= 0; x-- {
+			from := -1
+			if x-n >= 0 {
+				from = y*width + x - n
+			}
+			chip_8.movePixel(y*width+x, from)
+		}
+	}
+	chip_8.drawFlag = true
+}
+
+// Rola os bitplanes selecionados n pixels para a esquerda
+func (chip_8 *Machine) scrollLeft(n int) {
+	width, height := chip_8.width(), chip_8.height()
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			from := -1
+			if x+n < width {
+				from = y*width + x + n
+			}
+			chip_8.movePixel(y*width+x, from)
+		}
+	}
+	chip_8.drawFlag = true
+}
@@ -0,0 +1,143 @@
+package Chip8
+
+import (
+	"fmt"
+	"io"
+)
+
+// ErrROMTooLarge é retornado pelo LoadBytes/LoadROM quando a ROM não cabe na memoria da plataforma
+type ErrROMTooLarge struct {
+	Size int  // Tamanho da ROM
+	Max  int  // Maior ROM que cabe depois de 0x200
+	Mode Mode // Plataforma
+}
+
+func (e ErrROMTooLarge) Error() string {
+	return fmt.Sprintf("ROM too large: %d bytes, the maximum for %s is %d", e.Size, e.Mode, e.Max)
+}
+
+// ErrUnknownOpcode é uma instrução que a plataforma não conhece
+type ErrUnknownOpcode struct {
+	Addr uint16 // Endereço da instrução
+	Op   uint16
+}
+
+func (e ErrUnknownOpcode) Error() string {
+	return fmt.Sprintf("unknown opcode %04X at %03X", e.Op, e.Addr)
+}
+
+// ErrStackOverflow é um 2NNN com a pilha cheia
+type ErrStackOverflow struct {
+	Addr uint16 // Endereço da instrução
+	Op   uint16
+}
+
+func (e ErrStackOverflow) Error() string {
+	return fmt.Sprintf("stack overflow: %04X at %03X calls a subroutine with the stack full", e.Op, e.Addr)
+}
+
+// ErrStackUnderflow é um 00EE fora de uma subrotina
+type ErrStackUnderflow struct {
+	Addr uint16 // Endereço da instrução
+	Op   uint16
+}
+
+func (e ErrStackUnderflow) Error() string {
+	return fmt.Sprintf("stack underflow: %04X at %03X returns with an empty stack", e.Op, e.Addr)
+}
+
+// ErrMemoryOutOfBounds é um acesso fora da memoria da plataforma.
+// Quando a própria instrução está fora, Target == Addr e Op é 0.
+type ErrMemoryOutOfBounds struct {
+	Addr   uint16 // Endereço da instrução
+	Op     uint16
+	Target int // Endereço acessado
+	Size   int // Tamanho da memoria
+}
+
+func (e ErrMemoryOutOfBounds) Error() string {
+	if e.Target == int(e.Addr) {
+		return fmt.Sprintf("memory access out of bounds: instruction fetch at %03X, the memory ends at %X", e.Addr, e.Size-1)
+	}
+	return fmt.Sprintf("memory access out of bounds: %04X at %03X accesses %X, the memory ends at %X", e.Op, e.Addr, e.Target, e.Size-1)
+}
+
+// FaultPolicy diz o que a Machine faz quando uma instrução falha (os erros acima)
+type FaultPolicy int
+
+const (
+	FaultHalt FaultPolicy = iota // Para na instrução que falhou, Step e Run retornam o erro
+	FaultSkip                    // Pula a instrução que falhou sem avisar
+	FaultLog                     // Escreve o erro no writer do WithFaultLog e pula a instrução
+)
+
+func (policy FaultPolicy) String() string {
+	switch policy {
+	case FaultHalt:
+		return "halt"
+	case FaultSkip:
+		return "skip"
+	case FaultLog:
+		return "log"
+	}
+	return fmt.Sprintf("FaultPolicy(%d)", int(policy))
+}
+
+// ParseFaultPolicy converte o nome de uma política ("halt", "skip", "log") em FaultPolicy
+func ParseFaultPolicy(name string) (FaultPolicy, error) {
+	for _, policy := range []FaultPolicy{FaultHalt, FaultSkip, FaultLog} {
+		if policy.String() == name {
+			return policy, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown fault policy %q (available: halt, skip, log)", name)
+}
+
+// SetFaultPolicy troca a política de falhas, pode ser feito a qualquer momento
+func (chip_8 *Machine) SetFaultPolicy(policy FaultPolicy) {
+	chip_8.faultPolicy = policy
+}
+
+// Err retorna o erro que parou o Run (FaultHalt sem debugger), ou nil
+func (chip_8 *Machine) Err() error {
+	chip_8.mu.Lock()
+	defer chip_8.mu.Unlock()
+	return chip_8.err
+}
+
+// Registra a falha da instrução atual, ela é tratada pelo handleFault depois da instrução
+func (chip_8 *Machine) fail(err error) {
+	if chip_8.fault == nil {
+		chip_8.fault = err
+	}
+}
+
+// SetFaultLog troca aonde o FaultLog escreve as falhas (nil = em lugar nenhum)
+func (chip_8 *Machine) SetFaultLog(w io.Writer) {
+	chip_8.faultLog = w
+}
+
+// Aplica a política na falha da ultima instrução. Retorna o erro se a Machine deve parar.
+// Uma instrução fora da memoria não pode ser pulada (o PC só iria mais para fora), então
+// ela sempre para a Machine.
+func (chip_8 *Machine) handleFault() error {
+	err := chip_8.fault
+	chip_8.fault = nil
+	if err == nil {
+		return nil
+	}
+	if fetch, ok := err.(ErrMemoryOutOfBounds); ok && fetch.Target == int(fetch.Addr) {
+		return err
+	}
+	switch chip_8.faultPolicy {
+	case FaultLog:
+		if chip_8.faultLog != nil {
+			fmt.Fprintf(chip_8.faultLog, "%v, skipping it\n", err)
+		}
+		fallthrough
+	case FaultSkip:
+		chip_8.program_counter += 2
+		return nil
+	}
+	return err
+}
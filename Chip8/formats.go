@@ -0,0 +1,136 @@
+package Chip8
+
+import (
+	"image/color"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Program é uma ROM gerada a partir de outro formato de arquivo (código fonte, cartucho...).
+// Os campos opcionais são configurações que acompanham o programa, o LoadROM aplica na Machine
+// as que estiverem presentes e mantém as outras.
+type Program struct {
+	ROM            []byte
+	Symbols        *SymbolMap     // Labels e linhas do código fonte, se o formato tiver (opcional)
+	Mode           *Mode          // Plataforma para a qual o programa foi escrito (opcional)
+	Quirks         *Quirks        // Quirks que o programa espera (opcional)
+	Palette        *[4]color.RGBA // Cores do programa (opcional)
+	CyclesPerFrame int            // Instruções por quadro de 60Hz, 0 mantém o clock atual
+	Info           *ROMInfo       // Titulo, autores e descrição, quando a ROM é conhecida (opcional)
+}
+
+// ROMFormat converte o conteudo de um arquivo em Program, path é usado nas mensagens de erro e nos includes
+type ROMFormat func(path string, data []byte) (*Program, error)
+
+// Companion lê um arquivo com o mesmo nome da ROM e outra extensão (ex: game.json ao lado de game.ch8)
+// e completa program com as configurações dele
+type Companion func(path string, data []byte, program *Program) error
+
+var (
+	formatsMu  sync.Mutex
+	formats    = map[string]ROMFormat{}
+	companions = map[string]companionEntry{}
+)
+
+// Companion registrado e as extensões das ROMs que ele acompanha (todas se estiver vazio)
+type companionEntry struct {
+	companion Companion
+	romExts   []string
+}
+
+// RegisterFormat faz o LoadROM usar format para os arquivos com a extensão ext (ex: ".8o").
+// Normalmente é chamado no init do pacote que implementa o formato.
+func RegisterFormat(ext string, format ROMFormat) {
+	formatsMu.Lock()
+	defer formatsMu.Unlock()
+	formats[strings.ToLower(ext)] = format
+}
+
+// Procura o formato pela extensão do arquivo, nil para ROMs binarias
+func formatFor(path string) ROMFormat {
+	formatsMu.Lock()
+	defer formatsMu.Unlock()
+	return formats[strings.ToLower(filepath.Ext(path))]
+}
+
+// RegisterCompanion faz o LoadROM procurar, ao lado de cada ROM, um arquivo com a extensão ext
+// e passá-lo para companion. Com romExts (ex: ".8o"), só as ROMs com essas extensões são acompanhadas.
+func RegisterCompanion(ext string, companion Companion, romExts ...string) {
+	formatsMu.Lock()
+	defer formatsMu.Unlock()
+	entry := companionEntry{companion: companion}
+	for _, romExt := range romExts {
+		entry.romExts = append(entry.romExts, strings.ToLower(romExt))
+	}
+	companions[strings.ToLower(ext)] = entry
+}
+
+// Diz se o companion acompanha a ROM em path
+func (entry companionEntry) accepts(path string) bool {
+	if len(entry.romExts) == 0 {
+		return true
+	}
+	romExt := strings.ToLower(filepath.Ext(path))
+	for _, ext := range entry.romExts {
+		if ext == romExt {
+			return true
+		}
+	}
+	return false
+}
+
+// Aplica os arquivos companheiros que existirem ao lado de path, em ordem de extensão
+func loadCompanions(path string, program *Program) error {
+	formatsMu.Lock()
+	exts := make([]string, 0, len(companions))
+	for ext := range companions {
+		exts = append(exts, ext)
+	}
+	formatsMu.Unlock()
+	sort.Strings(exts)
+
+	base := strings.TrimSuffix(path, filepath.Ext(path))
+	for _, ext := range exts {
+		formatsMu.Lock()
+		entry := companions[ext]
+		formatsMu.Unlock()
+		companionPath := base + ext
+		if companionPath == path || !entry.accepts(path) {
+			continue
+		}
+		data, err := ioutil.ReadFile(companionPath)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if err := entry.companion(companionPath, data, program); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Aplica os quirks, as cores e o clock do programa na Machine, depois que a ROM foi carregada
+// (a plataforma já foi trocada pelo loadBytes)
+func (chip_8 *Machine) applyProgram(program *Program) {
+	if program.Quirks != nil {
+		chip_8.quirks = *program.Quirks
+	}
+	if program.Palette != nil {
+		chip_8.SetPalette(*program.Palette)
+	}
+	if program.CyclesPerFrame > 0 {
+		chip_8.SetCyclesPerFrame(program.CyclesPerFrame)
+	}
+}
+
+// Symbols retorna os símbolos do programa carregado, se o formato dele tiver (ex: código Octo)
+func (chip_8 *Machine) Symbols() *SymbolMap {
+	return chip_8.symbols
+}
@@ -0,0 +1,115 @@
+package Chip8
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+)
+
+// Frame é um quadro da tela pronto para ser desenhado.
+// Pixels tem Width*Height posições, linha por linha, e cada pixel é um indice da Palette
+// (0 = desligado, 1 = ligado; no XO-CHIP 2 e 3 são o segundo bitplane e os dois juntos).
+type Frame struct {
+	Width   int
+	Height  int
+	Pixels  []byte
+	Palette [4]color.RGBA
+}
+
+// Renderer recebe os quadros da tela sempre que o Chip-8 desenha algo
+type Renderer interface {
+	Render(frame Frame)
+}
+
+// AudioSink é avisado quando o tom do sound timer liga ou desliga
+type AudioSink interface {
+	SetTone(on bool)
+}
+
+// PatternSink é um AudioSink opcional que sabe tocar o audio pattern buffer do XO-CHIP.
+// O pattern tem 128 amostras de 1 bit, tocadas em loop a sampleRate amostras por segundo.
+type PatternSink interface {
+	AudioSink
+	SetPattern(pattern [16]byte, sampleRate float64)
+}
+
+// InputSource fornece o estado das 16 teclas do Chip-8 (0x0 - 0xF)
+type InputSource interface {
+	KeyState() [16]bool
+	// Closed indica que o usuário pediu para sair
+	Closed() bool
+}
+
+// Frame retorna uma cópia da tela atual
+func (chip_8 *Machine) Frame() Frame {
+	return Frame{
+		Width:   chip_8.width(),
+		Height:  chip_8.height(),
+		Pixels:  chip_8.GetGraphics(),
+		Palette: chip_8.palette,
+	}
+}
+
+// AudioPattern retorna o audio pattern buffer do XO-CHIP e quantas amostras por segundo ele toca
+func (chip_8 *Machine) AudioPattern() ([16]byte, float64) {
+	return chip_8.pattern, 4000 * math.Pow(2, (float64(chip_8.pitch)-64)/48)
+}
+
+// Avisa o AudioSink que o pattern ou o pitch mudou, se ele souber tocar patterns
+func (chip_8 *Machine) patternChanged() {
+	if sink, ok := chip_8.audio.(PatternSink); ok {
+		sink.SetPattern(chip_8.AudioPattern())
+	}
+}
+
+// Command é uma ação pedida pelo usuário no frontend, fora do teclado do Chip-8 (ex: hotkeys)
+type Command int
+
+const (
+	CommandSaveState Command = iota // Salva o estado no arquivo de save state
+	CommandLoadState                // Carrega o estado do arquivo de save state
+	CommandRewind                   // Volta um quadro no tempo, enviado enquanto o hotkey estiver segurado
+)
+
+// CommandSource é um InputSource opcional que também envia comandos para a Machine
+type CommandSource interface {
+	Commands() []Command
+}
+
+// Executa os comandos pedidos pelo frontend, se o InputSource enviar algum.
+// Retorna true se o estado da Machine foi trocado e ela não deve executar a proxima instrução.
+func (chip_8 *Machine) handleCommands() bool {
+	source, ok := chip_8.input.(CommandSource)
+	if !ok {
+		return false
+	}
+	replaced := false
+	for _, command := range source.Commands() {
+		switch command {
+		case CommandSaveState:
+			if err := chip_8.SaveStateFile(chip_8.statePath()); err != nil {
+				chip_8.logCommand("error saving state: %v\n", err)
+			} else {
+				chip_8.logCommand("state saved to %s\n", chip_8.statePath())
+			}
+		case CommandLoadState:
+			if err := chip_8.LoadStateFile(chip_8.statePath()); err != nil {
+				chip_8.logCommand("error loading state: %v\n", err)
+			} else {
+				chip_8.logCommand("state loaded from %s\n", chip_8.statePath())
+				replaced = true
+			}
+		case CommandRewind:
+			chip_8.Rewind()
+			replaced = true
+		}
+	}
+	return replaced
+}
+
+// Escreve o resultado de um comando no WithCommandLog, se ele foi definido
+func (chip_8 *Machine) logCommand(format string, args ...interface{}) {
+	if chip_8.commandLog != nil {
+		fmt.Fprintf(chip_8.commandLog, format, args...)
+	}
+}
@@ -0,0 +1,54 @@
+package Chip8
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// InputSource de teste que envia os comandos uma vez
+type commandInput struct {
+	commands []Command
+}
+
+func (input *commandInput) KeyState() [16]bool { return [16]bool{} }
+func (input *commandInput) Closed() bool       { return false }
+
+func (input *commandInput) Commands() []Command {
+	commands := input.commands
+	input.commands = nil
+	return commands
+}
+
+func TestCommandLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "game.state")
+	input := &commandInput{}
+	var log bytes.Buffer
+	chip_8 := newProgram(t, []uint16{0x7001, 0x1200}, WithInputSource(input), WithStatePath(path), WithCommandLog(&log))
+
+	input.commands = []Command{CommandLoadState}
+	chip_8.frame()
+	if !strings.HasPrefix(log.String(), "error loading state: ") {
+		t.Errorf("log = %q, want a load error", log.String())
+	}
+
+	log.Reset()
+	// O estado é salvo antes das instruções do quadro
+	saved := chip_8.Vx[0]
+	input.commands = []Command{CommandSaveState}
+	chip_8.frame()
+	chip_8.frame()
+	input.commands = []Command{CommandLoadState}
+	chip_8.frame()
+	if want := "state saved to " + path + "\nstate loaded from " + path + "\n"; log.String() != want {
+		t.Errorf("log = %q, want %q", log.String(), want)
+	}
+	if chip_8.Vx[0] != saved {
+		t.Errorf("V0 = %d after loading the state, want %d", chip_8.Vx[0], saved)
+	}
+
+	// Sem o WithCommandLog os resultados são descartados
+	chip_8 = newProgram(t, nil, WithInputSource(&commandInput{[]Command{CommandLoadState}}))
+	chip_8.frame()
+}
@@ -0,0 +1,183 @@
+package Chip8
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Listing é a disassembly de uma ROM inteira. O código é encontrado rastreando os caminhos possíveis
+// a partir de 0x200, o que não é alcançado é tratado como dados.
+type Listing struct {
+	Mode   Mode
+	Memory []byte              // Memoria com a ROM carregada em 0x200
+	End    int                 // Fim da ROM na memoria
+	Code   map[int]Instruction // Instruções alcançaveis, pelo endereço
+	Labels map[int]string      // Labels dos destinos de JP/CALL e dos dados apontados por LD I
+}
+
+// NewListing rastreia o código da rom, que é carregada em 0x200 como no LoadROM
+func NewListing(rom []byte, mode Mode) *Listing {
+	memory := make([]byte, 0x200+len(rom))
+	copy(memory[0x200:], rom)
+
+	l := &Listing{
+		Mode:   mode,
+		Memory: memory,
+		End:    len(memory),
+		Code:   map[int]Instruction{},
+		Labels: map[int]string{0x200: "start"},
+	}
+	l.trace(0x200)
+	return l
+}
+
+// Segue todos os caminhos a partir de entry, marcando as instruções e criando os labels
+func (l *Listing) trace(entry int) {
+	pending := []int{entry}
+	for len(pending) > 0 {
+		addr := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+
+		for addr >= 0x200 && addr < l.End {
+			if _, seen := l.Code[addr]; seen {
+				break
+			}
+			inst := DisassembleAt(l.Memory, addr, l.Mode)
+			if !inst.Valid {
+				break
+			}
+			l.Code[addr] = inst
+			next := addr + inst.Size
+
+			switch inst.Flow {
+			case FlowJump:
+				l.label(int(inst.Target), "L")
+				pending = append(pending, int(inst.Target))
+			case FlowCall:
+				l.label(int(inst.Target), "sub")
+				pending = append(pending, int(inst.Target))
+			case FlowSkip:
+				skipped := DisassembleAt(l.Memory, next, l.Mode)
+				pending = append(pending, next+skipped.Size)
+			case FlowIndirect:
+				// O destino depende do V0, só o começo da tabela de saltos é conhecido
+				l.label(int(inst.Target), "table")
+			default:
+				if inst.Data {
+					l.label(int(inst.Target), "data")
+				}
+			}
+
+			if inst.Flow == FlowJump || inst.Flow == FlowReturn || inst.Flow == FlowIndirect || inst.Flow == FlowExit {
+				break
+			}
+			addr = next
+		}
+	}
+}
+
+// Cria um label para addr se ele ainda não tiver um e estiver dentro da ROM
+func (l *Listing) label(addr int, prefix string) {
+	if addr < 0x200 || addr >= l.End {
+		return
+	}
+	if _, ok := l.Labels[addr]; !ok {
+		l.Labels[addr] = fmt.Sprintf("%s_%03X", prefix, addr)
+	}
+}
+
+// Quantos bytes de dados aparecem por linha
+const dataPerLine = 8
+
+// Print escreve a listagem com endereços, bytes, labels e a descrição de cada instrução
+func (l *Listing) Print(w io.Writer) error {
+	ew := &errWriter{w: w}
+	ew.printf("; %d bytes, %s, %d instructions reachable from 0x200\n", l.End-0x200, l.Mode, len(l.Code))
+
+	visited := map[int]bool{}
+	for addr := 0x200; addr < l.End; {
+		visited[addr] = true
+		if name, ok := l.Labels[addr]; ok {
+			ew.printf("\n%s:\n", name)
+		}
+
+		if inst, ok := l.Code[addr]; ok {
+			raw := hexBytes(l.Memory[addr : addr+inst.Size])
+			ew.printf("    %03X  %-12s %-24s ; %s\n", addr, raw, l.text(inst), inst.Description)
+			addr += inst.Size
+			continue
+		}
+
+		// Dados até o proximo label, instrução ou fim da linha
+		end := addr + 1
+		for end < l.End && end-addr < dataPerLine {
+			if _, ok := l.Code[end]; ok {
+				break
+			}
+			if _, ok := l.Labels[end]; ok {
+				break
+			}
+			end++
+		}
+		values := make([]string, 0, end-addr)
+		for _, b := range l.Memory[addr:end] {
+			values = append(values, fmt.Sprintf("0x%02X", b))
+		}
+		ew.printf("    %03X  %-12s DB %s\n", addr, hexBytes(l.Memory[addr:end]), strings.Join(values, ", "))
+		addr = end
+	}
+
+	if missing := l.hiddenLabels(visited); len(missing) > 0 {
+		ew.printf("\n; labels inside other instructions:\n")
+		for _, addr := range missing {
+			ew.printf(";   %s = 0x%03X\n", l.Labels[addr], addr)
+		}
+	}
+	return ew.err
+}
+
+// Texto da instrução com o destino trocado pelo label
+func (l *Listing) text(inst Instruction) string {
+	if inst.Flow == FlowJump || inst.Flow == FlowCall || inst.Flow == FlowIndirect || inst.Data {
+		if name, ok := l.Labels[int(inst.Target)]; ok && len(inst.Operands) > 0 {
+			operands := append([]string(nil), inst.Operands...)
+			operands[len(operands)-1] = name
+			inst.Operands = operands
+		}
+	}
+	return inst.String()
+}
+
+// Labels que caem no meio de uma instrução e não aparecem na listagem
+func (l *Listing) hiddenLabels(visited map[int]bool) []int {
+	var hidden []int
+	for addr := range l.Labels {
+		if !visited[addr] && addr < l.End {
+			hidden = append(hidden, addr)
+		}
+	}
+	sort.Ints(hidden)
+	return hidden
+}
+
+func hexBytes(data []byte) string {
+	if len(data) > 4 {
+		return fmt.Sprintf("% X..", data[:3])
+	}
+	return fmt.Sprintf("% X", data)
+}
+
+// errWriter guarda o primeiro erro de escrita para não checar cada Fprintf
+type errWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (ew *errWriter) printf(format string, args ...interface{}) {
+	if ew.err != nil {
+		return
+	}
+	_, ew.err = fmt.Fprintf(ew.w, format, args...)
+}
@@ -0,0 +1,465 @@
+package Chip8
+
+import (
+	"testing"
+	"time"
+)
+
+// Cria uma Machine com o programa words em 0x200
+func newProgram(t *testing.T, words []uint16, opts ...Option) *Machine {
+	t.Helper()
+	chip_8 := New(append([]Option{WithSeed(1)}, opts...)...)
+	rom := make([]byte, 0, len(words)*2)
+	for _, word := range words {
+		rom = append(rom, byte(word>>8), byte(word))
+	}
+	if err := chip_8.LoadBytes(rom); err != nil {
+		t.Fatal(err)
+	}
+	return chip_8
+}
+
+// Executa n instruções, falhando o teste se alguma delas falhar
+func steps(t *testing.T, chip_8 *Machine, n int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		if err := chip_8.Step(); err != nil {
+			t.Fatalf("step %d: %v", i+1, err)
+		}
+	}
+}
+
+func TestArithmetic(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		words  []uint16
+		v0, vf byte
+	}{
+		{"add", []uint16{0x60F0, 0x6120, 0x8014}, 0x10, 1},
+		{"add no carry", []uint16{0x6010, 0x6120, 0x8014}, 0x30, 0},
+		{"sub", []uint16{0x6030, 0x6110, 0x8015}, 0x20, 1},
+		{"sub borrow", []uint16{0x6010, 0x6130, 0x8015}, 0xE0, 0},
+		{"subn", []uint16{0x6010, 0x6130, 0x8017}, 0x20, 1},
+		{"or", []uint16{0x6012, 0x6121, 0x8011}, 0x33, 0},
+		{"and", []uint16{0x6013, 0x6131, 0x8012}, 0x11, 0},
+		{"xor", []uint16{0x6013, 0x6131, 0x8013}, 0x22, 0},
+		{"add immediate wraps", []uint16{0x60FF, 0x7002}, 0x01, 0},
+	} {
+		chip_8 := newProgram(t, test.words)
+		steps(t, chip_8, len(test.words))
+		if regs := chip_8.Registers(); regs[0] != test.v0 || regs[0xF] != test.vf {
+			t.Errorf("%s: V0 = %02X, VF = %02X, want %02X, %02X", test.name, regs[0], regs[0xF], test.v0, test.vf)
+		}
+	}
+}
+
+func TestFlowControl(t *testing.T) {
+	chip_8 := newProgram(t, []uint16{
+		0x6005, // 200: V0 = 5
+		0x3005, // 202: pula se V0 == 5
+		0x6101, // 204: (pulada)
+		0x2208, // 206: chama 208
+		0x6202, // 208: V2 = 2
+		0x00EE, // 20A: volta para 208
+	})
+	steps(t, chip_8, 4)
+	if stack, sp := chip_8.Stack(); sp != 1 || stack[1] != 0x206 {
+		t.Errorf("stack = %v, sp = %d after CALL", stack, sp)
+	}
+	steps(t, chip_8, 1)
+	if pc := chip_8.ProgramCounter(); pc != 0x208 {
+		t.Errorf("PC after RET = %03X, want 208", pc)
+	}
+	if regs := chip_8.Registers(); regs[1] != 0 || regs[2] != 2 {
+		t.Errorf("V1 = %d, V2 = %d, want 0, 2", regs[1], regs[2])
+	}
+}
+
+func TestDraw(t *testing.T) {
+	chip_8 := newProgram(t, []uint16{
+		0x6002, // V0 = 2 (x)
+		0x6103, // V1 = 3 (y)
+		0x6200, // V2 = 0
+		0xF229, // I = sprite do 0
+		0xD015, // desenha
+		0xD015, // desenha de novo, apagando
+	})
+	steps(t, chip_8, 5)
+	// Primeira linha do 0 é 0xF0: quatro pixels ligados a partir de (2, 3)
+	for x := 0; x < 8; x++ {
+		want := byte(0)
+		if x >= 2 && x < 6 {
+			want = 1
+		}
+		if got := chip_8.gfx[3*64+x]; got != want {
+			t.Errorf("pixel (%d, 3) = %d, want %d", x, got, want)
+		}
+	}
+	if vf := chip_8.Registers()[0xF]; vf != 0 || !chip_8.DrawFlag() {
+		t.Errorf("VF = %d, draw flag = %v after the first draw", vf, chip_8.DrawFlag())
+	}
+
+	steps(t, chip_8, 1)
+	for i, pixel := range chip_8.GetGraphics() {
+		if pixel != 0 {
+			t.Fatalf("pixel %d still on after drawing the sprite twice", i)
+		}
+	}
+	if vf := chip_8.Registers()[0xF]; vf != 1 {
+		t.Errorf("VF = %d after the collision, want 1", vf)
+	}
+}
+
+func TestTimers(t *testing.T) {
+	chip_8 := newProgram(t, []uint16{0x600A, 0xF015, 0xF018, 0x1206}, WithCyclesPerFrame(10))
+	steps(t, chip_8, 3)
+	if chip_8.DelayTimer != 10 || chip_8.SoundTimer != 10 {
+		t.Fatalf("timers = %d, %d, want 10, 10", chip_8.DelayTimer, chip_8.SoundTimer)
+	}
+	// O quadro termina na decima instrução
+	steps(t, chip_8, 6)
+	if chip_8.DelayTimer != 10 {
+		t.Errorf("delay timer = %d before the end of the frame, want 10", chip_8.DelayTimer)
+	}
+	steps(t, chip_8, 1)
+	if chip_8.DelayTimer != 9 || chip_8.SoundTimer != 9 {
+		t.Errorf("timers = %d, %d after one frame, want 9, 9", chip_8.DelayTimer, chip_8.SoundTimer)
+	}
+}
+
+func TestWaitKey(t *testing.T) {
+	chip_8 := newProgram(t, []uint16{0x6305, 0xF30A, 0xF40A})
+	steps(t, chip_8, 3)
+	if chip_8.program_counter != 0x202 || chip_8.Vx[3] != 5 {
+		t.Fatalf("FX0A without a key: PC = %03X, V3 = %d, want 202, 5", chip_8.program_counter, chip_8.Vx[3])
+	}
+
+	// A primeira tecla pressionada é lida e solta, as outras continuam pressionadas
+	chip_8.SetKeyDown(0x7)
+	chip_8.SetKeyDown(0xA)
+	steps(t, chip_8, 1)
+	if chip_8.program_counter != 0x204 || chip_8.Vx[3] != 7 {
+		t.Fatalf("FX0A with key 7: PC = %03X, V3 = %d, want 204, 7", chip_8.program_counter, chip_8.Vx[3])
+	}
+	if chip_8.key[0x7] != 0 || chip_8.key[0xA] == 0 {
+		t.Errorf("keys = %v, want only key A down", chip_8.key)
+	}
+	steps(t, chip_8, 1)
+	if chip_8.program_counter != 0x206 || chip_8.Vx[4] != 0xA {
+		t.Errorf("FX0A with key A: PC = %03X, V4 = %X, want 206, A", chip_8.program_counter, chip_8.Vx[4])
+	}
+}
+
+func TestReset(t *testing.T) {
+	chip_8 := newProgram(t, []uint16{0x6042, 0xA300, 0x2200})
+	steps(t, chip_8, 3)
+	chip_8.Reset()
+	if regs := chip_8.Registers(); regs[0] != 0 || chip_8.Index() != 0 || chip_8.ProgramCounter() != 0x200 {
+		t.Errorf("after Reset V0 = %02X, I = %03X, PC = %03X", regs[0], chip_8.Index(), chip_8.ProgramCounter())
+	}
+	if _, sp := chip_8.Stack(); sp != 0 {
+		t.Errorf("stack pointer = %d after Reset", sp)
+	}
+	if memory := chip_8.Memory(); memory[0x200] != 0x60 || memory[0x201] != 0x42 || memory[0] != FontSet[0] {
+		t.Errorf("Reset did not reload the ROM and the font")
+	}
+	steps(t, chip_8, 1)
+	if chip_8.Registers()[0] != 0x42 {
+		t.Errorf("the ROM does not run again after Reset")
+	}
+}
+
+func TestStepFault(t *testing.T) {
+	chip_8 := newProgram(t, []uint16{0x00EE})
+	err := chip_8.Step()
+	if _, ok := err.(ErrStackUnderflow); !ok {
+		t.Fatalf("Step = %v, want ErrStackUnderflow", err)
+	}
+	if pc := chip_8.ProgramCounter(); pc != 0x200 {
+		t.Errorf("PC = %03X after the fault, want 200", pc)
+	}
+}
+
+// Espera o Run retornar, falhando o teste se ele demorar
+func waitRun(t *testing.T, chip_8 *Machine) error {
+	t.Helper()
+	result := make(chan error, 1)
+	go func() { result <- chip_8.Run() }()
+	select {
+	case err := <-result:
+		return err
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not return")
+		return nil
+	}
+}
+
+func TestRunExit(t *testing.T) {
+	chip_8 := newProgram(t, []uint16{0x6001, 0x00FD}, WithMode(SuperChipMode))
+	if err := waitRun(t, chip_8); err != nil {
+		t.Fatal(err)
+	}
+	if !chip_8.Halted() || chip_8.Registers()[0] != 1 {
+		t.Errorf("halted = %v, V0 = %d after 00FD", chip_8.Halted(), chip_8.Registers()[0])
+	}
+}
+
+func TestRunFault(t *testing.T) {
+	chip_8 := newProgram(t, []uint16{0x00EE})
+	if _, ok := waitRun(t, chip_8).(ErrStackUnderflow); !ok {
+		t.Errorf("Run = %v, want ErrStackUnderflow", chip_8.Err())
+	}
+}
+
+func TestStop(t *testing.T) {
+	chip_8 := newProgram(t, []uint16{0x1200})
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		chip_8.Stop()
+		chip_8.Stop()
+	}()
+	if err := waitRun(t, chip_8); err != nil {
+		t.Fatal(err)
+	}
+	// Depois do Stop o Run retorna logo
+	if err := waitRun(t, chip_8); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Pixel (x, y) da tela atual
+func pixel(chip_8 *Machine, x, y int) byte {
+	return chip_8.gfx[y*chip_8.width()+x]
+}
+
+func TestSuperChipNeedsMode(t *testing.T) {
+	for _, word := range []uint16{0x00C1, 0x00FB, 0x00FC, 0x00FD, 0x00FE, 0x00FF, 0xF030, 0xF075, 0xF085} {
+		chip_8 := newProgram(t, []uint16{word})
+		if _, ok := chip_8.Step().(ErrUnknownOpcode); !ok {
+			t.Errorf("%04X in chip8 mode did not fail with ErrUnknownOpcode", word)
+		}
+		if chip_8.hires || chip_8.halted {
+			t.Errorf("%04X in chip8 mode changed the machine", word)
+		}
+
+		chip_8 = newProgram(t, []uint16{word}, WithMode(SuperChipMode))
+		if err := chip_8.Step(); err != nil {
+			t.Errorf("%04X in schip mode: %v", word, err)
+		}
+	}
+}
+
+func TestHighResolution(t *testing.T) {
+	chip_8 := newProgram(t, []uint16{
+		0x00FF, // 128x64
+		0x6064, // V0 = 100
+		0x6132, // V1 = 50
+		0xA000, // I = sprite do 0
+		0xD011, // uma linha em (100, 50)
+		0x00FE, // 64x32
+	}, WithMode(SuperChipMode))
+	steps(t, chip_8, 5)
+	if frame := chip_8.Frame(); frame.Width != 128 || frame.Height != 64 || len(frame.Pixels) != 128*64 {
+		t.Fatalf("frame = %dx%d with %d pixels after 00FF", frame.Width, frame.Height, len(frame.Pixels))
+	}
+	if pixel(chip_8, 100, 50) != 1 || pixel(chip_8, 104, 50) != 0 {
+		t.Errorf("sprite not drawn at (100, 50) in high resolution")
+	}
+	steps(t, chip_8, 1)
+	frame := chip_8.Frame()
+	if frame.Width != 64 || frame.Height != 32 {
+		t.Fatalf("frame = %dx%d after 00FE", frame.Width, frame.Height)
+	}
+	for i, p := range frame.Pixels {
+		if p != 0 {
+			t.Fatalf("pixel %d on after switching resolution", i)
+		}
+	}
+}
+
+func TestScroll(t *testing.T) {
+	chip_8 := newProgram(t, []uint16{
+		0x6000, // V0 = 0
+		0xA000, // I = sprite do 0, primeira linha 0xF0
+		0xD001, // pixels 0-3 da linha 0
+		0x00C2, // 2 linhas para baixo
+		0x00FB, // 4 pixels para a direita
+		0x00FC, // 4 pixels para a esquerda
+	}, WithMode(SuperChipMode))
+	steps(t, chip_8, 4)
+	if pixel(chip_8, 0, 0) != 0 || pixel(chip_8, 0, 2) != 1 || pixel(chip_8, 3, 2) != 1 {
+		t.Errorf("00C2 did not move the row from y = 0 to y = 2")
+	}
+	steps(t, chip_8, 1)
+	if pixel(chip_8, 0, 2) != 0 || pixel(chip_8, 4, 2) != 1 || pixel(chip_8, 7, 2) != 1 || pixel(chip_8, 8, 2) != 0 {
+		t.Errorf("00FB did not move the pixels 4 to the right")
+	}
+	steps(t, chip_8, 1)
+	if pixel(chip_8, 0, 2) != 1 || pixel(chip_8, 4, 2) != 0 {
+		t.Errorf("00FC did not move the pixels 4 to the left")
+	}
+}
+
+func TestDrawBigSprite(t *testing.T) {
+	words := []uint16{
+		0xA206, // I = 206
+		0xD000, // sprite 16x16 em (0, 0)
+		0x1204,
+	}
+	// Uma moldura: linhas de cima e de baixo cheias, as outras só com as bordas
+	words = append(words, 0xFFFF)
+	for i := 0; i < 14; i++ {
+		words = append(words, 0x8001)
+	}
+	words = append(words, 0xFFFF)
+
+	chip_8 := newProgram(t, words, WithMode(SuperChipMode))
+	steps(t, chip_8, 2)
+	for _, p := range [][2]int{{0, 0}, {15, 0}, {0, 8}, {15, 8}, {0, 15}, {15, 15}} {
+		if pixel(chip_8, p[0], p[1]) != 1 {
+			t.Errorf("pixel (%d, %d) of the 16x16 sprite is off", p[0], p[1])
+		}
+	}
+	if pixel(chip_8, 8, 8) != 0 || pixel(chip_8, 16, 0) != 0 || pixel(chip_8, 0, 16) != 0 {
+		t.Errorf("DXY0 drew outside the 16x16 frame")
+	}
+
+	// No Chip-8 o DXY0 não desenha nada
+	chip_8 = newProgram(t, words)
+	steps(t, chip_8, 2)
+	for i, p := range chip_8.GetGraphics() {
+		if p != 0 {
+			t.Fatalf("DXY0 in chip8 mode turned pixel %d on", i)
+		}
+	}
+}
+
+func TestBigFont(t *testing.T) {
+	chip_8 := newProgram(t, []uint16{0x6007, 0xF030}, WithMode(SuperChipMode))
+	steps(t, chip_8, 2)
+	if i := chip_8.Index(); i != bigFontAddr+70 {
+		t.Fatalf("I = %03X after F030 with V0 = 7, want %03X", i, bigFontAddr+70)
+	}
+	memory := chip_8.Memory()
+	for row := 0; row < 10; row++ {
+		if memory[bigFontAddr+70+row] != BigFontSet[70+row] {
+			t.Errorf("row %d of the big 7 = %02X, want %02X", row, memory[bigFontAddr+70+row], BigFontSet[70+row])
+		}
+	}
+}
+
+func TestRPLFlags(t *testing.T) {
+	chip_8 := newProgram(t, []uint16{0x6011, 0x6122, 0xF175, 0x6000, 0x6100, 0xF185}, WithMode(SuperChipMode))
+	steps(t, chip_8, 6)
+	if regs := chip_8.Registers(); regs[0] != 0x11 || regs[1] != 0x22 {
+		t.Errorf("V0, V1 = %02X, %02X after FX85, want 11, 22", regs[0], regs[1])
+	}
+}
+
+func TestSetMode(t *testing.T) {
+	chip_8 := New(WithMode(XOChipMode))
+	if err := chip_8.LoadBytes(make([]byte, 5000)); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := chip_8.SetMode(SuperChipMode).(ErrROMTooLarge); !ok || chip_8.Mode() != XOChipMode {
+		t.Errorf("SetMode with a ROM that does not fit: mode = %s", chip_8.Mode())
+	}
+	chip_8 = newProgram(t, []uint16{0x00FF})
+	if err := chip_8.SetMode(SuperChipMode); err != nil {
+		t.Fatal(err)
+	}
+	steps(t, chip_8, 1)
+	if !chip_8.hires {
+		t.Errorf("00FF did not run after SetMode(SuperChipMode)")
+	}
+}
+
+func TestXOChipNeedsMode(t *testing.T) {
+	for _, word := range []uint16{0x00D1, 0x5012, 0x5013, 0xF000, 0xF201, 0xF002, 0xF03A} {
+		for _, mode := range []Mode{Chip8Mode, SuperChipMode} {
+			chip_8 := newProgram(t, []uint16{word, 0x1234}, WithMode(mode))
+			if _, ok := chip_8.Step().(ErrUnknownOpcode); !ok {
+				t.Errorf("%04X in %s mode did not fail with ErrUnknownOpcode", word, mode)
+			}
+			if chip_8.plane != 1 || chip_8.Index() != 0 || chip_8.ProgramCounter() != 0x200 {
+				t.Errorf("%04X in %s mode changed the machine", word, mode)
+			}
+		}
+
+		chip_8 := newProgram(t, []uint16{word, 0x1234}, WithMode(XOChipMode))
+		if err := chip_8.Step(); err != nil {
+			t.Errorf("%04X in xochip mode: %v", word, err)
+		}
+	}
+}
+
+func TestBitplanes(t *testing.T) {
+	chip_8 := newProgram(t, []uint16{
+		0xF301, // os dois planos
+		0xA20E, // I = 20E
+		0x6000, // V0 = 0
+		0xD001, // uma linha em (0, 0), primeiro plano 0xF0, segundo 0x3C
+		0xF101, // só o primeiro plano
+		0x00E0, // limpa o primeiro plano
+		0x120C,
+		0xF03C, // 20E: dados dos dois planos
+	}, WithMode(XOChipMode))
+	steps(t, chip_8, 4)
+	for x, want := range []byte{1, 1, 3, 3, 2, 2, 0, 0} {
+		if got := pixel(chip_8, x, 0); got != want {
+			t.Errorf("pixel (%d, 0) = %d after drawing both planes, want %d", x, got, want)
+		}
+	}
+	steps(t, chip_8, 2)
+	for x, want := range []byte{0, 0, 2, 2, 2, 2, 0, 0} {
+		if got := pixel(chip_8, x, 0); got != want {
+			t.Errorf("pixel (%d, 0) = %d after clearing plane 1, want %d", x, got, want)
+		}
+	}
+	if frame := chip_8.Frame(); frame.Pixels[2] != 2 || frame.Palette != DefaultPalette {
+		t.Errorf("Frame does not carry the plane indexes and the palette")
+	}
+}
+
+func TestLongIndex(t *testing.T) {
+	chip_8 := newProgram(t, []uint16{
+		0x6000,         // V0 = 0
+		0x3000,         // pula se V0 == 0, o F000 NNNN inteiro
+		0xF000, 0x1234, // (pulada)
+		0xF000, 0x1234, // I = 1234
+		0xF065, // V0 = memoria[1234]
+	}, WithMode(XOChipMode))
+	steps(t, chip_8, 2)
+	if pc := chip_8.ProgramCounter(); pc != 0x208 {
+		t.Fatalf("PC = %03X after skipping F000 NNNN, want 208", pc)
+	}
+	steps(t, chip_8, 1)
+	if i, pc := chip_8.Index(), chip_8.ProgramCounter(); i != 0x1234 || pc != 0x20C {
+		t.Errorf("I = %04X, PC = %03X after F000 1234, want 1234, 20C", i, pc)
+	}
+	chip_8.memory[0x1234] = 0x42
+	steps(t, chip_8, 1)
+	if v0 := chip_8.Registers()[0]; v0 != 0x42 {
+		t.Errorf("V0 = %02X after reading above 4KB, want 42", v0)
+	}
+}
+
+func TestROMSizeLimit(t *testing.T) {
+	for _, test := range []struct {
+		mode Mode
+		max  int
+	}{
+		{Chip8Mode, 4096 - 0x200},
+		{SuperChipMode, 4096 - 0x200},
+		{XOChipMode, 65536 - 0x200},
+	} {
+		chip_8 := New(WithMode(test.mode))
+		if err := chip_8.LoadBytes(make([]byte, test.max)); err != nil {
+			t.Errorf("%s: LoadBytes(%d bytes) = %v", test.mode, test.max, err)
+		}
+		err, ok := chip_8.LoadBytes(make([]byte, test.max+1)).(ErrROMTooLarge)
+		if !ok || err.Max != test.max || err.Size != test.max+1 {
+			t.Errorf("%s: LoadBytes(%d bytes) = %v, want ErrROMTooLarge", test.mode, test.max+1, err)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package Chip8
+
+// Todo acesso das instruções à memoria e à pilha passa por aqui. Um acesso fora da memoria
+// da plataforma (4KB, ou 64KB no XO-CHIP) falha com ErrMemoryOutOfBounds, ou com o quirk
+// MemoryWrap volta para o inicio da memoria, como no COSMAC VIP.
+
+// Primeiro endereço de addr até addr+n-1 fora da memoria da plataforma, ou -1 se todos estão dentro
+func (chip_8 *Machine) outOfBounds(addr, n int) int {
+	size := chip_8.mode.MemorySize()
+	if chip_8.quirks.MemoryWrap || addr+n <= size {
+		return -1
+	}
+	if addr < size {
+		return size
+	}
+	return addr
+}
+
+// Verifica os n bytes a partir de addr antes da instrução acessar eles, para que uma
+// instrução que falha não seja executada pela metade
+func (chip_8 *Machine) checkMemory(addr, n int) bool {
+	target := chip_8.outOfBounds(addr, n)
+	if target < 0 {
+		return true
+	}
+	chip_8.fail(ErrMemoryOutOfBounds{
+		Addr:   chip_8.program_counter,
+		Op:     chip_8.opcode,
+		Target: target,
+		Size:   chip_8.mode.MemorySize(),
+	})
+	return false
+}
+
+// Byte no endereço addr, que volta para o inicio da memoria com o quirk MemoryWrap
+func (chip_8 *Machine) memoryAt(addr int) *byte {
+	return &chip_8.memory[addr%chip_8.mode.MemorySize()]
+}
+
+// Lê um byte da memoria para uma instrução, avisando o debugger (watchpoints).
+// Fora da memoria a instrução falha e o valor lido é 0.
+func (chip_8 *Machine) readMemory(addr int) byte {
+	if !chip_8.checkMemory(addr, 1) {
+		return 0
+	}
+	chip_8.debugger.memoryAccess(chip_8.wrapAddr(addr), false)
+	return *chip_8.memoryAt(addr)
+}
+
+// Escreve um byte da memoria para uma instrução, avisando o debugger (watchpoints).
+// Fora da memoria a instrução falha e nada é escrito.
+func (chip_8 *Machine) writeMemory(addr int, value byte) {
+	if !chip_8.checkMemory(addr, 1) {
+		return
+	}
+	chip_8.debugger.memoryAccess(chip_8.wrapAddr(addr), true)
+	*chip_8.memoryAt(addr) = value
+}
+
+func (chip_8 *Machine) wrapAddr(addr int) uint16 {
+	return uint16(addr % chip_8.mode.MemorySize())
+}
+
+// Coloca o endereço de retorno na pilha (2NNN). stack[0] nunca é usado, então cabem 15 chamadas.
+func (chip_8 *Machine) pushStack(addr uint16) bool {
+	if int(chip_8.stack_pointer) >= len(chip_8.stack)-1 {
+		chip_8.fail(ErrStackOverflow{Addr: chip_8.program_counter, Op: chip_8.opcode})
+		return false
+	}
+	chip_8.stack_pointer++
+	chip_8.stack[chip_8.stack_pointer] = addr
+	return true
+}
+
+// Tira o endereço de retorno da pilha (00EE)
+func (chip_8 *Machine) popStack() (uint16, bool) {
+	if chip_8.stack_pointer == 0 {
+		chip_8.fail(ErrStackUnderflow{Addr: chip_8.program_counter, Op: chip_8.opcode})
+		return 0, false
+	}
+	// Um stack pointer além da pilha só aparece com um save state corrompido
+	if int(chip_8.stack_pointer) >= len(chip_8.stack) {
+		chip_8.fail(ErrStackOverflow{Addr: chip_8.program_counter, Op: chip_8.opcode})
+		return 0, false
+	}
+	addr := chip_8.stack[chip_8.stack_pointer]
+	chip_8.stack_pointer--
+	return addr, true
+}
@@ -0,0 +1,104 @@
+package Chip8
+
+import "fmt"
+
+// Mode é a plataforma emulada, ela define o tamanho da memoria disponivel
+type Mode int
+
+const (
+	Chip8Mode     Mode = iota // COSMAC VIP, 4KB de memoria
+	SuperChipMode             // SUPER-CHIP 1.1, 4KB de memoria
+	XOChipMode                // XO-CHIP, 64KB de memoria
+)
+
+func (mode Mode) String() string {
+	switch mode {
+	case Chip8Mode:
+		return "chip8"
+	case SuperChipMode:
+		return "schip"
+	case XOChipMode:
+		return "xochip"
+	}
+	return fmt.Sprintf("Mode(%d)", int(mode))
+}
+
+// MemorySize retorna quantos bytes de memoria a plataforma enxerga
+func (mode Mode) MemorySize() int {
+	if mode == XOChipMode {
+		return 65536
+	}
+	return 4096
+}
+
+// ParseMode converte o nome de uma plataforma ("chip8", "schip", "xochip") em Mode
+func ParseMode(name string) (Mode, error) {
+	for _, mode := range []Mode{Chip8Mode, SuperChipMode, XOChipMode} {
+		if mode.String() == name {
+			return mode, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown mode %q (available: chip8, schip, xochip)", name)
+}
+
+// Mode retorna a plataforma emulada
+func (chip_8 *Machine) Mode() Mode {
+	return chip_8.mode
+}
+
+// SetMode troca a plataforma emulada e reinicia a Machine com a ROM atual.
+// Falha, sem trocar nada, se a ROM não couber na memoria da nova plataforma.
+func (chip_8 *Machine) SetMode(mode Mode) error {
+	if max := mode.MemorySize() - 0x200; len(chip_8.rom) > max {
+		return ErrROMTooLarge{Size: len(chip_8.rom), Max: max, Mode: mode}
+	}
+	chip_8.mode = mode
+	chip_8.Reset()
+	return nil
+}
+
+// Cada plataforma tem todas as instruções da anterior (Chip-8, SUPER-CHIP, XO-CHIP).
+// Falha com ErrUnknownOpcode se a instrução atual for de uma plataforma além da emulada.
+func (chip_8 *Machine) requireMode(mode Mode) bool {
+	if chip_8.mode >= mode {
+		return true
+	}
+	chip_8.fail(ErrUnknownOpcode{Addr: chip_8.program_counter, Op: chip_8.opcode})
+	return false
+}
+
+// Le a word logo depois da instrução atual, usada pelo F000 NNNN
+func (chip_8 *Machine) nextWord() uint16 {
+	pc := int(chip_8.program_counter)
+	return uint16(*chip_8.memoryAt(pc + 2))<<8 | uint16(*chip_8.memoryAt(pc + 3))
+}
+
+// Pula a proxima instrução. No XO-CHIP o F000 NNNN tem 4 bytes e é pulado inteiro
+func (chip_8 *Machine) skipNext() {
+	chip_8.program_counter += 2
+	if chip_8.mode == XOChipMode && chip_8.nextOpcode() == 0xF000 {
+		chip_8.program_counter += 2
+	}
+	chip_8.program_counter += 2
+}
+
+// Le a instrução que está no program counter
+func (chip_8 *Machine) nextOpcode() uint16 {
+	pc := int(chip_8.program_counter)
+	return uint16(*chip_8.memoryAt(pc))<<8 | uint16(*chip_8.memoryAt(pc + 1))
+}
+
+// Registradores usados pelo 5XY2/5XY3, de x até y (em ordem reversa se x > y)
+func registerRange(x, y uint16) []uint16 {
+	var regs []uint16
+	if x <= y {
+		for reg := x; reg <= y; reg++ {
+			regs = append(regs, reg)
+		}
+	} else {
+		for reg := x; reg >= y && reg <= x; reg-- {
+			regs = append(regs, reg)
+		}
+	}
+	return regs
+}
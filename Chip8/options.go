@@ -0,0 +1,126 @@
+package Chip8
+
+import (
+	"image/color"
+	"io"
+	"math/rand"
+)
+
+// Option configura uma Machine criada por New
+type Option func(*Machine)
+
+// WithRenderer conecta aonde os graficos serão desenhados
+func WithRenderer(renderer Renderer) Option {
+	return func(chip_8 *Machine) {
+		chip_8.renderer = renderer
+	}
+}
+
+// WithInputSource conecta a fonte do estado das teclas
+func WithInputSource(input InputSource) Option {
+	return func(chip_8 *Machine) {
+		chip_8.input = input
+	}
+}
+
+// WithAudioSink conecta quem toca o som do sound timer
+func WithAudioSink(audio AudioSink) Option {
+	return func(chip_8 *Machine) {
+		chip_8.audio = audio
+	}
+}
+
+// WithClockSpeed define quantas instruções por segundo o Run executa
+func WithClockSpeed(hz int) Option {
+	return func(chip_8 *Machine) {
+		chip_8.SetClockSpeed(hz)
+	}
+}
+
+// WithCyclesPerFrame define quantas instruções são executadas a cada quadro de 60Hz
+func WithCyclesPerFrame(cycles int) Option {
+	return func(chip_8 *Machine) {
+		chip_8.SetCyclesPerFrame(cycles)
+	}
+}
+
+// WithTiming escolhe o modelo de tempo (ver Timing)
+func WithTiming(timing Timing) Option {
+	return func(chip_8 *Machine) {
+		chip_8.timing = timing
+	}
+}
+
+// WithFaultPolicy escolhe o que fazer quando uma instrução falha (ver FaultPolicy)
+func WithFaultPolicy(policy FaultPolicy) Option {
+	return func(chip_8 *Machine) {
+		chip_8.faultPolicy = policy
+	}
+}
+
+// WithFaultLog escolhe aonde o FaultLog escreve as falhas, sem ele elas são só puladas
+func WithFaultLog(w io.Writer) Option {
+	return func(chip_8 *Machine) {
+		chip_8.faultLog = w
+	}
+}
+
+// WithQuirks define como as instruções ambíguas se comportam (ver QuirksProfiles)
+func WithQuirks(quirks Quirks) Option {
+	return func(chip_8 *Machine) {
+		chip_8.quirks = quirks
+	}
+}
+
+// WithMode escolhe a plataforma emulada, ela define o tamanho maximo da ROM
+func WithMode(mode Mode) Option {
+	return func(chip_8 *Machine) {
+		chip_8.mode = mode
+	}
+}
+
+// WithPalette define as cores de cada combinação de bitplanes
+func WithPalette(palette [4]color.RGBA) Option {
+	return func(chip_8 *Machine) {
+		chip_8.palette = palette
+	}
+}
+
+// WithSeed fixa a semente dos números aleatorios do CXNN, para execuções que se repetem
+func WithSeed(seed int64) Option {
+	return func(chip_8 *Machine) {
+		chip_8.random = rand.New(rand.NewSource(seed))
+	}
+}
+
+// WithStatePath define o arquivo usado pelos hotkeys de save state
+func WithStatePath(path string) Option {
+	return func(chip_8 *Machine) {
+		chip_8.stateFile = path
+	}
+}
+
+// WithCommandLog escolhe aonde os hotkeys de save state escrevem se o estado foi salvo ou
+// carregado, sem ele os resultados são descartados
+func WithCommandLog(w io.Writer) Option {
+	return func(chip_8 *Machine) {
+		chip_8.commandLog = w
+	}
+}
+
+// WithRewind guarda os ultimos frames estados da Machine para o Rewind (0 = desligado)
+func WithRewind(frames int) Option {
+	return func(chip_8 *Machine) {
+		chip_8.rewind = nil
+		if frames > 0 {
+			chip_8.rewind = newRewindBuffer(frames)
+		}
+	}
+}
+
+// WithDatabase troca o banco de dados de ROMs usado pelo LoadROM (nil desliga a consulta)
+func WithDatabase(db *Database) Option {
+	return func(chip_8 *Machine) {
+		chip_8.database = db
+	}
+}
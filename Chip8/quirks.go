@@ -0,0 +1,117 @@
+package Chip8
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Quirks escolhe como as instruções ambíguas do Chip-8 se comportam.
+// Cada interpretador (COSMAC VIP, CHIP-48, SUPER-CHIP...) implementou essas instruções
+// de um jeito diferente, e as ROMs dependem do comportamento da plataforma para a qual foram escritas.
+type Quirks struct {
+	Shift       bool // 8XY6/8XYE deslocam o proprio Vx, ignorando Vy (CHIP-48/SUPER-CHIP)
+	LoadStore   bool // FX55/FX65 avançam o I(ndex) para I + X + 1 (COSMAC VIP)
+	LoadStoreX  bool // FX55/FX65 avançam o I(ndex) só para I + X, um bug do CHIP-48 (ignorado com LoadStore)
+	Jump        bool // BNNN vira BXNN, pulando para XNN + Vx em vez de NNN + V0 (CHIP-48/SUPER-CHIP)
+	VFReset     bool // 8XY1/8XY2/8XY3 zeram o VF (COSMAC VIP)
+	Clipping    bool // Sprites são cortados na borda da tela em vez de aparecerem do outro lado
+	DisplayWait bool // DXYN espera o proximo vblank (60Hz) antes de desenhar (COSMAC VIP)
+	MemoryWrap  bool // Endereços além do fim da memoria voltam para o inicio em vez de falhar (COSMAC VIP)
+}
+
+// Perfis de quirks conhecidos, selecionados pelo nome
+var QuirksProfiles = map[string]Quirks{
+	"vip": {
+		LoadStore:   true,
+		VFReset:     true,
+		Clipping:    true,
+		DisplayWait: true,
+		MemoryWrap:  true,
+	},
+	"chip48": {
+		Shift:      true,
+		LoadStoreX: true,
+		Jump:       true,
+		Clipping:   true,
+	},
+	// O SUPER-CHIP 1.1 corrigiu o FX55/FX65 do CHIP-48, o I(ndex) não muda
+	"schip": {
+		Shift:    true,
+		Jump:     true,
+		Clipping: true,
+	},
+	"xochip": {
+		LoadStore: true,
+	},
+	"modern": {
+		LoadStore: true,
+		Clipping:  true,
+	},
+}
+
+// Perfil usado quando nenhum outro é escolhido
+const DefaultQuirksProfile = "modern"
+
+// QuirksProfile retorna os quirks de um perfil pelo nome (ex: "vip", "schip")
+func QuirksProfile(name string) (Quirks, error) {
+	quirks, ok := QuirksProfiles[strings.ToLower(name)]
+	if !ok {
+		return Quirks{}, fmt.Errorf("unknown quirks profile %q (available: %s)", name, strings.Join(QuirksProfileNames(), ", "))
+	}
+	return quirks, nil
+}
+
+// QuirksProfileNames retorna os nomes dos perfis em ordem alfabética
+func QuirksProfileNames() []string {
+	names := make([]string, 0, len(QuirksProfiles))
+	for name := range QuirksProfiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Nomes dos quirks, em minusculas, usados pelo Set e pelo String
+var QuirkNames = []string{"shift", "loadstore", "loadstorex", "jump", "vfreset", "clipping", "displaywait", "memorywrap"}
+
+// Campos dos quirks na ordem do QuirkNames
+func (quirks *Quirks) fields() []*bool {
+	return []*bool{&quirks.Shift, &quirks.LoadStore, &quirks.LoadStoreX, &quirks.Jump,
+		&quirks.VFReset, &quirks.Clipping, &quirks.DisplayWait, &quirks.MemoryWrap}
+}
+
+// Set liga ou desliga um quirk pelo nome (ver QuirkNames), para mudar um perfil quirk a quirk
+func (quirks *Quirks) Set(name string, on bool) error {
+	for i, field := range quirks.fields() {
+		if QuirkNames[i] == strings.ToLower(name) {
+			*field = on
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown quirk %q (available: %s)", name, strings.Join(QuirkNames, ", "))
+}
+
+// String lista os quirks ligados, ex: "shift, jump", ou "none"
+func (quirks Quirks) String() string {
+	var on []string
+	for i, field := range quirks.fields() {
+		if *field {
+			on = append(on, QuirkNames[i])
+		}
+	}
+	if len(on) == 0 {
+		return "none"
+	}
+	return strings.Join(on, ", ")
+}
+
+// Quirks retorna os quirks ativos
+func (chip_8 *Machine) Quirks() Quirks {
+	return chip_8.quirks
+}
+
+// SetQuirks troca os quirks ativos, pode ser feito a qualquer momento
+func (chip_8 *Machine) SetQuirks(quirks Quirks) {
+	chip_8.quirks = quirks
+}
@@ -0,0 +1,174 @@
+package Chip8
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestShiftQuirk(t *testing.T) {
+	for _, test := range []struct {
+		shift  bool
+		op     uint16
+		v0, vf byte
+	}{
+		// V0 = 0x01, V1 = 0x82
+		{false, 0x8016, 0x41, 0}, // V0 = V1 >> 1
+		{true, 0x8016, 0x00, 1},  // V0 = V0 >> 1
+		{false, 0x801E, 0x04, 1}, // V0 = V1 << 1
+		{true, 0x801E, 0x02, 0},  // V0 = V0 << 1
+	} {
+		chip_8 := newProgram(t, []uint16{0x6001, 0x6182, test.op}, WithQuirks(Quirks{Shift: test.shift}))
+		steps(t, chip_8, 3)
+		if chip_8.Vx[0] != test.v0 || chip_8.Vx[0xF] != test.vf {
+			t.Errorf("%04X with Shift=%v: V0 = %02X, VF = %d, want %02X, %d", test.op, test.shift, chip_8.Vx[0], chip_8.Vx[0xF], test.v0, test.vf)
+		}
+	}
+}
+
+func TestLoadStoreQuirks(t *testing.T) {
+	for _, test := range []struct {
+		quirks Quirks
+		index  uint16
+	}{
+		{Quirks{}, 0x300},
+		{Quirks{LoadStore: true}, 0x303},
+		{Quirks{LoadStoreX: true}, 0x302},
+		{Quirks{LoadStore: true, LoadStoreX: true}, 0x303},
+	} {
+		for _, op := range []uint16{0xF255, 0xF265} {
+			chip_8 := newProgram(t, []uint16{0xA300, op}, WithQuirks(test.quirks))
+			steps(t, chip_8, 2)
+			if chip_8.index != test.index {
+				t.Errorf("%04X with %+v: I = %03X, want %03X", op, test.quirks, chip_8.index, test.index)
+			}
+		}
+	}
+}
+
+func TestJumpQuirk(t *testing.T) {
+	for _, test := range []struct {
+		jump bool
+		pc   uint16
+	}{
+		{false, 0x310}, // NNN + V0
+		{true, 0x320},  // XNN + V3
+	} {
+		chip_8 := newProgram(t, []uint16{0x6010, 0x6320, 0xB300}, WithQuirks(Quirks{Jump: test.jump}))
+		steps(t, chip_8, 3)
+		if chip_8.program_counter != test.pc {
+			t.Errorf("B300 with Jump=%v: PC = %03X, want %03X", test.jump, chip_8.program_counter, test.pc)
+		}
+	}
+}
+
+func TestVFResetQuirk(t *testing.T) {
+	for _, op := range []uint16{0x8011, 0x8012, 0x8013} {
+		for _, reset := range []bool{false, true} {
+			chip_8 := newProgram(t, []uint16{0x6F05, 0x6003, 0x6106, op}, WithQuirks(Quirks{VFReset: reset}))
+			steps(t, chip_8, 4)
+			want := byte(5)
+			if reset {
+				want = 0
+			}
+			if chip_8.Vx[0xF] != want {
+				t.Errorf("%04X with VFReset=%v: VF = %d, want %d", op, reset, chip_8.Vx[0xF], want)
+			}
+		}
+	}
+}
+
+func TestClippingQuirk(t *testing.T) {
+	// Desenha o "0" da font em (62, 0): as colunas 2 e 3 caem fora da tela
+	for _, clipping := range []bool{false, true} {
+		chip_8 := newProgram(t, []uint16{0x603E, 0x6100, 0xA000, 0xD015}, WithQuirks(Quirks{Clipping: clipping}))
+		steps(t, chip_8, 4)
+		if pixel(chip_8, 62, 0) == 0 || pixel(chip_8, 63, 0) == 0 {
+			t.Errorf("Clipping=%v: the visible part of the sprite was not drawn", clipping)
+		}
+		if wrapped := pixel(chip_8, 0, 0) != 0; wrapped == clipping {
+			t.Errorf("Clipping=%v: pixel (0, 0) on = %v", clipping, wrapped)
+		}
+	}
+}
+
+func TestDisplayWaitQuirk(t *testing.T) {
+	chip_8 := newProgram(t, []uint16{0xA000, 0xD015}, WithQuirks(Quirks{DisplayWait: true}))
+	steps(t, chip_8, 2)
+	if chip_8.program_counter != 0x202 || pixel(chip_8, 0, 0) != 0 {
+		t.Fatalf("DXYN drew before the next frame (PC = %03X)", chip_8.program_counter)
+	}
+	chip_8.endFrame()
+	steps(t, chip_8, 1)
+	if chip_8.program_counter != 0x204 || pixel(chip_8, 0, 0) == 0 {
+		t.Errorf("DXYN did not draw after the frame ended (PC = %03X)", chip_8.program_counter)
+	}
+}
+
+func TestMemoryWrapQuirk(t *testing.T) {
+	// FX65 lendo a partir de FFF passa do fim da memoria
+	chip_8 := newProgram(t, []uint16{0xAFFF, 0xF165})
+	steps(t, chip_8, 1)
+	if _, ok := chip_8.Step().(ErrMemoryOutOfBounds); !ok {
+		t.Errorf("FX65 past the end of memory did not fail without MemoryWrap")
+	}
+
+	chip_8 = newProgram(t, []uint16{0xAFFF, 0xF165}, WithQuirks(Quirks{MemoryWrap: true}))
+	steps(t, chip_8, 2)
+	if chip_8.Vx[1] != chip_8.memory[0] {
+		t.Errorf("V1 = %02X, want the first byte of memory %02X", chip_8.Vx[1], chip_8.memory[0])
+	}
+}
+
+func TestQuirksSet(t *testing.T) {
+	quirks, err := QuirksProfile("schip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := quirks.Set("Clipping", false); err != nil {
+		t.Fatal(err)
+	}
+	if err := quirks.Set("loadstorex", true); err != nil {
+		t.Fatal(err)
+	}
+	if want := (Quirks{Shift: true, LoadStoreX: true, Jump: true}); quirks != want {
+		t.Errorf("quirks = %+v, want %+v", quirks, want)
+	}
+	if got := quirks.String(); got != "shift, loadstorex, jump" {
+		t.Errorf("String() = %q", got)
+	}
+	if got := (Quirks{}).String(); got != "none" {
+		t.Errorf("String() of no quirks = %q, want none", got)
+	}
+	if err := quirks.Set("speed", true); err == nil {
+		t.Errorf("Set of an unknown quirk did not fail")
+	}
+}
+
+func TestQuirksProfilesDiffer(t *testing.T) {
+	names := QuirksProfileNames()
+	for i, a := range names {
+		for _, b := range names[i+1:] {
+			if reflect.DeepEqual(QuirksProfiles[a], QuirksProfiles[b]) {
+				t.Errorf("profiles %s and %s are the same", a, b)
+			}
+		}
+	}
+}
+
+func TestDatabaseQuirks(t *testing.T) {
+	for _, test := range []struct {
+		overrides map[string]bool
+		want      Quirks
+	}{
+		{map[string]bool{"memoryIncrementByX": true}, Quirks{LoadStoreX: true, Clipping: true}},
+		{map[string]bool{"memoryLeaveIUnchanged": true}, Quirks{Clipping: true}},
+		{map[string]bool{"shift": true, "jump": true, "logic": true, "vblank": true}, Quirks{Shift: true, LoadStore: true, Jump: true, VFReset: true, Clipping: true, DisplayWait: true}},
+		{map[string]bool{"wrap": true}, Quirks{LoadStore: true}},
+	} {
+		quirks := QuirksProfiles["modern"]
+		applyDatabaseQuirks(&quirks, test.overrides)
+		if quirks != test.want {
+			t.Errorf("applyDatabaseQuirks(%v) = %+v, want %+v", test.overrides, quirks, test.want)
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package Chip8
+
+import (
+	"encoding/binary"
+	"image/color"
+)
+
+// Buffer circular com os ultimos quadros da Machine, usado para voltar no tempo.
+// Apenas o estado mais novo é guardado inteiro, os anteriores são guardados como
+// a diferença (xor + zeros comprimidos) para o estado seguinte.
+type rewindBuffer struct {
+	current []byte   // Estado mais novo, completo
+	deltas  [][]byte // deltas[i] transforma o estado i+1 no estado i (do mais velho para o mais novo)
+	start   int      // Posição do delta mais velho no buffer circular
+	count   int
+}
+
+func newRewindBuffer(frames int) *rewindBuffer {
+	return &rewindBuffer{deltas: make([][]byte, frames)}
+}
+
+// Guarda um novo estado, descartando o mais velho se o buffer estiver cheio
+func (rb *rewindBuffer) push(state []byte) {
+	if rb.current != nil && len(rb.deltas) > 0 {
+		delta := encodeDelta(rb.current, state)
+		if rb.count == len(rb.deltas) {
+			rb.deltas[rb.start] = delta
+			rb.start = (rb.start + 1) % len(rb.deltas)
+		} else {
+			rb.deltas[(rb.start+rb.count)%len(rb.deltas)] = delta
+			rb.count++
+		}
+	}
+	rb.current = state
+}
+
+// Volta um quadro, retornando o estado anterior ao mais novo
+func (rb *rewindBuffer) pop() ([]byte, bool) {
+	if rb.count == 0 {
+		return nil, false
+	}
+	last := (rb.start + rb.count - 1) % len(rb.deltas)
+	previous := applyDelta(rb.current, rb.deltas[last])
+	rb.deltas[last] = nil
+	rb.count--
+	rb.current = previous
+	return previous, true
+}
+
+// Delta entre dois estados de mesmo tamanho: o xor dos dois, com as sequencias de zeros comprimidas.
+// Formato: repetições de (uvarint zeros, uvarint tamanho, bytes do xor)
+func encodeDelta(from, to []byte) []byte {
+	var delta []byte
+	var buf [binary.MaxVarintLen64]byte
+
+	i := 0
+	for i < len(to) {
+		zeros := 0
+		for i < len(to) && from[i] == to[i] {
+			zeros++
+			i++
+		}
+		literal := i
+		for i < len(to) && from[i] != to[i] {
+			i++
+		}
+
+		delta = append(delta, buf[:binary.PutUvarint(buf[:], uint64(zeros))]...)
+		delta = append(delta, buf[:binary.PutUvarint(buf[:], uint64(i-literal))]...)
+		for j := literal; j < i; j++ {
+			delta = append(delta, from[j]^to[j])
+		}
+	}
+	return delta
+}
+
+// Aplica o delta no estado, que funciona nos dois sentidos por ser um xor
+func applyDelta(state, delta []byte) []byte {
+	result := append([]byte(nil), state...)
+
+	i := 0
+	for len(delta) > 0 {
+		zeros, n := binary.Uvarint(delta)
+		delta = delta[n:]
+		size, n := binary.Uvarint(delta)
+		delta = delta[n:]
+
+		i += int(zeros)
+		for j := 0; j < int(size); j++ {
+			result[i+j] ^= delta[j]
+		}
+		i += int(size)
+		delta = delta[size:]
+	}
+	return result
+}
+
+// Rewind volta a Machine um quadro no tempo, retorna false se não houver mais quadros guardados
+func (chip_8 *Machine) Rewind() bool {
+	if chip_8.rewind == nil {
+		return false
+	}
+	state, ok := chip_8.rewind.pop()
+	if !ok {
+		return false
+	}
+	chip_8.restore(unmarshalState(state))
+	return true
+}
+
+// Guarda o quadro atual no buffer de rewind, se ele estiver ligado
+func (chip_8 *Machine) recordRewind() {
+	if chip_8.rewind != nil {
+		chip_8.rewind.push(marshalState(chip_8.snapshot()))
+	}
+}
+
+// Serialização rapida do machineState para o rewind, sem reflection.
+// O encoding/binary do SaveState é lento demais para ser usado a cada quadro.
+func marshalState(state *machineState) []byte {
+	b := make([]byte, 0, len(state.Memory)+len(state.Gfx)+256)
+	b = append(b, byte(state.Opcode>>8), byte(state.Opcode))
+	b = append(b, state.Memory[:]...)
+	b = append(b, byte(state.Mode))
+	b = append(b, state.Vx[:]...)
+	b = append(b, byte(state.Index>>8), byte(state.Index))
+	b = append(b, byte(state.ProgramCounter>>8), byte(state.ProgramCounter))
+	for _, addr := range state.Stack {
+		b = append(b, byte(addr>>8), byte(addr))
+	}
+	b = append(b, byte(state.StackPointer>>8), byte(state.StackPointer))
+	b = append(b, state.DelayTimer, state.SoundTimer)
+	b = append(b, state.Gfx[:]...)
+	b = append(b, boolByte(state.Hires), boolByte(state.Halted))
+	b = append(b, state.RPL[:]...)
+	b = append(b, state.Plane)
+	b = append(b, state.Pattern[:]...)
+	b = append(b, state.Pitch)
+	b = append(b, state.Key[:]...)
+	b = append(b, boolByte(state.Vblank))
+	q := state.Quirks
+	b = append(b, boolByte(q.Shift), boolByte(q.LoadStore), boolByte(q.Jump),
+		boolByte(q.VFReset), boolByte(q.Clipping), boolByte(q.DisplayWait), boolByte(q.MemoryWrap), boolByte(q.LoadStoreX))
+	for _, c := range state.Palette {
+		b = append(b, c.R, c.G, c.B, c.A)
+	}
+	for _, v := range []int32{state.CyclesPerFrame, state.Timing, state.FrameCycle} {
+		b = append(b, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+	}
+	return b
+}
+
+func unmarshalState(b []byte) *machineState {
+	var state machineState
+	u16 := func() uint16 {
+		v := uint16(b[0])<<8 | uint16(b[1])
+		b = b[2:]
+		return v
+	}
+	u8 := func() byte {
+		v := b[0]
+		b = b[1:]
+		return v
+	}
+	i32 := func() int32 {
+		return int32(u16())<<16 | int32(u16())
+	}
+
+	state.Opcode = u16()
+	b = b[copy(state.Memory[:], b):]
+	state.Mode = int32(u8())
+	b = b[copy(state.Vx[:], b):]
+	state.Index = u16()
+	state.ProgramCounter = u16()
+	for i := range state.Stack {
+		state.Stack[i] = u16()
+	}
+	state.StackPointer = u16()
+	state.DelayTimer = u8()
+	state.SoundTimer = u8()
+	b = b[copy(state.Gfx[:], b):]
+	state.Hires = u8() != 0
+	state.Halted = u8() != 0
+	b = b[copy(state.RPL[:], b):]
+	state.Plane = u8()
+	b = b[copy(state.Pattern[:], b):]
+	state.Pitch = u8()
+	b = b[copy(state.Key[:], b):]
+	state.Vblank = u8() != 0
+	state.Quirks = Quirks{
+		Shift:       u8() != 0,
+		LoadStore:   u8() != 0,
+		Jump:        u8() != 0,
+		VFReset:     u8() != 0,
+		Clipping:    u8() != 0,
+		DisplayWait: u8() != 0,
+		MemoryWrap:  u8() != 0,
+		LoadStoreX:  u8() != 0,
+	}
+	for i := range state.Palette {
+		state.Palette[i] = color.RGBA{u8(), u8(), u8(), u8()}
+	}
+	state.CyclesPerFrame = i32()
+	state.Timing = i32()
+	state.FrameCycle = i32()
+	return &state
+}
+
+func boolByte(b bool) byte {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,66 @@
+package Chip8
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestRewindBuffer(t *testing.T) {
+	states := [][]byte{
+		{1, 2, 3, 4, 5, 6},
+		{1, 2, 9, 4, 5, 6},
+		{0, 2, 9, 4, 5, 7},
+		{0, 0, 0, 0, 0, 0},
+	}
+	rb := newRewindBuffer(2)
+	for _, state := range states {
+		rb.push(state)
+	}
+	// Só cabem 2 deltas: o primeiro estado foi descartado
+	for _, want := range [][]byte{states[2], states[1]} {
+		state, ok := rb.pop()
+		if !ok || !bytes.Equal(state, want) {
+			t.Fatalf("pop() = %v, %v, want %v", state, ok, want)
+		}
+	}
+	if _, ok := rb.pop(); ok {
+		t.Errorf("pop() of an empty buffer succeeded")
+	}
+}
+
+func TestMarshalState(t *testing.T) {
+	chip_8 := newProgram(t, stateProgram, WithMode(XOChipMode), WithQuirks(Quirks{Jump: true, MemoryWrap: true}),
+		WithCyclesPerFrame(3), WithTiming(VIPTiming))
+	steps(t, chip_8, 20)
+	state := chip_8.snapshot()
+	if got := unmarshalState(marshalState(state)); *got != *state {
+		t.Errorf("unmarshalState(marshalState(state)) differs from state")
+	}
+}
+
+func TestRewind(t *testing.T) {
+	// Cada quadro (2 instruções) soma 1 no V0
+	chip_8 := newProgram(t, []uint16{0x7001, 0x1200}, WithCyclesPerFrame(2), WithRewind(4))
+	steps(t, chip_8, 20)
+	for _, want := range []byte{9, 8, 7, 6} {
+		if !chip_8.Rewind() {
+			t.Fatalf("Rewind() to V0 = %d failed", want)
+		}
+		if chip_8.Vx[0] != want {
+			t.Fatalf("V0 = %d after rewinding, want %d", chip_8.Vx[0], want)
+		}
+	}
+	if chip_8.Rewind() {
+		t.Errorf("Rewind() went further than the 4 frames kept")
+	}
+
+	// Depois de voltar a Machine continua do estado restaurado
+	steps(t, chip_8, 2)
+	if chip_8.Vx[0] != 7 {
+		t.Errorf("V0 = %d after running again, want 7", chip_8.Vx[0])
+	}
+
+	if newProgram(t, []uint16{0x1200}).Rewind() {
+		t.Errorf("Rewind() without WithRewind succeeded")
+	}
+}
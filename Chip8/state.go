@@ -0,0 +1,225 @@
+package Chip8
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha1"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image/color"
+	"io"
+	"os"
+)
+
+// Formato do save state:
+//
+//	"XP8S"       - 4 bytes, identifica o arquivo
+//	versão       - uint16 big endian
+//	hash da ROM  - 20 bytes, SHA-1 da ROM carregada quando o estado foi salvo
+//	estado       - machineState em big endian, comprimido com gzip
+const stateMagic = "XP8S"
+
+// Versão atual do formato, deve ser incrementada sempre que o machineState mudar
+const StateVersion uint16 = 4
+
+var (
+	ErrNotAState        = errors.New("not an XP-8 save state")
+	ErrStateROMMismatch = errors.New("save state belongs to a different ROM")
+)
+
+// ErrStateVersion é retornado quando o save state foi criado por outra versão do XP-8
+type ErrStateVersion struct {
+	Version uint16
+}
+
+func (err ErrStateVersion) Error() string {
+	return fmt.Sprintf("save state version %d is not supported (expected %d)", err.Version, StateVersion)
+}
+
+// Tudo que é salvo em um save state. Apenas tipos de tamanho fixo, para usar o encoding/binary.
+// A ROM, os símbolos, o banco de dados e a politica de falhas não fazem parte do estado: eles vêm
+// do LoadROM e das opções da Machine que carrega o estado.
+type machineState struct {
+	Opcode         uint16
+	Memory         [65536]byte
+	Mode           int32
+	Vx             [16]byte
+	Index          uint16
+	ProgramCounter uint16
+	Stack          [16]uint16
+	StackPointer   uint16
+	DelayTimer     byte
+	SoundTimer     byte
+	Gfx            [128 * 64]byte
+	Hires          bool
+	Halted         bool
+	RPL            [16]byte
+	Plane          byte
+	Pattern        [16]byte
+	Pitch          byte
+	Key            [16]byte
+	Vblank         bool
+	Quirks         Quirks
+	Palette        [4]color.RGBA
+	CyclesPerFrame int32
+	Timing         int32
+	FrameCycle     int32
+}
+
+// SaveState escreve um snapshot completo da Machine no writer
+func (chip_8 *Machine) SaveState(w io.Writer) error {
+	header := make([]byte, 0, 4+2+sha1.Size)
+	header = append(header, stateMagic...)
+	header = append(header, byte(StateVersion>>8), byte(StateVersion))
+	hash := chip_8.romHash()
+	header = append(header, hash[:]...)
+
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+
+	zw := gzip.NewWriter(w)
+	if err := binary.Write(zw, binary.BigEndian, chip_8.snapshot()); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// LoadState restaura um snapshot criado pelo SaveState.
+// O estado precisa ter sido salvo com a mesma ROM e a mesma versão do formato.
+func (chip_8 *Machine) LoadState(r io.Reader) error {
+	header := make([]byte, 4+2+sha1.Size)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return ErrNotAState
+	}
+	if string(header[:4]) != stateMagic {
+		return ErrNotAState
+	}
+	if version := binary.BigEndian.Uint16(header[4:6]); version != StateVersion {
+		return ErrStateVersion{Version: version}
+	}
+	hash := chip_8.romHash()
+	if !bytes.Equal(header[6:], hash[:]) {
+		return ErrStateROMMismatch
+	}
+
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return fmt.Errorf("corrupted save state: %v", err)
+	}
+	var state machineState
+	if err := binary.Read(zr, binary.BigEndian, &state); err != nil {
+		return fmt.Errorf("corrupted save state: %v", err)
+	}
+
+	chip_8.restore(&state)
+	return nil
+}
+
+// SaveStateFile salva o estado no arquivo path
+func (chip_8 *Machine) SaveStateFile(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	if err := chip_8.SaveState(w); err != nil {
+		f.Close()
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// LoadStateFile carrega o estado salvo no arquivo path
+func (chip_8 *Machine) LoadStateFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return chip_8.LoadState(bufio.NewReader(f))
+}
+
+// Arquivo usado pelos hotkeys de save state, por padrão o caminho da ROM + ".state"
+func (chip_8 *Machine) statePath() string {
+	if chip_8.stateFile != "" {
+		return chip_8.stateFile
+	}
+	if chip_8.romPath != "" {
+		return chip_8.romPath + ".state"
+	}
+	return "xp8.state"
+}
+
+// SHA-1 da ROM carregada
+func (chip_8 *Machine) romHash() [sha1.Size]byte {
+	return sha1.Sum(chip_8.rom)
+}
+
+func (chip_8 *Machine) snapshot() *machineState {
+	return &machineState{
+		Opcode:         chip_8.opcode,
+		Memory:         chip_8.memory,
+		Mode:           int32(chip_8.mode),
+		Vx:             chip_8.Vx,
+		Index:          chip_8.index,
+		ProgramCounter: chip_8.program_counter,
+		Stack:          chip_8.stack,
+		StackPointer:   chip_8.stack_pointer,
+		DelayTimer:     chip_8.DelayTimer,
+		SoundTimer:     chip_8.SoundTimer,
+		Gfx:            chip_8.gfx,
+		Hires:          chip_8.hires,
+		Halted:         chip_8.halted,
+		RPL:            chip_8.rpl,
+		Plane:          chip_8.plane,
+		Pattern:        chip_8.pattern,
+		Pitch:          chip_8.pitch,
+		Key:            chip_8.key,
+		Vblank:         chip_8.vblank,
+		Quirks:         chip_8.quirks,
+		Palette:        chip_8.palette,
+		CyclesPerFrame: int32(chip_8.cyclesPerFrame),
+		Timing:         int32(chip_8.timing),
+		FrameCycle:     int32(chip_8.frameCycle),
+	}
+}
+
+func (chip_8 *Machine) restore(state *machineState) {
+	chip_8.opcode = state.Opcode
+	chip_8.memory = state.Memory
+	chip_8.mode = Mode(state.Mode)
+	chip_8.Vx = state.Vx
+	chip_8.index = state.Index
+	chip_8.program_counter = state.ProgramCounter
+	chip_8.stack = state.Stack
+	chip_8.stack_pointer = state.StackPointer
+	chip_8.DelayTimer = state.DelayTimer
+	chip_8.SoundTimer = state.SoundTimer
+	chip_8.gfx = state.Gfx
+	chip_8.hires = state.Hires
+	chip_8.halted = state.Halted
+	chip_8.rpl = state.RPL
+	chip_8.plane = state.Plane
+	chip_8.pattern = state.Pattern
+	chip_8.pitch = state.Pitch
+	chip_8.key = state.Key
+	chip_8.vblank = state.Vblank
+	chip_8.quirks = state.Quirks
+	chip_8.palette = state.Palette
+	if state.CyclesPerFrame > 0 {
+		chip_8.cyclesPerFrame = int(state.CyclesPerFrame)
+	}
+	chip_8.timing = Timing(state.Timing)
+	chip_8.frameCycle = int(state.FrameCycle)
+
+	chip_8.drawFlag = true // A tela precisa ser desenhada de novo
+	chip_8.screenChanged = true
+	chip_8.patternChanged()
+}
@@ -0,0 +1,105 @@
+package Chip8
+
+import (
+	"bytes"
+	"image/color"
+	"path/filepath"
+	"testing"
+)
+
+// Programa que muda registradores, tela, pilha e timers a cada quadro
+var stateProgram = []uint16{
+	0x6005, 0xF015, 0xA000, 0xD015, // V0 = 5, DT = 5, I = font 0, draw
+	0x2210, 0x7001, 0x120A, 0x0000, // call, V0++, loop
+	0x7101, 0x00EE, // V1++, return
+}
+
+func TestSaveStateRoundTrip(t *testing.T) {
+	palette := DefaultPalette
+	palette[1] = color.RGBA{0x12, 0x34, 0x56, 0xFF}
+	chip_8 := newProgram(t, stateProgram, WithMode(XOChipMode), WithQuirks(Quirks{Shift: true, LoadStoreX: true}),
+		WithPalette(palette), WithCyclesPerFrame(7), WithTiming(VIPTiming))
+	steps(t, chip_8, 25)
+
+	var saved bytes.Buffer
+	if err := chip_8.SaveState(&saved); err != nil {
+		t.Fatal(err)
+	}
+	want := *chip_8.snapshot()
+
+	// Outra Machine, com outras configurações, carrega o estado inteiro
+	other := newProgram(t, stateProgram)
+	if err := other.LoadState(bytes.NewReader(saved.Bytes())); err != nil {
+		t.Fatal(err)
+	}
+	if got := *other.snapshot(); got != want {
+		t.Errorf("loaded state differs from the saved one")
+	}
+	if other.Palette() != palette || other.CyclesPerFrame() != 7 || other.Timing() != VIPTiming || other.Mode() != XOChipMode {
+		t.Errorf("palette = %v, cycles per frame = %d, timing = %s, mode = %s", other.Palette(), other.CyclesPerFrame(), other.Timing(), other.Mode())
+	}
+
+	// As duas continuam iguais
+	steps(t, chip_8, 10)
+	steps(t, other, 10)
+	if *chip_8.snapshot() != *other.snapshot() {
+		t.Errorf("the machines diverged after loading the state")
+	}
+}
+
+func TestSaveStateFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "game.state")
+	chip_8 := newProgram(t, stateProgram)
+	steps(t, chip_8, 6)
+	if err := chip_8.SaveStateFile(path); err != nil {
+		t.Fatal(err)
+	}
+	v0 := chip_8.Vx[0]
+	steps(t, chip_8, 6)
+	if err := chip_8.LoadStateFile(path); err != nil {
+		t.Fatal(err)
+	}
+	if chip_8.Vx[0] != v0 || chip_8.program_counter != 0x212 || chip_8.stack_pointer != 1 {
+		t.Errorf("V0 = %d, PC = %03X, SP = %d after loading, want %d, 212, 1", chip_8.Vx[0], chip_8.program_counter, chip_8.stack_pointer, v0)
+	}
+}
+
+func TestLoadStateErrors(t *testing.T) {
+	chip_8 := newProgram(t, stateProgram)
+	var buf bytes.Buffer
+	if err := chip_8.SaveState(&buf); err != nil {
+		t.Fatal(err)
+	}
+	saved := buf.Bytes()
+
+	// Troca a versão do cabeçalho
+	withVersion := func(version uint16) []byte {
+		data := append([]byte(nil), saved...)
+		data[4], data[5] = byte(version>>8), byte(version)
+		return data
+	}
+
+	for _, version := range []uint16{1, 2, StateVersion - 1, StateVersion + 1} {
+		err, ok := chip_8.LoadState(bytes.NewReader(withVersion(version))).(ErrStateVersion)
+		if !ok || err.Version != version {
+			t.Errorf("LoadState of a version %d state = %v, want ErrStateVersion", version, err)
+		}
+	}
+	for _, data := range [][]byte{nil, []byte("XP8"), []byte("PK\x03\x04 not a state at all......")} {
+		if err := chip_8.LoadState(bytes.NewReader(data)); err != ErrNotAState {
+			t.Errorf("LoadState(%q) = %v, want ErrNotAState", data, err)
+		}
+	}
+	if err := chip_8.LoadState(bytes.NewReader(saved[:len(saved)/2])); err == nil {
+		t.Errorf("LoadState of a truncated state did not fail")
+	}
+
+	other := newProgram(t, []uint16{0x1200})
+	if err := other.LoadState(bytes.NewReader(saved)); err != ErrStateROMMismatch {
+		t.Errorf("LoadState with another ROM = %v, want ErrStateROMMismatch", err)
+	}
+	// A Machine não muda quando o estado é recusado
+	if other.program_counter != 0x200 || other.memory[0x200] != 0x12 {
+		t.Errorf("a refused state changed the machine")
+	}
+}
@@ -0,0 +1,100 @@
+package Chip8
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+)
+
+// SymbolMap liga os endereços de uma ROM com os labels e as linhas do código fonte que a gerou.
+// É salvo em JSON ao lado da ROM e usado pelos debuggers.
+type SymbolMap struct {
+	Labels map[string]uint16 `json:"labels"`
+	Lines  []SourceLine      `json:"lines"`
+}
+
+// SourceLine é a linha do código fonte que gerou a instrução no endereço Addr
+type SourceLine struct {
+	File string `json:"file"`
+	Line int    `json:"line"`
+	Addr uint16 `json:"addr"`
+}
+
+// LoadSymbolMap lê um symbol map salvo pelo Save
+func LoadSymbolMap(path string) (*SymbolMap, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var symbols SymbolMap
+	if err := json.Unmarshal(data, &symbols); err != nil {
+		return nil, err
+	}
+	return &symbols, nil
+}
+
+// Save escreve o symbol map em JSON no path
+func (symbols *SymbolMap) Save(path string) error {
+	data, err := json.MarshalIndent(symbols, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0644)
+}
+
+// AddrForLine retorna o endereço da instrução gerada pela linha, ou pela proxima linha do mesmo arquivo que gerou código.
+// O arquivo é comparado pelo caminho completo ou, se não bater, pelo nome.
+func (symbols *SymbolMap) AddrForLine(file string, line int) (uint16, int, bool) {
+	var best *SourceLine
+	for i := range symbols.Lines {
+		l := &symbols.Lines[i]
+		if !sameFile(l.File, file) || l.Line < line {
+			continue
+		}
+		if best == nil || l.Line < best.Line || (l.Line == best.Line && l.Addr < best.Addr) {
+			best = l
+		}
+	}
+	if best == nil {
+		return 0, 0, false
+	}
+	return best.Addr, best.Line, true
+}
+
+// LineForAddr retorna a linha do código fonte que gerou o endereço
+func (symbols *SymbolMap) LineForAddr(addr uint16) (SourceLine, bool) {
+	for _, l := range symbols.Lines {
+		if l.Addr == addr {
+			return l, true
+		}
+	}
+	return SourceLine{}, false
+}
+
+// LabelForAddr retorna o label do endereço, escolhendo o primeiro em ordem alfabética se houver vários
+func (symbols *SymbolMap) LabelForAddr(addr uint16) (string, bool) {
+	var names []string
+	for name, a := range symbols.Labels {
+		if a == addr {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return "", false
+	}
+	sort.Strings(names)
+	return names[0], true
+}
+
+func sameFile(a, b string) bool {
+	if a == b {
+		return true
+	}
+	absA, errA := filepath.Abs(a)
+	absB, errB := filepath.Abs(b)
+	if errA == nil && errB == nil && absA == absB {
+		return true
+	}
+	return filepath.Base(a) == filepath.Base(b)
+}
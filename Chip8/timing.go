@@ -0,0 +1,140 @@
+package Chip8
+
+import "fmt"
+
+// Timing é o modelo de tempo que divide as instruções em quadros de 60Hz
+type Timing int
+
+const (
+	FrameTiming Timing = iota // cyclesPerFrame instruções por quadro, todas com o mesmo custo
+	VIPTiming                 // Cada instrução custa os ciclos de máquina que ela levava no COSMAC VIP
+)
+
+func (timing Timing) String() string {
+	switch timing {
+	case FrameTiming:
+		return "frames"
+	case VIPTiming:
+		return "vip"
+	}
+	return fmt.Sprintf("Timing(%d)", int(timing))
+}
+
+// ParseTiming converte o nome de um modelo de tempo ("frames", "vip") em Timing
+func ParseTiming(name string) (Timing, error) {
+	for _, timing := range []Timing{FrameTiming, VIPTiming} {
+		if timing.String() == name {
+			return timing, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown timing %q (available: frames, vip)", name)
+}
+
+// O RCA 1802 do COSMAC VIP roda a 1,76064MHz e cada ciclo de máquina leva 8 pulsos de clock,
+// então um quadro de 60Hz tem 3668 ciclos de máquina
+const vipFrameCycles = 1760640 / 8 / refreshRate
+
+// Ciclos de cada quadro que o interpretador não usa: o DMA do CDP1861 rouba 8 ciclos
+// por linha nas 128 linhas da tela e a rotina de interrupção (timers) leva cerca de 30
+const vipInterruptCycles = 128*8 + 30
+
+// Timing retorna o modelo de tempo atual
+func (chip_8 *Machine) Timing() Timing {
+	return chip_8.timing
+}
+
+// SetTiming troca o modelo de tempo, pode ser feito a qualquer momento.
+// No VIPTiming o clock (SetClockSpeed e SetCyclesPerFrame) é ignorado.
+func (chip_8 *Machine) SetTiming(timing Timing) {
+	chip_8.timing = timing
+	chip_8.frameCycle = 0
+}
+
+// Quanto cabe em um quadro: instruções no FrameTiming, ciclos de máquina no VIPTiming
+func (chip_8 *Machine) frameBudget() int {
+	if chip_8.timing == VIPTiming {
+		return vipFrameCycles - vipInterruptCycles
+	}
+	return chip_8.cyclesPerFrame
+}
+
+// Custo da instrução no program counter, calculado antes dela executar
+func (chip_8 *Machine) instructionCost() int {
+	if chip_8.timing != VIPTiming {
+		return 1
+	}
+	return chip_8.vipCycles(chip_8.nextOpcode())
+}
+
+// Ciclos de máquina que o interpretador original do COSMAC VIP leva para executar opcode.
+// Os valores são aproximados a partir dos tempos medidos no VIP (busca e decodificação
+// incluidas); as instruções que dependem dos dados (DXYN, FX33, FX55, FX65) usam um
+// modelo simples do laço que o interpretador executa.
+func (chip_8 *Machine) vipCycles(opcode uint16) int {
+	x := (opcode & 0x0F00) >> 8
+	switch opcode & 0xF000 {
+	case 0x0000:
+		switch opcode {
+		case 0x00E0:
+			return 24
+		case 0x00EE:
+			return 10
+		}
+		return 23 // 0NNN, sub-rotina em linguagem de máquina, o custo depende dela
+	case 0x1000:
+		return 12
+	case 0x2000:
+		return 26
+	case 0x3000, 0x4000:
+		return 10
+	case 0x5000, 0x9000:
+		return 14
+	case 0x6000:
+		return 6
+	case 0x7000:
+		return 10
+	case 0x8000:
+		return 44
+	case 0xA000:
+		return 12
+	case 0xB000:
+		return 22
+	case 0xC000:
+		return 36
+	case 0xD000:
+		// O interpretador espera o vblank antes de desenhar, o resto do quadro é gasto esperando
+		if chip_8.quirks.DisplayWait && !chip_8.vblank {
+			if rest := chip_8.frameBudget() - chip_8.frameCycle; rest > 0 {
+				return rest
+			}
+			return 1
+		}
+		// Cada linha do sprite é deslocada bit a bit até a coluna X e, fora do alinhamento
+		// de 8 pixels, escrita em dois bytes da tela
+		rows := int(opcode & 0x000F)
+		shift := int(chip_8.Vx[x] & 7)
+		perRow := 18 + 4*shift
+		if shift != 0 {
+			perRow += 8
+		}
+		return 26 + rows*perRow
+	case 0xE000:
+		return 14
+	case 0xF000:
+		switch opcode & 0x00FF {
+		case 0x0007, 0x000A, 0x0015, 0x0018:
+			return 10
+		case 0x001E:
+			return 19
+		case 0x0029:
+			return 20
+		case 0x0033:
+			// Os digitos são calculados por subtrações repetidas
+			v := chip_8.Vx[x]
+			return 40 + 16*int(v/100+v/10%10+v%10)
+		case 0x0055, 0x0065:
+			return 14 + 7*int(x+1)
+		}
+	}
+	return 10
+}
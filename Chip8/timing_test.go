@@ -0,0 +1,117 @@
+package Chip8
+
+import "testing"
+
+func TestTimersIndependentOfClock(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		opt    Option
+		cycles int
+	}{
+		{"1 cycle per frame", WithCyclesPerFrame(1), 1},
+		{"500Hz", WithClockSpeed(500), 8},
+		{"700Hz", WithClockSpeed(700), 12},
+		{"1000 cycles per frame", WithCyclesPerFrame(1000), 1000},
+	} {
+		chip_8 := newProgram(t, []uint16{0x7001, 0x1200}, test.opt)
+		if chip_8.CyclesPerFrame() != test.cycles {
+			t.Errorf("%s: cycles per frame = %d, want %d", test.name, chip_8.CyclesPerFrame(), test.cycles)
+			continue
+		}
+		chip_8.DelayTimer, chip_8.SoundTimer = 60, 30
+
+		// Os timers só andam no fim do quadro, não a cada instrução
+		steps(t, chip_8, test.cycles-1)
+		if chip_8.DelayTimer != 60 {
+			t.Errorf("%s: DT = %d before the end of the first frame, want 60", test.name, chip_8.DelayTimer)
+		}
+		steps(t, chip_8, 1)
+		if chip_8.DelayTimer != 59 || chip_8.SoundTimer != 29 {
+			t.Errorf("%s: DT = %d, ST = %d after one frame, want 59, 29", test.name, chip_8.DelayTimer, chip_8.SoundTimer)
+		}
+
+		// Qualquer que seja o clock, 20 quadros tiram 20 dos timers
+		for i := 0; i < 19; i++ {
+			chip_8.frame()
+		}
+		if chip_8.DelayTimer != 40 || chip_8.SoundTimer != 10 {
+			t.Errorf("%s: DT = %d, ST = %d after 20 frames, want 40, 10", test.name, chip_8.DelayTimer, chip_8.SoundTimer)
+		}
+		// Enquanto isso o laço rodou cyclesPerFrame instruções por quadro
+		if want := 20 * test.cycles / 2 % 256; int(chip_8.Vx[0]) != want {
+			t.Errorf("%s: V0 = %d after 20 frames, want %d", test.name, chip_8.Vx[0], want)
+		}
+
+		// O timer para no 0
+		for i := 0; i < 50; i++ {
+			chip_8.frame()
+		}
+		if chip_8.DelayTimer != 0 || chip_8.SoundTimer != 0 {
+			t.Errorf("%s: DT = %d, ST = %d, want the timers stopped at 0", test.name, chip_8.DelayTimer, chip_8.SoundTimer)
+		}
+	}
+}
+
+func TestVIPCycles(t *testing.T) {
+	chip_8 := newProgram(t, []uint16{0x1200}, WithTiming(VIPTiming))
+	chip_8.Vx[0], chip_8.Vx[1], chip_8.Vx[2] = 8, 3, 255
+	for _, test := range []struct {
+		opcode uint16
+		want   int
+	}{
+		{0x00E0, 24},
+		{0x00EE, 10},
+		{0x1234, 12},
+		{0x2234, 26},
+		{0x6012, 6},
+		{0x7012, 10},
+		{0x8014, 44},
+		{0xA123, 12},
+		{0xD005, 26 + 5*18},        // Alinhado em 8 pixels
+		{0xD105, 26 + 5*(18+12+8)}, // Deslocado 3 bits, escreve dois bytes por linha
+		{0xF01E, 19},
+		{0xF233, 40 + 16*(2+5+5)}, // 255
+		{0xF355, 14 + 7*4},
+		{0xF065, 14 + 7*1},
+	} {
+		if got := chip_8.vipCycles(test.opcode); got != test.want {
+			t.Errorf("vipCycles(%04X) = %d, want %d", test.opcode, got, test.want)
+		}
+	}
+}
+
+func TestVIPFrame(t *testing.T) {
+	// Cada volta do laço custa 10 + 12 ciclos
+	chip_8 := newProgram(t, []uint16{0x7001, 0x1200}, WithTiming(VIPTiming), WithCyclesPerFrame(1000))
+	chip_8.DelayTimer = 10
+	chip_8.frame()
+	// 118 voltas (2596 ciclos) e mais um 7001 cabem nos 2614 ciclos do quadro, o 1200 seguinte o termina
+	if chip_8.Vx[0] != 119 || chip_8.program_counter != 0x200 || chip_8.DelayTimer != 9 {
+		t.Errorf("V0 = %d, PC = %03X, DT = %d after one frame, want 119, 200, 9", chip_8.Vx[0], chip_8.program_counter, chip_8.DelayTimer)
+	}
+	// Os 4 ciclos que passaram ficam para o proximo quadro
+	if chip_8.frameCycle != 4 {
+		t.Errorf("frame cycle = %d, want 4", chip_8.frameCycle)
+	}
+}
+
+func TestVIPDisplayWait(t *testing.T) {
+	// I = font 0, V0 = 1, desenha em 1,1
+	chip_8 := newProgram(t, []uint16{0xA000, 0x6001, 0xD005, 0x1206}, WithTiming(VIPTiming), WithQuirks(Quirks{DisplayWait: true}))
+	chip_8.DelayTimer = 10
+	steps(t, chip_8, 2)
+
+	// O DXYN espera o vblank: o resto do quadro passa sem desenhar
+	if ended, err := chip_8.step(); err != nil || !ended {
+		t.Fatalf("step() = %v, %v, want the DXYN to end the frame", ended, err)
+	}
+	if chip_8.program_counter != 0x204 || chip_8.DelayTimer != 9 || pixel(chip_8, 1, 1) != 0 {
+		t.Errorf("PC = %03X, DT = %d after waiting, want 204, 9 and nothing drawn", chip_8.program_counter, chip_8.DelayTimer)
+	}
+
+	// No novo quadro ele desenha e custa as linhas do sprite
+	steps(t, chip_8, 1)
+	if chip_8.program_counter != 0x206 || pixel(chip_8, 1, 1) == 0 || chip_8.frameCycle != 26+5*(18+4+8) {
+		t.Errorf("PC = %03X, frame cycle = %d after drawing, want 206, %d", chip_8.program_counter, chip_8.frameCycle, 26+5*(18+4+8))
+	}
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/mellotonio/go-chip8/Chip8/Assembler"
+)
+
+// xp8 asm [-o rom.ch8] [-sym rom.ch8.sym.json] source.asm
+func runAsm(args []string) error {
+	flags := newFlagSet("asm", "source.asm")
+	out := flags.String("o", "", "output ROM (default: the source name with the .ch8 extension)")
+	sym := flags.String("sym", "", "also write a symbol map for the debuggers (use <rom>.sym.json to have xp8 dap find it)")
+	if err := parseFlags(flags, args, 1); err != nil {
+		return err
+	}
+
+	source := flags.Arg(0)
+	program, err := Assembler.AssembleFile(source)
+	if err != nil {
+		return err
+	}
+
+	if *out == "" {
+		*out = strings.TrimSuffix(source, filepath.Ext(source)) + ".ch8"
+	}
+	if err := ioutil.WriteFile(*out, program.ROM, 0644); err != nil {
+		return err
+	}
+	if *sym != "" {
+		if err := program.Symbols.Save(*sym); err != nil {
+			return err
+		}
+	}
+	fmt.Printf("%s: %d bytes\n", *out, len(program.ROM))
+	return nil
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"github.com/faiface/pixel/pixelgl"
+	"github.com/mellotonio/go-chip8/Chip8"
+	"github.com/mellotonio/go-chip8/Chip8/Audio"
+	"github.com/mellotonio/go-chip8/Chip8/DAP"
+	"github.com/mellotonio/go-chip8/Chip8/Display"
+)
+
+// xp8 dap [-addr localhost:4711]
+func runDAP(args []string) error {
+	flags := newFlagSet("dap", "")
+	addr := flags.String("addr", "localhost:4711", "address to serve the Debug Adapter Protocol on; the editor chooses the ROM")
+	if err := parseFlags(flags, args, 0); err != nil {
+		return err
+	}
+
+	var err error
+	pixelgl.Run(func() { err = serveDAP(*addr) })
+	return err
+}
+
+// Modo DAP: o editor escolhe a ROM no launch, a janela só abre depois disso
+func serveDAP(addr string) error {
+	var beeper *Audio.Beeper
+
+	launch := func(args DAP.LaunchArgs) (*Chip8.Machine, error) {
+		window, err := Display.NewWindow()
+		if err != nil {
+			return nil, err
+		}
+		beeper = Audio.NewBeeper("assets/beep.mp3")
+
+		chip_8 := Chip8.New(
+			Chip8.WithRenderer(window),
+			Chip8.WithInputSource(window),
+			Chip8.WithAudioSink(beeper),
+			Chip8.WithRewind(rewindFrames),
+		)
+		if err := loadROM(chip_8, args.Program); err != nil {
+			return nil, err
+		}
+		go beeper.Run()
+		return chip_8, nil
+	}
+
+	err := DAP.NewServer(launch).ListenAndServe(addr)
+	if beeper != nil {
+		beeper.Close()
+	}
+	return err
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+
+	"github.com/mellotonio/go-chip8/Chip8"
+)
+
+// xp8 disasm [-mode chip8|schip|xochip] rom.ch8
+func runDisasm(args []string) error {
+	flags := newFlagSet("disasm", "rom.ch8")
+	modeName := flags.String("mode", "xochip", "platform used to decode the instructions (chip8, schip, xochip)")
+	if err := parseFlags(flags, args, 1); err != nil {
+		return err
+	}
+
+	mode, err := Chip8.ParseMode(*modeName)
+	if err != nil {
+		return usageError{err.Error()}
+	}
+	rom, err := ioutil.ReadFile(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	return Chip8.NewListing(rom, mode).Print(os.Stdout)
+}
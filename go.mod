@@ -1,8 +1,9 @@
 module github.com/mellotonio/go-chip8
 
-go 1.15
+go 1.16
 
 require (
+	github.com/BurntSushi/toml v1.2.0
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/faiface/beep v1.0.2
 	github.com/faiface/pixel v0.10.0
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"crypto/sha1"
+	"fmt"
+
+	"github.com/mellotonio/go-chip8/Chip8"
+)
+
+// xp8 info rom
+func runInfo(args []string) error {
+	flags := newFlagSet("info", "rom")
+	if err := parseFlags(flags, args, 1); err != nil {
+		return err
+	}
+
+	path := flags.Arg(0)
+	chip_8 := Chip8.New()
+	if err := loadROM(chip_8, path); err != nil {
+		return err
+	}
+
+	rom := chip_8.ROM()
+	fmt.Printf("file:     %s\n", path)
+	fmt.Printf("size:     %d bytes\n", len(rom))
+	fmt.Printf("sha1:     %x\n", sha1.Sum(rom))
+	fmt.Printf("platform: %s\n", chip_8.Mode())
+	fmt.Printf("quirks:   %s\n", chip_8.Quirks())
+	if info := chip_8.Info(); info != nil {
+		fmt.Println()
+		printROMInfo(info)
+	} else {
+		fmt.Println("\nnot in the ROM database")
+	}
+	return nil
+}
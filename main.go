@@ -1,51 +1,137 @@
 package main
 
 import (
+	"errors"
+	"flag"
 	"fmt"
 	"os"
+	"sort"
+	"strings"
 
-	"github.com/faiface/pixel/pixelgl"
 	"github.com/mellotonio/go-chip8/Chip8"
+	_ "github.com/mellotonio/go-chip8/Chip8/Octo" // LoadROM compila arquivos .8o e cartuchos .gif
 )
 
-func main() {
-	fmt.Printf("WELCOME to XP-8\n\nPONG COMMANDS:\nPlayer1: Keyboard [1] [Q]\nPlayer2: Keyboard [4] [R]\n\nSPACE INVADERS: Keyboard [Q] [W] [E]\n1. Pong\n2. Space Invaders\n3. Tetris\n\n")
-	pixelgl.Run(mainFunc) // Pixelgl precisa do controle da função principal
-
+// Subcomandos do xp8, cada um recebe os argumentos depois do nome dele
+var commands = map[string]func(args []string) error{
+	"run":    runROM,
+	"info":   runInfo,
+	"disasm": runDisasm,
+	"asm":    runAsm,
+	"test":   runTest,
+	"dap":    runDAP,
 }
 
-func mainFunc() {
-	var number int
+const usage = `usage: xp8 <command> [flags] [arguments]
 
-	var pathToROM string
+commands:
+  run     run a ROM, Octo source (.8o) or Octo cartridge (.gif) in a window
+  info    show what is known about a ROM
+  disasm  disassemble a ROM
+  asm     assemble a source file into a ROM
+  test    run a ROM without a window and print the final screen
+  dap     serve the Debug Adapter Protocol for editors
 
-	_, err := fmt.Scanf("%d", &number)
+Run "xp8 <command> -h" for the flags of a command.
+`
 
-	if number == 1 {
-		pathToROM = "./Chip8/roms/pong.ch8"
-	} else if number == 2 {
-		pathToROM = "./Chip8/roms/Space Invaders [David Winter].ch8"
-	} else if number == 3 {
-		pathToROM = "./Chip8/roms/tetris.ch8"
-	}
+// usageError é um erro nos argumentos da linha de comando, sai com o código 2
+type usageError struct {
+	msg string
+}
 
-	// chip_8, err := Chip8.Start(pathToROM)
+func (e usageError) Error() string {
+	return e.msg
+}
 
-	chip_8, err := Chip8.Start(pathToROM)
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	name := os.Args[1]
+	if name == "-h" || name == "-help" || name == "--help" || name == "help" {
+		fmt.Print(usage)
+		return
+	}
+	command, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "xp8: unknown command %q\n\n%s", name, usage)
+		os.Exit(2)
+	}
 
-	if err != nil {
-		fmt.Printf("\nerror creating a new chip-8 VM: %v\n", err)
+	err := command(os.Args[2:])
+	switch {
+	case err == nil:
+	case errors.Is(err, flag.ErrHelp):
+	case errors.As(err, new(usageError)):
+		fmt.Fprintf(os.Stderr, "xp8 %s: %v\n", name, err)
+		os.Exit(2)
+	default:
+		fmt.Fprintf(os.Stderr, "xp8 %s: %v\n", name, err)
 		os.Exit(1)
 	}
+}
 
-	go chip_8.Run()
-	go chip_8.ManageAudio()
+// Cria o FlagSet de um subcomando. Erros nas flags viram usageError, o flag já mostrou a mensagem.
+func newFlagSet(name, args string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: xp8 %s [flags] %s\n", name, args)
+		flags.PrintDefaults()
+	}
+	return flags
+}
 
-	if err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+// Lê as flags e confere se sobrou o número certo de argumentos
+func parseFlags(flags *flag.FlagSet, args []string, nargs int) error {
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return usageError{err.Error()}
+	}
+	if flags.NArg() != nargs {
+		flags.Usage()
+		return usageError{fmt.Sprintf("expected %d argument(s), got %d", nargs, flags.NArg())}
+	}
+	return nil
+}
+
+// Carrega a ROM de path, explicando os erros mais comuns
+func loadROM(chip_8 *Chip8.Machine, path string) error {
+	err := chip_8.LoadROM(path)
+	if os.IsNotExist(err) {
+		return fmt.Errorf("ROM %q not found", path)
+	}
+	return err
+}
+
+// Mostra o que o banco de dados sabe sobre a ROM carregada
+func printROMInfo(info *Chip8.ROMInfo) {
+	if info == nil {
+		return
+	}
+	fmt.Println(info.Title)
+	if len(info.Authors) > 0 {
+		fmt.Printf("by %s", strings.Join(info.Authors, ", "))
+		if info.Release != "" {
+			fmt.Printf(" (%s)", info.Release)
+		}
+		fmt.Println()
+	}
+	if info.Description != "" {
+		fmt.Println(info.Description)
+	}
+	if len(info.Keys) > 0 {
+		actions := make([]string, 0, len(info.Keys))
+		for action := range info.Keys {
+			actions = append(actions, action)
+		}
+		sort.Strings(actions)
+		for i, action := range actions {
+			actions[i] = fmt.Sprintf("%s=%X", action, info.Keys[action])
+		}
+		fmt.Printf("keys: %s\n", strings.Join(actions, " "))
 	}
-	//	ticker := time.NewTicker(time.Second / 60)
-	//		defer ticker.Stop()
-	<-chip_8.Shutdown
 }
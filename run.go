@@ -0,0 +1,353 @@
+package main
+
+import (
+	"crypto/sha1"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/faiface/pixel/pixelgl"
+	"github.com/mellotonio/go-chip8/Chip8"
+	"github.com/mellotonio/go-chip8/Chip8/Audio"
+	"github.com/mellotonio/go-chip8/Chip8/Config"
+	"github.com/mellotonio/go-chip8/Chip8/Console"
+	"github.com/mellotonio/go-chip8/Chip8/Display"
+	"github.com/mellotonio/go-chip8/Chip8/GDB"
+	"github.com/mellotonio/go-chip8/Chip8/VIP"
+)
+
+// Quantos quadros são guardados para o rewind (10 segundos a 60 quadros por segundo)
+const rewindFrames = 10 * 60
+
+// Flags do xp8 run que não são preferencias do Config
+type runFlags struct {
+	mute           bool
+	debug          bool
+	gdb            string
+	config         string
+	vipInterpreter string
+	vipMonitor     string
+}
+
+// xp8 run [flags] rom
+func runROM(args []string) error {
+	var opts runFlags
+	var flagSettings Config.Settings
+	var keymapSpec, quirkSpec string
+	var gamepadSpecs [2]string
+	flags := newFlagSet("run", "rom")
+	flags.IntVar(&flagSettings.Clock, "clock", 0, "instructions per second (default: the ROM database tick rate or 300)")
+	flags.IntVar(&flagSettings.Scale, "scale", 0, "window pixels per CHIP-8 pixel (default: a 1024x768 window)")
+	flags.StringVar(&flagSettings.Palette, "palette", "", "comma separated colors: background, plane 1, plane 2, both planes (e.g. #000000,#FFCC00)")
+	flags.StringVar(&flagSettings.Mode, "mode", "", "platform: chip8, schip or xochip (default: the ROM database platform or chip8)")
+	flags.StringVar(&flagSettings.Quirks, "quirks", "", "quirks profile: "+strings.Join(Chip8.QuirksProfileNames(), ", "))
+	flags.StringVar(&quirkSpec, "quirk", "", "quirks turned on or off over the profile, e.g. shift=false,clipping")
+	flags.StringVar(&flagSettings.Timing, "timing", "", "timing model: frames (the -clock instructions per frame) or vip (COSMAC VIP machine cycles)")
+	flags.StringVar(&flagSettings.OnFault, "on-fault", "", "what to do when an instruction fails (unknown opcode, stack or memory fault): halt, skip or log (default halt)")
+	flags.BoolVar(&opts.mute, "mute", false, "disable the sound")
+	fullscreen := flags.Bool("fullscreen", false, "open the window in fullscreen (-fullscreen=false overrides the config file)")
+	flags.StringVar(&flagSettings.Layout, "layout", "", "keyboard layout: "+strings.Join(Display.PresetNames(), ", ")+" (default "+Display.DefaultPreset+")")
+	flags.StringVar(&keymapSpec, "keymap", "", "CHIP-8 key overrides, several keys separated by |, e.g. 5=Up|W,8=Down,4=Left,6=Right")
+	flags.StringVar(&gamepadSpecs[0], "gamepad1", "", "CHIP-8 key overrides for the first gamepad, e.g. 1=Up|LeftY-,4=Down|LeftY+")
+	flags.StringVar(&gamepadSpecs[1], "gamepad2", "", "CHIP-8 key overrides for the second gamepad")
+	flags.BoolVar(&opts.debug, "debug", false, "start paused with the interactive debugger on stdin")
+	flags.StringVar(&opts.gdb, "gdb", "", "start paused and wait for a gdb remote connection on this address (e.g. localhost:1234)")
+	flags.StringVar(&opts.vipInterpreter, "vip-interpreter", "", "run on an emulated COSMAC VIP with this original 512-byte CHIP-8 interpreter (needs -vip-monitor)")
+	flags.StringVar(&opts.vipMonitor, "vip-monitor", "", "COSMAC VIP operating system ROM (512 bytes) used with -vip-interpreter")
+	flags.StringVar(&opts.config, "config", Config.DefaultPath(), "configuration file (ignored if it does not exist)")
+	if err := parseFlags(flags, args, 1); err != nil {
+		return err
+	}
+	if opts.debug && opts.gdb != "" {
+		return usageError{"-debug and -gdb cannot be used together"}
+	}
+	if (opts.vipInterpreter == "") != (opts.vipMonitor == "") {
+		return usageError{"-vip-interpreter and -vip-monitor must be used together"}
+	}
+	if opts.vipInterpreter != "" && (opts.debug || opts.gdb != "") {
+		return usageError{"-debug and -gdb cannot be used with the COSMAC VIP emulation"}
+	}
+	// Só as flags passadas valem mais que o config, -fullscreen=false também
+	flags.Visit(func(f *flag.Flag) {
+		if f.Name == "fullscreen" {
+			flagSettings.Fullscreen = fullscreen
+		}
+	})
+	if flagSettings.Clock < 0 || flagSettings.Scale < 0 {
+		return usageError{"-clock and -scale must be positive"}
+	}
+	var err error
+	if flagSettings.Keymap, err = parseKeysFlag("-keymap", keymapSpec); err != nil {
+		return err
+	}
+	for i, spec := range gamepadSpecs {
+		if flagSettings.Gamepads[i], err = parseKeysFlag(fmt.Sprintf("-gamepad%d", i+1), spec); err != nil {
+			return err
+		}
+	}
+	if flagSettings.QuirkFlags, err = parseQuirkFlag(quirkSpec); err != nil {
+		return err
+	}
+	if _, _, err := applySettings(Chip8.New(), flagSettings); err != nil {
+		return usageError{err.Error()}
+	}
+
+	config, err := Config.Load(opts.config)
+	if err != nil {
+		return err
+	}
+
+	// A plataforma das flags ou das preferencias gerais já vale ao carregar a ROM, para que
+	// ROMs do XO-CHIP maiores que 4KB caibam na memoria
+	general := config.Settings
+	general.Merge(flagSettings)
+	mode := Chip8.Chip8Mode
+	if general.Mode != "" {
+		if mode, err = Chip8.ParseMode(general.Mode); err != nil {
+			return fmt.Errorf("%s: %v", opts.config, err)
+		}
+	}
+
+	path := flags.Arg(0)
+	chip_8 := Chip8.New(Chip8.WithMode(mode), Chip8.WithRewind(rewindFrames), Chip8.WithFaultLog(os.Stderr), Chip8.WithCommandLog(os.Stdout))
+	if err := loadROM(chip_8, path); err != nil {
+		return err
+	}
+
+	// Ordem de prioridade: flags, preferencias da ROM no config, preferencias gerais, banco de dados de ROMs
+	settings := config.ForROM(fmt.Sprintf("%x", sha1.Sum(chip_8.ROM())))
+	settings.Merge(flagSettings)
+	keymap, gamepads, err := applySettings(chip_8, settings)
+	if err != nil {
+		return fmt.Errorf("%s: %v", opts.config, err)
+	}
+	printROMInfo(chip_8.Info())
+
+	var vip *VIP.Machine
+	if opts.vipInterpreter != "" {
+		if vip, err = newVIP(opts, chip_8.ROM()); err != nil {
+			return err
+		}
+	}
+
+	pixelgl.Run(func() { // Pixelgl precisa do controle da função principal
+		err = runWindow(chip_8, vip, settings, keymap, gamepads, opts)
+	})
+	return err
+}
+
+// Cria o COSMAC VIP com as imagens do interpretador e do sistema operacional e carrega a ROM nele
+func newVIP(opts runFlags, rom []byte) (*VIP.Machine, error) {
+	interpreter, err := ioutil.ReadFile(opts.vipInterpreter)
+	if err != nil {
+		return nil, err
+	}
+	monitor, err := ioutil.ReadFile(opts.vipMonitor)
+	if err != nil {
+		return nil, err
+	}
+	vip, err := VIP.New(interpreter, monitor)
+	if err != nil {
+		return nil, err
+	}
+	return vip, vip.LoadBytes(rom)
+}
+
+// Lê o valor de -keymap ou -gamepadN ("5=Up|W,8=Down") no formato do Config.Settings
+func parseKeysFlag(flag, spec string) (map[string][]string, error) {
+	if spec == "" {
+		return nil, nil
+	}
+	keys := map[string][]string{}
+	for _, entry := range strings.Split(spec, ",") {
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) != 2 {
+			return nil, usageError{fmt.Sprintf("invalid %s entry %q (expected chip8key=name, e.g. 5=Up)", flag, entry)}
+		}
+		keys[strings.ToUpper(strings.TrimSpace(parts[0]))] = strings.Split(parts[1], "|")
+	}
+	return keys, nil
+}
+
+// Lê a flag -quirk: quirks separados por virgulas, "nome" liga e "nome=false" desliga
+func parseQuirkFlag(spec string) (map[string]bool, error) {
+	if spec == "" {
+		return nil, nil
+	}
+	quirks := map[string]bool{}
+	for _, entry := range strings.Split(spec, ",") {
+		parts := strings.SplitN(entry, "=", 2)
+		on := true
+		if len(parts) == 2 {
+			var err error
+			if on, err = strconv.ParseBool(strings.TrimSpace(parts[1])); err != nil {
+				return nil, usageError{fmt.Sprintf("invalid -quirk entry %q (expected name or name=false, e.g. shift=false)", entry)}
+			}
+		}
+		quirks[strings.ToLower(strings.TrimSpace(parts[0]))] = on
+	}
+	return quirks, nil
+}
+
+// Liga ou desliga os quirks avulsos por cima dos quirks atuais, em ordem alfabetica para
+// que os erros sejam sempre os mesmos
+func applyQuirkFlags(chip_8 *Chip8.Machine, flags map[string]bool) error {
+	if len(flags) == 0 {
+		return nil
+	}
+	names := make([]string, 0, len(flags))
+	for name := range flags {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	quirks := chip_8.Quirks()
+	for _, name := range names {
+		if err := quirks.Set(name, flags[name]); err != nil {
+			return err
+		}
+	}
+	chip_8.SetQuirks(quirks)
+	return nil
+}
+
+// Aplica na Machine as preferencias que dependem só dela e monta o keymap e os mapas dos
+// gamepads: o layout, as teclas sugeridas pelo banco de dados para a ROM e por ultimo as
+// teclas escolhidas pelo usuário
+func applySettings(chip_8 *Chip8.Machine, settings Config.Settings) (Display.Keymap, []Display.GamepadMap, error) {
+	if settings.Mode != "" {
+		mode, err := Chip8.ParseMode(settings.Mode)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := chip_8.SetMode(mode); err != nil {
+			return nil, nil, err
+		}
+	}
+	if settings.Palette != "" {
+		palette, err := Chip8.ParsePalette(settings.Palette)
+		if err != nil {
+			return nil, nil, err
+		}
+		chip_8.SetPalette(palette)
+	}
+	if settings.Quirks != "" {
+		quirks, err := Chip8.QuirksProfile(settings.Quirks)
+		if err != nil {
+			return nil, nil, err
+		}
+		chip_8.SetQuirks(quirks)
+	}
+	if err := applyQuirkFlags(chip_8, settings.QuirkFlags); err != nil {
+		return nil, nil, err
+	}
+	if settings.Clock > 0 {
+		chip_8.SetClockSpeed(settings.Clock)
+	}
+	if settings.Timing != "" {
+		timing, err := Chip8.ParseTiming(settings.Timing)
+		if err != nil {
+			return nil, nil, err
+		}
+		chip_8.SetTiming(timing)
+	}
+	if settings.OnFault != "" {
+		policy, err := Chip8.ParseFaultPolicy(settings.OnFault)
+		if err != nil {
+			return nil, nil, err
+		}
+		chip_8.SetFaultPolicy(policy)
+	}
+	layout := settings.Layout
+	if layout == "" {
+		layout = Display.DefaultPreset
+	}
+	keymap, err := Display.Preset(layout)
+	if err != nil {
+		return nil, nil, err
+	}
+	var actions map[string]byte
+	if info := chip_8.Info(); info != nil {
+		actions = info.Keys
+	}
+	keymap.BindActions(actions)
+	overrides, err := Display.ParseKeymap(settings.KeymapSpec())
+	if err != nil {
+		return nil, nil, err
+	}
+	keymap.Merge(overrides)
+
+	var gamepads []Display.GamepadMap
+	for player := 1; player <= Display.Gamepads; player++ {
+		gamepad := Display.NewGamepadMap(player, actions)
+		overrides, err := Display.ParseGamepadMap(settings.GamepadSpec(player))
+		if err != nil {
+			return nil, nil, err
+		}
+		gamepad.Merge(overrides)
+		gamepads = append(gamepads, gamepad)
+	}
+	return keymap, gamepads, nil
+}
+
+// Abre a janela e roda a Machine, ou o vip se ele não for nil
+func runWindow(chip_8 *Chip8.Machine, vip *VIP.Machine, settings Config.Settings, keymap Display.Keymap, gamepads []Display.GamepadMap, opts runFlags) error {
+	window, err := Display.NewWindow(
+		Display.WithScale(settings.Scale),
+		Display.WithSize(settings.Width, settings.Height),
+		Display.WithFullscreen(settings.Fullscreen != nil && *settings.Fullscreen),
+		Display.WithKeymap(keymap),
+		Display.WithGamepads(gamepads...),
+	)
+	if err != nil {
+		return err
+	}
+	Chip8.WithRenderer(window)(chip_8)
+	Chip8.WithInputSource(window)(chip_8)
+
+	var beeper *Audio.Beeper
+	if !opts.mute {
+		beeper = Audio.NewBeeper("assets/beep.mp3")
+		if settings.Volume != nil {
+			beeper.SetVolume(*settings.Volume)
+		}
+		Chip8.WithAudioSink(beeper)(chip_8)
+	}
+
+	if vip != nil {
+		VIP.WithRenderer(window)(vip)
+		VIP.WithInputSource(window)(vip)
+		if beeper != nil {
+			VIP.WithAudioSink(beeper)(vip)
+			go beeper.Run()
+			defer beeper.Close()
+		}
+		vip.Run()
+		return nil
+	}
+
+	if opts.debug {
+		debugger := Chip8.NewDebugger(chip_8)
+		debugger.Pause()
+		go Console.Run(debugger, os.Stdin, os.Stdout)
+	}
+
+	if opts.gdb != "" {
+		debugger := Chip8.NewDebugger(chip_8)
+		debugger.Pause()
+		go func() {
+			if err := GDB.NewServer(debugger).ListenAndServe(opts.gdb); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+			}
+		}()
+	}
+
+	if beeper != nil {
+		go beeper.Run()
+		defer beeper.Close()
+	}
+	return chip_8.Run()
+}
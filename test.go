@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/mellotonio/go-chip8/Chip8"
+	"github.com/mellotonio/go-chip8/Chip8/Terminal"
+)
+
+// xp8 test [-cycles n] [-mode platform] [-quirks profile] [-quirk list] [-timing model] [-on-fault policy] [-keys 1,2] [-seed n] [-expect screen.txt] rom
+//
+// Roda a ROM sem janela e mostra a tela no fim, útil para ROMs de teste que mostram
+// o resultado na tela e para comparar a saida com uma tela esperada.
+func runTest(args []string) error {
+	flags := newFlagSet("test", "rom")
+	cycles := flags.Int("cycles", 100000, "instructions to run (stops earlier if the ROM exits)")
+	modeName := flags.String("mode", "", "platform: chip8, schip or xochip (default: the ROM database platform or chip8)")
+	quirksName := flags.String("quirks", "", "quirks profile: "+strings.Join(Chip8.QuirksProfileNames(), ", "))
+	quirkSpec := flags.String("quirk", "", "quirks turned on or off over the profile, e.g. shift=false,clipping")
+	timingName := flags.String("timing", "frames", "timing model: frames or vip (COSMAC VIP machine cycles)")
+	faultName := flags.String("on-fault", "halt", "what to do when an instruction fails: halt (the test fails), skip or log")
+	keys := flags.String("keys", "", "CHIP-8 keys held down during the whole run, e.g. 1,A")
+	seed := flags.Int64("seed", 1, "seed of the random numbers, so that runs are repeatable")
+	expect := flags.String("expect", "", "file with the expected screen; the test fails if the final screen differs")
+	if err := parseFlags(flags, args, 1); err != nil {
+		return err
+	}
+
+	mode := Chip8.Chip8Mode
+	if *modeName != "" {
+		var err error
+		if mode, err = Chip8.ParseMode(*modeName); err != nil {
+			return usageError{err.Error()}
+		}
+	}
+
+	path := flags.Arg(0)
+	chip_8 := Chip8.New(Chip8.WithMode(mode), Chip8.WithSeed(*seed), Chip8.WithFaultLog(os.Stderr))
+	if err := loadROM(chip_8, path); err != nil {
+		return err
+	}
+	// A flag vale mais que a plataforma do banco de dados
+	if *modeName != "" {
+		if err := chip_8.SetMode(mode); err != nil {
+			return err
+		}
+	}
+	if *quirksName != "" {
+		quirks, err := Chip8.QuirksProfile(*quirksName)
+		if err != nil {
+			return usageError{err.Error()}
+		}
+		chip_8.SetQuirks(quirks)
+	}
+	quirkFlags, err := parseQuirkFlag(*quirkSpec)
+	if err != nil {
+		return err
+	}
+	if err := applyQuirkFlags(chip_8, quirkFlags); err != nil {
+		return usageError{err.Error()}
+	}
+	timing, err := Chip8.ParseTiming(*timingName)
+	if err != nil {
+		return usageError{err.Error()}
+	}
+	chip_8.SetTiming(timing)
+	policy, err := Chip8.ParseFaultPolicy(*faultName)
+	if err != nil {
+		return usageError{err.Error()}
+	}
+	chip_8.SetFaultPolicy(policy)
+	for _, key := range strings.Split(*keys, ",") {
+		if key = strings.TrimSpace(key); key == "" {
+			continue
+		}
+		k, err := strconv.ParseUint(key, 16, 8)
+		if err != nil || k > 0xF {
+			return usageError{fmt.Sprintf("invalid CHIP-8 key %q (expected 0-F)", key)}
+		}
+		chip_8.SetKeyDown(byte(k))
+	}
+
+	var fault error
+	for i := 0; i < *cycles && !chip_8.Halted() && fault == nil; i++ {
+		fault = chip_8.Step()
+	}
+
+	var screen bytes.Buffer
+	if err := Terminal.WriteFrame(&screen, chip_8.Frame()); err != nil {
+		return err
+	}
+	os.Stdout.Write(screen.Bytes())
+	if fault != nil {
+		return fault
+	}
+
+	if *expect == "" {
+		return nil
+	}
+	expected, err := ioutil.ReadFile(*expect)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(screen.Bytes(), expected) {
+		return errors.New("the screen differs from " + *expect)
+	}
+	fmt.Println("ok")
+	return nil
+}